@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"slices"
+	"time"
+
+	authz "komodo-forge-sdk-go/http/services/authz"
+)
+
+const (
+	DEFAULT_ACCESS_TOKEN_TTL_SEC = 3600
+	DEFAULT_SERVICE_AUDIENCE     = "komodo-apis:service" // client_credentials tokens
+	DEFAULT_USER_AUDIENCE        = "komodo-apis:user"    // tokens issued on behalf of a user
+)
+
+// An OAuth client allowed to request tokens from this service
+type Client struct {
+	ID                string    `json:"clientId" dynamodbav:"client_id"`
+	Name              string    `json:"name,omitempty" dynamodbav:"name,omitempty"`
+	SecretHash        string    `json:"-" dynamodbav:"secret_hash,omitempty"` // argon2id or bcrypt; empty for public (PKCE-only) clients
+	RedirectURIs      []string  `json:"redirectUris,omitempty" dynamodbav:"redirect_uris,omitempty"`
+	GrantTypes        []string  `json:"grantTypes" dynamodbav:"grant_types"`
+	Scopes            []string  `json:"scopes,omitempty" dynamodbav:"scopes,omitempty"`
+	Audience          string    `json:"audience,omitempty" dynamodbav:"audience,omitempty"`
+	ExchangeAudiences []string  `json:"exchangeAudiences,omitempty" dynamodbav:"exchange_audiences,omitempty"` // downstream audiences for token exchange
+	AccessTokenTTL    int64     `json:"accessTokenTtlSec,omitempty" dynamodbav:"access_token_ttl_sec,omitempty"`
+	RefreshTokenTTL   int64     `json:"refreshTokenTtlSec,omitempty" dynamodbav:"refresh_token_ttl_sec,omitempty"`
+	Disabled          bool      `json:"disabled" dynamodbav:"disabled"`
+	CreatedAt         time.Time `json:"createdAt" dynamodbav:"created_at"`
+	UpdatedAt         time.Time `json:"updatedAt" dynamodbav:"updated_at"`
+}
+
+func (c *Client) IsPublic() bool { return c.SecretHash == "" }
+
+func (c *Client) AllowsGrant(grantType string) bool { return slices.Contains(c.GrantTypes, grantType) }
+
+// Redirect URIs must match a registered one exactly (RFC 6749 Section 3.1.2.3)
+func (c *Client) AllowsRedirect(uri string) bool { return slices.Contains(c.RedirectURIs, uri) }
+
+// Reports whether the client's scopes cover every requested one, honouring configured scope implications
+func (c *Client) AllowsScopes(scopes []string) bool { return len(authz.MissingScopes(c.Scopes, scopes)) == 0 }
+
+// Reports whether the client may exchange tokens for ones aimed at the audience
+func (c *Client) AllowsExchangeAudience(audience string) bool {
+	return slices.Contains(c.ExchangeAudiences, audience)
+}
+
+// Audiences a subject token may carry when this client exchanges it: user tokens and tokens
+// previously exchanged with this client as the target
+func (c *Client) SubjectAudiences() []string {
+	out := []string{DEFAULT_USER_AUDIENCE, c.ID}
+	if c.Audience != "" && !slices.Contains(out, c.Audience) { out = append(out, c.Audience) }
+	return out
+}
+
+// Access token lifetime in seconds
+func (c *Client) AccessTTL() int64 {
+	if c.AccessTokenTTL > 0 { return c.AccessTokenTTL }
+	return DEFAULT_ACCESS_TOKEN_TTL_SEC
+}
+
+// Refresh token lifetime; zero means the refresh token service default
+func (c *Client) RefreshTTL() time.Duration { return time.Duration(c.RefreshTokenTTL) * time.Second }
+
+// Audience for tokens issued to this client, falling back to dflt
+func (c *Client) TokenAudience(dflt string) string {
+	if c.Audience != "" { return c.Audience }
+	return dflt
+}
@@ -0,0 +1,40 @@
+package clients
+
+import (
+	"context"
+	"errors"
+
+	"komodo-forge-sdk-go/aws/dynamodb"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Registry backed by a DynamoDB table keyed by client_id
+type DynamoRegistry struct {
+	table string
+}
+
+func NewDynamoRegistry(table string) *DynamoRegistry { return &DynamoRegistry{table: table} }
+
+func (r *DynamoRegistry) Get(ctx context.Context, clientID string) (*Client, error) {
+	key, err := dynamodb.BuildKey("client_id", clientID, "", nil)
+	if err != nil { return nil, err }
+
+	var client Client
+	if err := dynamodb.GetItemAs(ctx, r.table, key, false, nil, &client); err != nil {
+		if errors.Is(err, dynamodb.ErrItemNotFound) { return nil, ErrClientNotFound }
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *DynamoRegistry) Put(ctx context.Context, client *Client, create bool) error {
+	var condition *string
+	if create { condition = aws.String("attribute_not_exists(client_id)") }
+
+	err := dynamodb.WriteItemFrom(ctx, r.table, client, false, nil, condition)
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) { return ErrClientExists }
+	return err
+}
@@ -0,0 +1,34 @@
+package clients
+
+import (
+	"context"
+	"sync"
+)
+
+// Process-local registry for tests and environments without DynamoDB
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	clients map[string]Client
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{clients: make(map[string]Client)}
+}
+
+func (r *MemoryRegistry) Get(_ context.Context, clientID string) (*Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[clientID]
+	if !ok { return nil, ErrClientNotFound }
+	return &client, nil
+}
+
+func (r *MemoryRegistry) Put(_ context.Context, client *Client, create bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.clients[client.ID]; exists && create { return ErrClientExists }
+	r.clients[client.ID] = *client
+	return nil
+}
@@ -0,0 +1,177 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"komodo-forge-sdk-go/aws/dynamodb"
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/crypto/oauth"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrClientNotFound = errors.New("unknown client")
+	ErrClientExists   = errors.New("client already exists")
+	ErrClientDisabled = errors.New("client is disabled")
+	ErrInvalidSecret  = errors.New("invalid client secret")
+	ErrPublicClient   = errors.New("public clients have no secret")
+	ErrInvalidClient  = errors.New("invalid client definition")
+)
+
+type Registry interface {
+	// Returns ErrClientNotFound when no client has the id
+	Get(ctx context.Context, clientID string) (*Client, error)
+	// Stores the client; with create set, fails with ErrClientExists instead of overwriting
+	Put(ctx context.Context, client *Client, create bool) error
+}
+
+var (
+	registry   Registry
+	registryMu sync.Mutex
+)
+
+// Returns the process-wide registry: DynamoDB once the client is initialized, in-memory otherwise
+func Default() Registry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if registry == nil {
+		if dynamodb.IsInitialized() {
+			registry = NewDynamoRegistry(TableName())
+		} else {
+			registry = NewMemoryRegistry()
+		}
+	}
+	return registry
+}
+
+// Overrides the process-wide registry (tests, or services wiring their own backend)
+func SetRegistry(r Registry) {
+	registryMu.Lock()
+	registry = r
+	registryMu.Unlock()
+}
+
+// Table holding the clients (OAUTH_CLIENTS_TABLE)
+func TableName() string {
+	if table := config.GetConfigValue("OAUTH_CLIENTS_TABLE"); table != "" { return table }
+	return "komodo-oauth-clients-dev"
+}
+
+// Returns an enabled client without authenticating it (e.g. for the authorize endpoint)
+func Lookup(ctx context.Context, clientID string) (*Client, error) {
+	client, err := Default().Get(ctx, clientID)
+	if err != nil { return nil, err }
+	if client.Disabled { return nil, ErrClientDisabled }
+	return client, nil
+}
+
+// Authenticates a client. Confidential clients must present their secret; public clients must not send one.
+func Authenticate(ctx context.Context, clientID string, secret string) (*Client, error) {
+	client, err := Default().Get(ctx, clientID)
+	if errors.Is(err, ErrClientNotFound) {
+		VerifySecret(dummyHash, secret)
+		return nil, err
+	}
+	if err != nil { return nil, err }
+
+	if client.IsPublic() {
+		if secret != "" { return nil, ErrInvalidSecret }
+	} else if !VerifySecret(client.SecretHash, secret) {
+		return nil, ErrInvalidSecret
+	}
+
+	if client.Disabled { return nil, ErrClientDisabled }
+	return client, nil
+}
+
+// Registers a client. Confidential clients get a generated secret, returned here only.
+func Create(ctx context.Context, client Client, public bool) (*Client, string, error) {
+	if client.ID == "" { client.ID = uuid.NewString() }
+	if err := validate(&client); err != nil { return nil, "", err }
+
+	var secret string
+	client.SecretHash = ""
+	if !public {
+		var err error
+		if secret, err = GenerateSecret(); err != nil { return nil, "", err }
+		if client.SecretHash, err = HashSecret(secret); err != nil { return nil, "", err }
+	}
+
+	now := time.Now().UTC()
+	client.CreatedAt, client.UpdatedAt, client.Disabled = now, now, false
+
+	if err := Default().Put(ctx, &client, true); err != nil { return nil, "", err }
+	return &client, secret, nil
+}
+
+// Replaces a confidential client's secret; the old one stops working immediately
+func RotateSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := Default().Get(ctx, clientID)
+	if err != nil { return "", err }
+	if client.IsPublic() { return "", ErrPublicClient }
+
+	secret, err := GenerateSecret()
+	if err != nil { return "", err }
+	if client.SecretHash, err = HashSecret(secret); err != nil { return "", err }
+
+	client.UpdatedAt = time.Now().UTC()
+	if err := Default().Put(ctx, client, false); err != nil { return "", err }
+	return secret, nil
+}
+
+// Enables or disables a client; disabled clients can't authenticate or start authorizations
+func SetDisabled(ctx context.Context, clientID string, disabled bool) (*Client, error) {
+	client, err := Default().Get(ctx, clientID)
+	if err != nil { return nil, err }
+
+	client.Disabled = disabled
+	client.UpdatedAt = time.Now().UTC()
+	if err := Default().Put(ctx, client, false); err != nil { return nil, err }
+	return client, nil
+}
+
+func validate(client *Client) error {
+	if len(client.GrantTypes) == 0 { return fmt.Errorf("%w: at least one grant type is required", ErrInvalidClient) }
+	for _, grant := range client.GrantTypes {
+		if !oauth.IsValidGrantType(grant) { return fmt.Errorf("%w: unsupported grant type %q", ErrInvalidClient, grant) }
+	}
+	if client.AllowsGrant("authorization_code") && len(client.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: authorization_code requires redirectUris", ErrInvalidClient)
+	}
+	if client.AllowsGrant(oauth.GrantTypeTokenExchange) && len(client.ExchangeAudiences) == 0 {
+		return fmt.Errorf("%w: token exchange requires exchangeAudiences", ErrInvalidClient)
+	}
+	for _, uri := range client.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return fmt.Errorf("%w: redirect uri %q must be an absolute https uri or http loopback", ErrInvalidClient, uri)
+		}
+	}
+	if client.AccessTokenTTL < 0 || client.RefreshTokenTTL < 0 {
+		return fmt.Errorf("%w: token ttls must not be negative", ErrInvalidClient)
+	}
+	return nil
+}
+
+// Accepts absolute https URIs, or plain http only on a loopback host (RFC 8252 native apps);
+// userinfo and fragments are rejected so the host a user sees is the host that gets the code
+func validRedirectURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" || parsed.User != nil || parsed.Fragment != "" || strings.Contains(uri, "#") { return false }
+
+	switch parsed.Scheme {
+		case "https":
+			return true
+		case "http":
+			host := parsed.Hostname()
+			return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
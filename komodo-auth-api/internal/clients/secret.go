@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OWASP baseline for argon2id
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// Compared against when the client doesn't exist so lookups take the same time either way
+var dummyHash, _ = HashSecret("komodo-dummy-client-secret")
+
+// Returns a random client secret; it is shown to the caller once and only its hash is stored
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil { return "", err }
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hashes a secret with argon2id in PHC format ($argon2id$v=19$m=...,t=...,p=...$salt$hash)
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil { return "", err }
+
+	key := argon2.IDKey([]byte(secret), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Checks a secret against an argon2id or bcrypt hash
+func VerifySecret(hash string, secret string) bool {
+	switch {
+		case strings.HasPrefix(hash, "$argon2id$"):
+			return verifyArgon2id(hash, secret)
+		case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+		default:
+			return false
+	}
+}
+
+func verifyArgon2id(hash string, secret string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 { return false }
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version { return false }
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil { return false }
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil { return false }
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 { return false }
+
+	got := argon2.IDKey([]byte(secret), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	rateLimiter "komodo-forge-sdk-go/http/services/rate_limiter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitPoliciesHoldCredentialAttemptsToTokenLimit(t *testing.T) {
+	if err := rateLimiter.LoadPolicies("rate_limits.yml"); err != nil { t.Fatalf("load: %v", err) }
+
+	// Basic auth is exactly what a brute-force attempt sends, so it must not select a looser policy
+	basic := httptest.NewRequest("POST", "/v1/oauth/token", nil)
+	basic.SetBasicAuth("client-id", "guess")
+	anonymous := httptest.NewRequest("POST", "/v1/oauth/token", nil)
+
+	for _, req := range []*http.Request{basic, anonymous} {
+		if got := rateLimiter.MatchPolicy(req).Name; got != "oauth-token" {
+			t.Errorf("%s %s (auth %q) matched %q, want oauth-token", req.Method, req.URL.Path, req.Header.Get("Authorization"), got)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"komodo-auth-api/internal/clients"
+
+	httpErr "komodo-forge-sdk-go/http/errors"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+type ClientCreateRequest struct {
+	ClientID           string   `json:"clientId,omitempty"` // generated when empty
+	Name               string   `json:"name,omitempty"`
+	Public             bool     `json:"public,omitempty"` // PKCE-only client without a secret
+	RedirectURIs       []string `json:"redirectUris,omitempty"`
+	GrantTypes         []string `json:"grantTypes"`
+	Scopes             []string `json:"scopes,omitempty"`
+	Audience           string   `json:"audience,omitempty"`
+	ExchangeAudiences  []string `json:"exchangeAudiences,omitempty"`
+	AccessTokenTTLSec  int64    `json:"accessTokenTtlSec,omitempty"`
+	RefreshTokenTTLSec int64    `json:"refreshTokenTtlSec,omitempty"`
+}
+
+// The client secret is only ever returned here, at creation and rotation
+type ClientSecretResponse struct {
+	*clients.Client
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// Registers an OAuth client; confidential clients get a generated secret
+func ClientCreateHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	var reqBody ClientCreateRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		logger.Error("failed to parse request body", err)
+		httpErr.SendError(
+			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
+		)
+		return
+	}
+
+	client, secret, err := clients.Create(req.Context(), clients.Client{
+		ID:                reqBody.ClientID,
+		Name:              reqBody.Name,
+		RedirectURIs:      reqBody.RedirectURIs,
+		GrantTypes:        reqBody.GrantTypes,
+		Scopes:            reqBody.Scopes,
+		Audience:          reqBody.Audience,
+		ExchangeAudiences: reqBody.ExchangeAudiences,
+		AccessTokenTTL:    reqBody.AccessTokenTTLSec,
+		RefreshTokenTTL:   reqBody.RefreshTokenTTLSec,
+	}, reqBody.Public)
+	if err != nil {
+		sendClientError(wtr, req, err)
+		return
+	}
+
+	logger.Info("registered oauth client: " + client.ID)
+
+	wtr.WriteHeader(http.StatusCreated)
+	json.NewEncoder(wtr).Encode(ClientSecretResponse{Client: client, ClientSecret: secret})
+}
+
+// Issues a new secret for a confidential client; the previous secret stops working immediately
+func ClientRotateSecretHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	clientID := req.PathValue("clientId")
+	secret, err := clients.RotateSecret(req.Context(), clientID)
+	if err != nil {
+		sendClientError(wtr, req, err)
+		return
+	}
+
+	logger.Info("rotated secret for oauth client: " + clientID)
+	json.NewEncoder(wtr).Encode(map[string]string{"clientId": clientID, "clientSecret": secret})
+}
+
+// Disables a client: it can no longer authenticate or start authorizations
+func ClientDisableHandler(wtr http.ResponseWriter, req *http.Request) {
+	setClientDisabled(wtr, req, true)
+}
+
+// Re-enables a previously disabled client
+func ClientEnableHandler(wtr http.ResponseWriter, req *http.Request) {
+	setClientDisabled(wtr, req, false)
+}
+
+func setClientDisabled(wtr http.ResponseWriter, req *http.Request, disabled bool) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	client, err := clients.SetDisabled(req.Context(), req.PathValue("clientId"), disabled)
+	if err != nil {
+		sendClientError(wtr, req, err)
+		return
+	}
+
+	if disabled {
+		logger.Info("disabled oauth client: " + client.ID)
+	} else {
+		logger.Info("enabled oauth client: " + client.ID)
+	}
+	json.NewEncoder(wtr).Encode(client)
+}
+
+func sendClientError(wtr http.ResponseWriter, req *http.Request, err error) {
+	logger.Error("oauth client update failed", err)
+	switch {
+		case errors.Is(err, clients.ErrClientNotFound):
+			httpErr.SendError(wtr, req, httpErr.Global.NotFound, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, clients.ErrClientExists):
+			httpErr.SendError(wtr, req, httpErr.Global.Conflict, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, clients.ErrInvalidClient), errors.Is(err, clients.ErrPublicClient):
+			httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail(err.Error()))
+		default:
+			httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to update oauth client"))
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	httpErr "komodo-forge-sdk-go/http/errors"
+	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+type IPAccessEntryRequest struct {
+	List   string `json:"list"`
+	CIDR   string `json:"cidr"`
+	TTLSec int64  `json:"ttlSec,omitempty"` // 0 never expires
+	Reason string `json:"reason,omitempty"`
+}
+
+type IPAccessListResponse struct {
+	Whitelist []ipsvc.Entry `json:"whitelist"`
+	Blacklist []ipsvc.Entry `json:"blacklist"`
+}
+
+// Lists the IP whitelist and blacklist, including dynamic and auto-ban entries
+func IPAccessListHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	lists := ipsvc.Default()
+	json.NewEncoder(wtr).Encode(IPAccessListResponse{
+		Whitelist: lists.Entries(ipsvc.ListWhitelist),
+		Blacklist: lists.Entries(ipsvc.ListBlacklist),
+	})
+}
+
+// Adds an IP or CIDR range to a list, optionally expiring after ttlSec
+func IPAccessAddHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	var reqBody IPAccessEntryRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		logger.Error("failed to parse request body", err)
+		httpErr.SendError(
+			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
+		)
+		return
+	}
+	if reqBody.TTLSec < 0 {
+		logger.Error("negative ip access ttl", fmt.Errorf("ttlSec %d is negative", reqBody.TTLSec))
+		httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("ttlSec must not be negative"))
+		return
+	}
+
+	entry, err := ipsvc.Add(
+		req.Context(), reqBody.List, reqBody.CIDR, time.Duration(reqBody.TTLSec)*time.Second, reqBody.Reason, ipsvc.SourceAdmin,
+	)
+	if err != nil {
+		sendIPAccessError(wtr, req, err)
+		return
+	}
+
+	wtr.WriteHeader(http.StatusCreated)
+	json.NewEncoder(wtr).Encode(entry)
+}
+
+// Removes an admin or auto-ban entry (?list=blacklist&cidr=203.0.113.0/24)
+func IPAccessRemoveHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	query := req.URL.Query()
+	if err := ipsvc.Remove(req.Context(), query.Get("list"), query.Get("cidr")); err != nil {
+		sendIPAccessError(wtr, req, err)
+		return
+	}
+	wtr.WriteHeader(http.StatusNoContent)
+}
+
+func sendIPAccessError(wtr http.ResponseWriter, req *http.Request, err error) {
+	logger.Error("ip access list update failed", err)
+	switch {
+		case errors.Is(err, ipsvc.ErrEntryNotFound):
+			httpErr.SendError(wtr, req, httpErr.Global.NotFound, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, ipsvc.ErrManagedByConfig):
+			httpErr.SendError(wtr, req, httpErr.Global.Conflict, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, ipsvc.ErrUnknownList), errors.Is(err, ipsvc.ErrInvalidAddress):
+			httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail(err.Error()))
+		default:
+			httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to update ip access list"))
+	}
+}
@@ -2,16 +2,35 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 
+	"komodo-auth-api/internal/clients"
+
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/crypto/oauth"
 	httpErr "komodo-forge-sdk-go/http/errors"
+	authCode "komodo-forge-sdk-go/http/services/auth_code"
 	logger "komodo-forge-sdk-go/logging/runtime"
 )
 
-// Handles OAuth 2.0 authorization endpoint (RFC 6749 Section 3.1).
-// Authorizes client applications and issues authorization codes
+type AuthorizeCompleteRequest struct {
+	AuthRequest string `json:"authRequest"`
+	Subject     string `json:"subject,omitempty"`
+	Denied      bool   `json:"denied,omitempty"`
+}
+
+type AuthorizeCompleteResponse struct {
+	RedirectTo string `json:"redirectTo"`
+}
+
+// Handles OAuth 2.0 authorization endpoint (RFC 6749 Section 3.1) for the code flow with PKCE (RFC 7636).
+// Validates the client and redirect URI, parks the request and hands the user off to the login UI.
 func OAuthAuthorizeHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Cache-Control", "no-store")
+
 	// Parse query parameters
 	query := req.URL.Query()
 	responseType := query.Get("responseType")
@@ -29,39 +48,131 @@ func OAuthAuthorizeHandler(wtr http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Only support "code" response type for now
-	if responseType != "code" {
-		logger.Error("unsupported oauth response type: " + responseType, fmt.Errorf("unsupported oauth response type"))
+	// Until the redirect URI is known to belong to the client, errors must not redirect (RFC 6749 Section 4.1.2.1)
+	client, err := clients.Lookup(req.Context(), clientID)
+	if err != nil {
+		if !errors.Is(err, clients.ErrClientNotFound) && !errors.Is(err, clients.ErrClientDisabled) {
+			logger.Error("failed to look up oauth client", err)
+			httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to look up client"))
+			return
+		}
+		logger.Error("oauth client rejected: " + clientID, err)
+		httpErr.SendError(wtr, req, httpErr.Auth.UnauthorizedClient, httpErr.WithDetail(err.Error()))
+		return
+	}
+	if !client.AllowsGrant("authorization_code") {
+		logger.Error("client not allowed the code flow: " + clientID, fmt.Errorf("unauthorized client"))
 		httpErr.SendError(
-			wtr, req, httpErr.Auth.AccessDenied, httpErr.WithDetail("unsupported oauth response type"),
+			wtr, req, httpErr.Auth.UnauthorizedClient, httpErr.WithDetail("client may not use the authorization code flow"),
 		)
 		return
 	}
+	if !client.AllowsRedirect(redirectURI) {
+		logger.Error("unregistered redirect uri for client " + clientID + ": " + redirectURI, fmt.Errorf("invalid redirect uri"))
+		httpErr.SendError(wtr, req, httpErr.Auth.InvalidRedirectURI, httpErr.WithDetail("redirect uri is not registered"))
+		return
+	}
 
-	// TODO: Implement authorization code flow
-	// 1. Validate clientId against database/client registry
-	// 2. Validate redirectUri is registered for this client
-	// 3. Check if user is authenticated (session/cookie)
-	//    - If not authenticated: redirect to login page with return URL
-	// 4. Show consent screen (if needed) asking user to approve scopes
-	// 5. Generate authorization code (short-lived, single-use)
-	// 6. Store code with clientId, redirectUri, scope, user_id in cache
-	// 7. Redirect back to redirectUri with code and state:
-	//    redirect_uri?code=<authorization_code>&state=<state>
-
-	logger.Info("authorization endpoint called",
-		"clientId", clientID,
-		"redirectUri", redirectURI,
-		"scope", scope,
-		"state", state,
-	)
+	if responseType != "code" {
+		redirectWithError(wtr, req, redirectURI, state, "unsupported_response_type", "only the code response type is supported")
+		return
+	}
 
-	wtr.Header().Set("Content-Type", "application/json")
-	wtr.WriteHeader(http.StatusNotImplemented)
-	json.NewEncoder(wtr).Encode(map[string]string{
-		"error":             "not_implemented",
-		"errorDescription": "Authorization code flow requires login UI implementation",
-		"clientId":          clientID,
-		"redirectUri":       redirectURI,
+	scopes := client.Scopes
+	if scope != "" { scopes = oauth.ParseScopes(scope) }
+	if !client.AllowsScopes(scopes) {
+		redirectWithError(wtr, req, redirectURI, state, "invalid_scope", "requested scope exceeds the client's scopes")
+		return
+	}
+
+	// PKCE is required for every client, confidential ones included
+	codeChallenge := query.Get("codeChallenge")
+	if codeChallenge == "" || query.Get("codeChallengeMethod") != authCode.MethodS256 {
+		redirectWithError(wtr, req, redirectURI, state, "invalid_request", "code challenge with method S256 is required")
+		return
+	}
+
+	loginURL := config.GetConfigValue("LOGIN_URL")
+	if loginURL == "" {
+		logger.Error("login handoff not configured", fmt.Errorf("LOGIN_URL is not set"))
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("login is not available"))
+		return
+	}
+
+	authRequest, err := authCode.Begin(req.Context(), authCode.Request{
+		ClientID:      clientID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		State:         state,
+		CodeChallenge: codeChallenge,
 	})
+	if err != nil {
+		logger.Error("failed to store authorization request", err)
+		redirectWithError(wtr, req, redirectURI, state, "server_error", "failed to start authorization")
+		return
+	}
+
+	target, err := authCode.RedirectURL(loginURL, url.Values{"authRequest": {authRequest}, "clientId": {clientID}}, "")
+	if err != nil {
+		logger.Error("invalid LOGIN_URL", err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("login is not available"))
+		return
+	}
+
+	logger.Info("authorization request handed off to login for client: " + clientID)
+	http.Redirect(wtr, req, target, http.StatusFound)
+}
+
+// Completes the login handoff. Called by the trusted login service once the user has authenticated
+// (or declined); returns where to send the user's browser: the client's redirect URI with code and state.
+func OAuthAuthorizeCompleteHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	var reqBody AuthorizeCompleteRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		logger.Error("failed to parse request body", err)
+		httpErr.SendError(
+			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
+		)
+		return
+	}
+	if reqBody.AuthRequest == "" || (!reqBody.Denied && reqBody.Subject == "") {
+		logger.Error("missing login handoff parameters", fmt.Errorf("missing authRequest or subject"))
+		httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("missing authRequest or subject"))
+		return
+	}
+
+	var redirect string
+	var err error
+	if reqBody.Denied {
+		redirect, err = authCode.Deny(req.Context(), reqBody.AuthRequest)
+	} else {
+		redirect, err = authCode.Complete(req.Context(), reqBody.AuthRequest, reqBody.Subject)
+	}
+	if err != nil {
+		if errors.Is(err, authCode.ErrInvalidRequest) {
+			logger.Error("unknown or expired authorization request", err)
+			httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail(err.Error()))
+			return
+		}
+		logger.Error("failed to complete authorization request", err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to complete authorization"))
+		return
+	}
+
+	wtr.WriteHeader(http.StatusOK)
+	json.NewEncoder(wtr).Encode(AuthorizeCompleteResponse{RedirectTo: redirect})
+}
+
+// Reports an authorization error back to the client (RFC 6749 Section 4.1.2.1)
+func redirectWithError(wtr http.ResponseWriter, req *http.Request, redirectURI string, state string, code string, description string) {
+	logger.Error("authorization request rejected: " + description, fmt.Errorf("%s", code))
+
+	target, err := authCode.RedirectURL(redirectURI, url.Values{"error": {code}, "error_description": {description}}, state)
+	if err != nil {
+		httpErr.SendError(wtr, req, httpErr.Auth.InvalidRedirectURI, httpErr.WithDetail("invalid redirect uri"))
+		return
+	}
+	http.Redirect(wtr, req, target, http.StatusFound)
 }
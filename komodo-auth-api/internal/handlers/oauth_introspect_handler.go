@@ -2,14 +2,36 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"komodo-forge-sdk-go/crypto/jwt"
+	httpReq "komodo-forge-sdk-go/http/request"
 	logger "komodo-forge-sdk-go/logging/runtime"
 )
 
+type IntrospectRequest struct {
+	Token         string `json:"token,omitempty"` // defaults to the caller's own Bearer token in JSON mode
+	TokenTypeHint string `json:"tokenTypeHint,omitempty"`
+	ClientID      string `json:"clientId,omitempty"`
+	ClientSecret  string `json:"clientSecret,omitempty"`
+}
+
+// Reads an introspection request from RFC 7662 form parameters
+func (r *IntrospectRequest) fromForm(form url.Values) {
+	*r = IntrospectRequest{
+		Token:         form.Get("token"),
+		TokenTypeHint: form.Get("token_type_hint"),
+		ClientID:      form.Get("client_id"),
+		ClientSecret:  form.Get("client_secret"),
+	}
+}
+
 type IntrospectResponse struct {
 	Active    bool   `json:"active"`
 	Scope     string `json:"scope,omitempty"`
@@ -21,18 +43,60 @@ type IntrospectResponse struct {
 	Aud       string `json:"aud,omitempty"`
 }
 
+// IntrospectResponse with the RFC 7662 Section 2.2 field names, sent to form-encoded callers
+type OAuthIntrospectResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+}
+
+// Unusable tokens are reported as inactive; JSON callers keep the historical 400 status
+func sendInactive(wtr http.ResponseWriter, req *http.Request, status int) {
+	if httpReq.IsFormRequest(req) { status = http.StatusOK }
+	wtr.WriteHeader(status)
+	json.NewEncoder(wtr).Encode(IntrospectResponse{Active: false})
+}
+
 // Handles OAuth 2.0 token introspection (RFC 7662).
+// Callers are confidential clients (HTTP Basic or body credentials) or Bearer tokens with the admin scope.
 // Returns token metadata if active, or {"active": false} if invalid/expired/revoked
 func OAuthIntrospectHandler(wtr http.ResponseWriter, req *http.Request) {
 	wtr.Header().Set("Content-Type", "application/json")
 	wtr.Header().Set("Cache-Control", "no-store")
 
-	// Extract token from Authorization header or request body
-	tokenString, err := jwt.ExtractTokenFromRequest(req)
-	if err != nil {
-		logger.Error("no token found in request", err)
-		wtr.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(wtr).Encode(IntrospectResponse{Active: false})
+	// JSON callers may send no body at all and introspect their own token
+	var reqBody IntrospectRequest
+	if err := decodeOAuthRequest(req, &reqBody, reqBody.fromForm); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("failed to parse request body", err)
+		sendOAuthError(wtr, req, errInvalidRequest, "failed to parse request body")
+		return
+	}
+	if err := applyBasicAuth(req, &reqBody.ClientID, &reqBody.ClientSecret); err != nil {
+		logger.Error("invalid basic client authentication", err)
+		sendOAuthError(wtr, req, errInvalidRequest, err.Error())
+		return
+	}
+
+	client, ok := authenticateCaller(wtr, req, reqBody.ClientID, reqBody.ClientSecret, "admin")
+	if !ok { return }
+	if client != nil && client.IsPublic() {
+		logger.Error("public client attempted introspection: " + client.ID, fmt.Errorf("unauthorized client"))
+		sendOAuthError(wtr, req, errUnauthorizedClient, "introspection requires a confidential client")
+		return
+	}
+
+	tokenString := reqBody.Token
+	if tokenString == "" && client == nil && !httpReq.IsFormRequest(req) {
+		tokenString, _ = jwt.ExtractTokenFromRequest(req)
+	}
+	if tokenString == "" {
+		logger.Error("no token found in request", fmt.Errorf("missing token parameter"))
+		sendOAuthError(wtr, req, errInvalidRequest, "missing token parameter")
 		return
 	}
 
@@ -40,16 +104,14 @@ func OAuthIntrospectHandler(wtr http.ResponseWriter, req *http.Request) {
 	claims, err := jwt.ParseClaims(tokenString)
 	if err != nil {
 		logger.Error("failed to parse claims", err)
-		wtr.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(wtr).Encode(IntrospectResponse{Active: false})
+		sendInactive(wtr, req, http.StatusBadRequest)
 		return
 	}
 
 	// Check if token is expired
 	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
 		logger.Info("token is expired")
-		wtr.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(wtr).Encode(IntrospectResponse{Active: false})
+		sendInactive(wtr, req, http.StatusBadRequest)
 		return
 	}
 
@@ -66,19 +128,16 @@ func OAuthIntrospectHandler(wtr http.ResponseWriter, req *http.Request) {
 	iat := int64(0)
 	if claims.IssuedAt != nil { iat = claims.IssuedAt.Unix() }
 
-	// TODO: Check if token is revoked in Elasticache
-	// if claims.ID != "" && elasticache.Exists("revoked:token:" + claims.ID) {
-	//     logger.Info("token has been revoked: " + claims.ID)
-	//     wtr.WriteHeader(http.StatusOK)
-	//     json.NewEncoder(wtr).Encode(IntrospectResponse{Active: false})
-	//     return
-	// }
+	if err := jwt.CheckRevocation(req.Context(), claims); err != nil {
+		logger.Info("token is not active: " + err.Error())
+		sendInactive(wtr, req, http.StatusOK)
+		return
+	}
 
 	logger.Info("token introspection successful for subject: " + claims.Subject)
 
 	// Return token metadata per RFC 7662
-	wtr.WriteHeader(http.StatusOK)
-	json.NewEncoder(wtr).Encode(IntrospectResponse{
+	resp := IntrospectResponse{
 		Active:    true,
 		Scope:     scope,
 		ClientID:  claims.Subject,
@@ -87,5 +146,6 @@ func OAuthIntrospectHandler(wtr http.ResponseWriter, req *http.Request) {
 		Iat:       iat,
 		Sub:       claims.Subject,
 		Aud:       aud,
-	})
+	}
+	sendOAuthResponse(wtr, req, resp, OAuthIntrospectResponse(resp))
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"komodo-auth-api/internal/clients"
+
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	httpReq "komodo-forge-sdk-go/http/request"
+	authz "komodo-forge-sdk-go/http/services/authz"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+// Standard OAuth clients send application/x-www-form-urlencoded snake_case parameters and expect
+// RFC 6749 error bodies. JSON requests are the compatibility mode: camelCase fields and Komodo errors.
+
+// OAuth error codes (RFC 6749 Section 5.2, RFC 6750 Section 3.1) with the Komodo code used in JSON mode
+type oauthError struct {
+	Code   string
+	Status int
+	Compat httpErr.ErrorCode
+}
+
+var (
+	errInvalidRequest       = oauthError{"invalid_request", http.StatusBadRequest, httpErr.Global.BadRequest}
+	errInvalidClient        = oauthError{"invalid_client", http.StatusUnauthorized, httpErr.Auth.InvalidClientCredentials}
+	errInvalidGrant         = oauthError{"invalid_grant", http.StatusBadRequest, httpErr.Auth.InvalidToken}
+	errUnauthorizedClient   = oauthError{"unauthorized_client", http.StatusBadRequest, httpErr.Auth.UnauthorizedClient}
+	errUnsupportedGrantType = oauthError{"unsupported_grant_type", http.StatusBadRequest, httpErr.Auth.UnsupportedGrantType}
+	errInvalidScope         = oauthError{"invalid_scope", http.StatusBadRequest, httpErr.Auth.InvalidScope}
+	errInsufficientScope    = oauthError{"insufficient_scope", http.StatusForbidden, httpErr.Auth.InsufficientScope}
+	errServerError          = oauthError{"server_error", http.StatusInternalServerError, httpErr.Global.Internal}
+)
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Sends an RFC 6749 error for form requests, or the Komodo error shape for JSON requests
+func sendOAuthError(wtr http.ResponseWriter, req *http.Request, oauthErr oauthError, description string) {
+	if !httpReq.IsFormRequest(req) {
+		httpErr.SendError(wtr, req, oauthErr.Compat, httpErr.WithDetail(description))
+		return
+	}
+
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	// Clients that tried HTTP Basic get the matching challenge back (RFC 6749 Section 5.2)
+	if oauthErr.Status == http.StatusUnauthorized {
+		if _, _, ok := req.BasicAuth(); ok { wtr.Header().Set("WWW-Authenticate", `Basic realm="komodo-auth-api"`) }
+	}
+
+	wtr.WriteHeader(oauthErr.Status)
+	json.NewEncoder(wtr).Encode(OAuthErrorResponse{Error: oauthErr.Code, ErrorDescription: description})
+}
+
+// Writes a success body, using the standard field names for form requests
+func sendOAuthResponse(wtr http.ResponseWriter, req *http.Request, compat any, standard any) {
+	wtr.WriteHeader(http.StatusOK)
+	if httpReq.IsFormRequest(req) {
+		json.NewEncoder(wtr).Encode(standard)
+		return
+	}
+	json.NewEncoder(wtr).Encode(compat)
+}
+
+// Decodes the request body into dst: snake_case form fields for form requests, camelCase JSON otherwise
+func decodeOAuthRequest(req *http.Request, dst any, fromForm func(url.Values)) error {
+	if !httpReq.IsFormRequest(req) { return json.NewDecoder(req.Body).Decode(dst) }
+
+	if err := req.ParseForm(); err != nil { return err }
+
+	// Parameters must not be repeated (RFC 6749 Section 3.1)
+	for name, values := range req.PostForm {
+		if len(values) > 1 { return fmt.Errorf("parameter %s included more than once", name) }
+	}
+	fromForm(req.PostForm)
+	return nil
+}
+
+// Applies HTTP Basic client credentials (RFC 6749 Section 2.3.1) over those from the body.
+// Clients must use a single authentication method, so a body secret alongside Basic is rejected.
+func applyBasicAuth(req *http.Request, clientID *string, clientSecret *string) error {
+	user, pass, ok := req.BasicAuth()
+	if !ok { return nil }
+
+	// Both parts are form-urlencoded before being base64 encoded
+	id, err := url.QueryUnescape(user)
+	if err != nil { return fmt.Errorf("malformed basic client id: %w", err) }
+	secret, err := url.QueryUnescape(pass)
+	if err != nil { return fmt.Errorf("malformed basic client secret: %w", err) }
+
+	if *clientSecret != "" { return fmt.Errorf("client used more than one authentication method") }
+	if *clientID != "" && *clientID != id { return fmt.Errorf("client_id does not match basic credentials") }
+
+	*clientID, *clientSecret = id, secret
+	return nil
+}
+
+// Identifies the caller of introspection or revocation: a Bearer token already checked by AuthMiddleware,
+// or client credentials. Returns the client (nil for Bearer callers) and false once an error was sent.
+func authenticateCaller(
+	wtr http.ResponseWriter, req *http.Request, clientID string, clientSecret string, requiredScope string,
+) (*clients.Client, bool) {
+	if clientID == "" {
+		if valid, _ := req.Context().Value(ctxKeys.AUTH_VALID_KEY).(bool); !valid {
+			logger.Error("missing caller authentication", fmt.Errorf("missing client credentials or bearer token"))
+			sendOAuthError(wtr, req, errInvalidClient, "client authentication required")
+			return nil, false
+		}
+		if requiredScope != "" {
+			scopes, _ := req.Context().Value(ctxKeys.SCOPES_KEY).([]string)
+			if !authz.Satisfies(scopes, requiredScope) {
+				logger.Error("bearer caller missing scope " + requiredScope, fmt.Errorf("insufficient scope"))
+				sendOAuthError(wtr, req, errInsufficientScope, "token requires the " + requiredScope + " scope")
+				return nil, false
+			}
+		}
+		return nil, true
+	}
+
+	client, err := clients.Authenticate(req.Context(), clientID, clientSecret)
+	if err != nil {
+		switch {
+			case errors.Is(err, clients.ErrClientNotFound), errors.Is(err, clients.ErrInvalidSecret),
+				errors.Is(err, clients.ErrClientDisabled):
+				logger.Error("invalid client credentials for: " + clientID, err)
+				sendOAuthError(wtr, req, errInvalidClient, "invalid client credentials")
+			default:
+				logger.Error("failed to authenticate client", err)
+				sendOAuthError(wtr, req, errServerError, "failed to authenticate client")
+		}
+		return nil, false
+	}
+	return client, true
+}
@@ -4,42 +4,96 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"komodo-auth-api/internal/clients"
+
 	"komodo-forge-sdk-go/crypto/jwt"
+	ctxKeys "komodo-forge-sdk-go/http/context"
 	httpErr "komodo-forge-sdk-go/http/errors"
+	refreshToken "komodo-forge-sdk-go/http/services/refresh_token"
+	"komodo-forge-sdk-go/http/services/revocation"
 	logger "komodo-forge-sdk-go/logging/runtime"
 )
 
 type RevokeRequest struct {
 	Token         string `json:"token"`
 	TokenTypeHint string `json:"token_type_hint,omitempty"` // "access_token" or "refresh_token"
+	ClientID      string `json:"clientId,omitempty"`
+	ClientSecret  string `json:"clientSecret,omitempty"`
+}
+
+// Reads a revocation request from RFC 7009 form parameters
+func (r *RevokeRequest) fromForm(form url.Values) {
+	*r = RevokeRequest{
+		Token:         form.Get("token"),
+		TokenTypeHint: form.Get("token_type_hint"),
+		ClientID:      form.Get("client_id"),
+		ClientSecret:  form.Get("client_secret"),
+	}
 }
 
 // Handles OAuth 2.0 token revocation (RFC 7009).
-// Revokes access or refresh tokens
+// Callers are clients (HTTP Basic or body credentials) or Bearer tokens. Revokes access or refresh tokens
 func OAuthRevokeHandler(wtr http.ResponseWriter, req *http.Request) {
 	wtr.Header().Set("Content-Type", "application/json")
 	wtr.Header().Set("Cache-Control", "no-store")
 
 	// Parse request body
 	var reqBody RevokeRequest
-	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+	if err := decodeOAuthRequest(req, &reqBody, reqBody.fromForm); err != nil {
 		logger.Error("failed to parse request body", err)
-		httpErr.SendError(
-			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
-		)
+		sendOAuthError(wtr, req, errInvalidRequest, "failed to parse request body")
+		return
+	}
+	if err := applyBasicAuth(req, &reqBody.ClientID, &reqBody.ClientSecret); err != nil {
+		logger.Error("invalid basic client authentication", err)
+		sendOAuthError(wtr, req, errInvalidRequest, err.Error())
 		return
 	}
 
+	client, ok := authenticateCaller(wtr, req, reqBody.ClientID, reqBody.ClientSecret, "")
+	if !ok { return }
+
 	if reqBody.Token == "" {
 		logger.Error("missing token parameter", fmt.Errorf("missing token parameter"))
-		httpErr.SendError(
-			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("missing token parameter"),
-		)
+		sendOAuthError(wtr, req, errInvalidRequest, "missing token parameter")
 		return
 	}
 
+	// Refresh tokens are opaque, so anything that isn't a JWT is looked up in the refresh token store
+	if reqBody.TokenTypeHint == "refresh_token" || strings.Count(reqBody.Token, ".") != 2 {
+		rec, err := refreshToken.Lookup(req.Context(), reqBody.Token)
+		if err != nil && !isRefreshRejection(err) {
+			logger.Error("failed to look up refresh token", err)
+			sendOAuthError(wtr, req, errServerError, "failed to revoke token")
+			return
+		}
+		if rec != nil && !ownsToken(req, client, rec.ClientID, rec.Subject) {
+			logger.Error("caller attempted to revoke a refresh token it doesn't own", fmt.Errorf("owner mismatch"))
+			sendOAuthError(wtr, req, errUnauthorizedClient, "token was not issued to this caller")
+			return
+		}
+
+		found, err := refreshToken.Revoke(req.Context(), reqBody.Token)
+		if err != nil {
+			logger.Error("failed to revoke refresh token", err)
+			sendOAuthError(wtr, req, errServerError, "failed to revoke token")
+			return
+		}
+		if found || reqBody.TokenTypeHint == "refresh_token" {
+			if found { logger.Info("refresh token family revoked") }
+			wtr.WriteHeader(http.StatusOK)
+			json.NewEncoder(wtr).Encode(map[string]interface{}{
+				"revoked":    true,
+				"revoked_at": time.Now().Unix(),
+			})
+			return
+		}
+	}
+
 	// Parse claims from token
 	claims, err := jwt.ParseClaims(reqBody.Token)
 	if err != nil {
@@ -51,6 +105,12 @@ func OAuthRevokeHandler(wtr http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !ownsToken(req, client, claims.ClientID, claims.Subject) {
+		logger.Error("caller attempted to revoke an access token it doesn't own", fmt.Errorf("owner mismatch"))
+		sendOAuthError(wtr, req, errUnauthorizedClient, "token was not issued to this caller")
+		return
+	}
+
 	// Extract JTI (token ID) from claims
 	jti := claims.ID
 	if jti == "" {
@@ -74,19 +134,11 @@ func OAuthRevokeHandler(wtr http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// TODO: Store revoked token in Elasticache with TTL
-	// revokeKey := "revoked:token:" + jti
-	// if err := elasticache.SetCacheItem(revokeKey, clientID, ttl); err != nil {
-	// 	logger.Error("failed to revoke token in cache", err)
-	// 	errors.WriteErrorResponse(
-	// 		wtr,
-	// 		req,
-	// 		http.StatusInternalServerError,
-	// 		"server_error",
-	// 		errCodes.ERR_INTERNAL_SERVER,
-	// 	)
-	// 	return
-	// }
+	if err := revocation.RevokeToken(req.Context(), jti, claims.ExpiresAt.Time); err != nil {
+		logger.Error("failed to store token revocation", err)
+		sendOAuthError(wtr, req, errServerError, "failed to revoke token")
+		return
+	}
 
 	logger.Info("token revoked successfully for subject: " + claims.Subject + ", JTI: " + jti)
 
@@ -97,3 +149,60 @@ func OAuthRevokeHandler(wtr http.ResponseWriter, req *http.Request) {
 		"revoked_at": time.Now().Unix(),
 	})
 }
+
+// Callers may only revoke their own tokens (RFC 7009 Section 2.1): a client those issued to it,
+// a Bearer caller those of its own subject. Admins may revoke any token.
+func ownsToken(req *http.Request, client *clients.Client, tokenClientID string, tokenSubject string) bool {
+	if client != nil {
+		if tokenClientID != "" { return tokenClientID == client.ID }
+		// Service tokens issued before client_id was recorded carry the client as subject
+		return tokenSubject == client.ID
+	}
+
+	if isAdmin, _ := req.Context().Value(ctxKeys.IS_ADMIN_KEY).(bool); isAdmin { return true }
+	userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+	return userID != "" && tokenSubject == userID
+}
+
+type RevokeSubjectRequest struct {
+	Subject string `json:"subject"`
+}
+
+// Revokes every token issued to a subject so far (e.g. logout everywhere or a credential reset)
+func RevokeSubjectHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	var reqBody RevokeSubjectRequest
+	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+		logger.Error("failed to parse request body", err)
+		httpErr.SendError(
+			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
+		)
+		return
+	}
+	if reqBody.Subject == "" {
+		logger.Error("missing subject parameter", fmt.Errorf("missing subject parameter"))
+		httpErr.SendError(
+			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("missing subject parameter"),
+		)
+		return
+	}
+
+	notBefore, err := revocation.RevokeSubject(req.Context(), reqBody.Subject)
+	if err != nil {
+		logger.Error("failed to store subject revocation", err)
+		httpErr.SendError(
+			wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to revoke subject tokens"),
+		)
+		return
+	}
+
+	logger.Info("revoked all tokens for subject: " + reqBody.Subject)
+
+	wtr.WriteHeader(http.StatusOK)
+	json.NewEncoder(wtr).Encode(map[string]interface{}{
+		"revoked":    true,
+		"not_before": notBefore.Unix(),
+	})
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"komodo-auth-api/internal/clients"
+
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/crypto/jwt"
+	"komodo-forge-sdk-go/crypto/oauth"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	authz "komodo-forge-sdk-go/http/services/authz"
+	logger "komodo-forge-sdk-go/logging/runtime"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const DEFAULT_TOKEN_EXCHANGE_TTL_SEC = 300
+
+// Requested audience isn't one the client may exchange for (RFC 8693 Section 2.2.2)
+var errInvalidTarget = oauthError{"invalid_target", http.StatusBadRequest, httpErr.Auth.InvalidTarget}
+
+// Token types accepted as subject or actor tokens; both are our own signed JWTs
+var exchangeableTokenTypes = []string{oauth.TokenTypeAccessToken, oauth.TokenTypeJWT}
+
+// Handles token exchange (RFC 8693): a confidential client trades a user token it received for a
+// short-lived, down-scoped token aimed at one downstream service. The client is recorded as the
+// actor (act claim) on top of any delegation chain the subject token already carried.
+func handleTokenExchange(wtr http.ResponseWriter, req *http.Request, reqBody *TokenRequest) {
+	if reqBody.SubjectToken == "" || reqBody.SubjectTokenType == "" || reqBody.Audience == "" {
+		logger.Error("missing token exchange parameters", fmt.Errorf("missing subject token, subject token type or audience"))
+		sendOAuthError(wtr, req, errInvalidRequest, "missing subject_token, subject_token_type or audience")
+		return
+	}
+	if !slices.Contains(exchangeableTokenTypes, reqBody.SubjectTokenType) {
+		logger.Error("unsupported subject token type: " + reqBody.SubjectTokenType, fmt.Errorf("unsupported token type"))
+		sendOAuthError(wtr, req, errInvalidRequest, "unsupported subject_token_type")
+		return
+	}
+	if reqBody.ActorToken != "" && !slices.Contains(exchangeableTokenTypes, reqBody.ActorTokenType) {
+		logger.Error("unsupported actor token type: " + reqBody.ActorTokenType, fmt.Errorf("unsupported token type"))
+		sendOAuthError(wtr, req, errInvalidRequest, "missing or unsupported actor_token_type")
+		return
+	}
+	if reqBody.RequestedTokenType != "" && !slices.Contains(exchangeableTokenTypes, reqBody.RequestedTokenType) {
+		logger.Error("unsupported requested token type: " + reqBody.RequestedTokenType, fmt.Errorf("unsupported token type"))
+		sendOAuthError(wtr, req, errInvalidRequest, "unsupported requested_token_type")
+		return
+	}
+
+	client := authenticateClient(wtr, req, reqBody, oauth.GrantTypeTokenExchange)
+	if client == nil { return }
+
+	// Only a client holding a credential may act on someone else's behalf
+	if client.IsPublic() {
+		logger.Error("public client requested token exchange: " + client.ID, fmt.Errorf("unauthorized client"))
+		sendOAuthError(wtr, req, errUnauthorizedClient, "token exchange requires a confidential client")
+		return
+	}
+	if !client.AllowsExchangeAudience(reqBody.Audience) {
+		logger.Error("client " + client.ID + " may not exchange for audience " + reqBody.Audience, fmt.Errorf("invalid target"))
+		sendOAuthError(wtr, req, errInvalidTarget, "audience not allowed for client")
+		return
+	}
+
+	subject, err := verifySubjectToken(client, reqBody.SubjectToken)
+	if err != nil {
+		logger.Error("subject token rejected for client " + client.ID, err)
+		sendOAuthError(wtr, req, errInvalidGrant, "invalid subject token")
+		return
+	}
+
+	actor := &jwt.Actor{Subject: client.ID, Actor: subject.Actor}
+	if reqBody.ActorToken != "" {
+		if err := verifyActorToken(client, reqBody.ActorToken); err != nil {
+			logger.Error("actor token rejected for client " + client.ID, err)
+			sendOAuthError(wtr, req, errInvalidGrant, "invalid actor token")
+			return
+		}
+	}
+
+	scopes, ok := exchangeScopes(wtr, req, client, subject, reqBody.Scope)
+	if !ok { return }
+
+	// Never outlive the token being exchanged
+	expiresIn := exchangeTTL()
+	if subject.ExpiresAt != nil {
+		remaining := int64(time.Until(subject.ExpiresAt.Time).Seconds())
+		if remaining < expiresIn { expiresIn = remaining }
+	}
+	if expiresIn <= 0 {
+		logger.Error("subject token about to expire", fmt.Errorf("no lifetime left to exchange"))
+		sendOAuthError(wtr, req, errInvalidGrant, "subject token expired")
+		return
+	}
+
+	// Admin rights are not delegated; downstream services see only the exchanged scopes
+	accessToken, err := jwt.SignClaims(jwt.CustomClaims{
+		Scopes:   scopes,
+		ClientID: client.ID,
+		Actor:    actor,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:  subject.Subject,
+			Issuer:   "komodo-auth-api",
+			Audience: gojwt.ClaimStrings{reqBody.Audience},
+		},
+	}, expiresIn)
+	if err != nil {
+		logger.Error("failed to sign exchanged token", err)
+		sendOAuthError(wtr, req, errServerError, "failed to sign access token")
+		return
+	}
+
+	issuedType := oauth.TokenTypeAccessToken
+	if reqBody.RequestedTokenType != "" { issuedType = reqBody.RequestedTokenType }
+
+	sendTokenResponse(wtr, req, TokenResponse{
+		AccessToken:     accessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(expiresIn),
+		Scope:           strings.Join(scopes, " "),
+		IssuedTokenType: issuedType,
+	})
+
+	logger.Info("exchanged token for: " + subject.Subject + " via client " + client.ID + " to " + reqBody.Audience)
+}
+
+// The client must have been a legitimate recipient of the subject token: a user token, or a token
+// exchanged with the client as its target. Tokens aimed at any other service are refused, so a
+// down-scoped token minted for one service can't be re-exchanged by another.
+func verifySubjectToken(client *clients.Client, token string) (*jwt.CustomClaims, error) {
+	accepted := client.SubjectAudiences()
+	claims, err := jwt.VerifyToken(token, accepted...)
+	if err != nil { return nil, err }
+
+	for _, aud := range claims.Audience {
+		if !slices.Contains(accepted, aud) { return nil, fmt.Errorf("subject token also targets %s", aud) }
+	}
+	// Exchanged tokens name their target as audience; only that service may exchange them further
+	if claims.Actor != nil && !slices.Contains(claims.Audience, client.ID) {
+		return nil, fmt.Errorf("subject token was exchanged by %s for another service", claims.ClientID)
+	}
+	return claims, nil
+}
+
+// The actor token must be the client's own service token, since the client is recorded as the actor
+func verifyActorToken(client *clients.Client, token string) error {
+	claims, err := jwt.VerifyToken(token, client.TokenAudience(clients.DEFAULT_SERVICE_AUDIENCE))
+	if err != nil { return err }
+
+	if claims.Subject != client.ID || (claims.ClientID != "" && claims.ClientID != client.ID) {
+		return fmt.Errorf("actor token was issued to %s, not the authenticated client", claims.Subject)
+	}
+	for _, aud := range claims.Audience {
+		if aud != client.TokenAudience(clients.DEFAULT_SERVICE_AUDIENCE) { return fmt.Errorf("actor token also targets %s", aud) }
+	}
+	return nil
+}
+
+// Resolves the exchanged token's scopes: requested scopes must be covered by both the subject token
+// and the client's registration. Without a request, the subject scopes the client may hold are used.
+func exchangeScopes(
+	wtr http.ResponseWriter, req *http.Request, client *clients.Client, subject *jwt.CustomClaims, requested string,
+) ([]string, bool) {
+	if requested == "" {
+		scopes := make([]string, 0)
+		for _, scope := range subject.Scopes {
+			if authz.Satisfies(client.Scopes, scope) { scopes = append(scopes, scope) }
+		}
+		if len(scopes) == 0 {
+			logger.Error("no exchangeable scopes for client " + client.ID, fmt.Errorf("invalid scope"))
+			sendOAuthError(wtr, req, errInvalidScope, "subject token carries no scope the client may hold")
+			return nil, false
+		}
+		return scopes, true
+	}
+
+	scopes := oauth.ParseScopes(requested)
+	if missing := authz.MissingScopes(subject.Scopes, scopes); len(missing) > 0 {
+		logger.Error("exchange scope exceeds subject token: " + strings.Join(missing, " "), fmt.Errorf("invalid scope"))
+		sendOAuthError(wtr, req, errInvalidScope, "scope exceeds subject token")
+		return nil, false
+	}
+	if missing := authz.MissingScopes(client.Scopes, scopes); len(missing) > 0 {
+		logger.Error("exchange scope not registered for client " + client.ID + ": " + strings.Join(missing, " "), fmt.Errorf("invalid scope"))
+		sendOAuthError(wtr, req, errInvalidScope, "invalid grant scope")
+		return nil, false
+	}
+	return scopes, true
+}
+
+// Lifetime of exchanged tokens (TOKEN_EXCHANGE_TTL_SEC)
+func exchangeTTL() int64 {
+	return int64(config.GetSeconds("TOKEN_EXCHANGE_TTL_SEC", DEFAULT_TOKEN_EXCHANGE_TTL_SEC).Seconds())
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
+
+	"komodo-auth-api/internal/clients"
 
 	"komodo-forge-sdk-go/crypto/jwt"
 	"komodo-forge-sdk-go/crypto/oauth"
-	httpErr "komodo-forge-sdk-go/http/errors"
+	authCode "komodo-forge-sdk-go/http/services/auth_code"
+	authz "komodo-forge-sdk-go/http/services/authz"
+	refreshToken "komodo-forge-sdk-go/http/services/refresh_token"
 	logger "komodo-forge-sdk-go/logging/runtime"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
 )
 
 type TokenRequest struct {
@@ -21,44 +27,91 @@ type TokenRequest struct {
 	RefreshToken string `json:"refreshToken,omitempty"` // For refresh_token grant
 	Code         string `json:"code,omitempty"`          // For authorization_code grant
 	RedirectURI  string `json:"redirectUri,omitempty"`  // For authorization_code grant
+	CodeVerifier string `json:"codeVerifier,omitempty"` // For authorization_code grant (PKCE)
 	Username     string `json:"username,omitempty"`      // For password grant
 	Password     string `json:"password,omitempty"`      // For password grant
+
+	// For token exchange (RFC 8693 Section 2.1)
+	SubjectToken       string `json:"subjectToken,omitempty"`
+	SubjectTokenType   string `json:"subjectTokenType,omitempty"`
+	ActorToken         string `json:"actorToken,omitempty"`
+	ActorTokenType     string `json:"actorTokenType,omitempty"`
+	Audience           string `json:"audience,omitempty"`
+	RequestedTokenType string `json:"requestedTokenType,omitempty"`
 }
 
 type TokenResponse struct {
-	AccessToken  string `json:"accessToken"`
-	TokenType    string `json:"tokenType"`
-	ExpiresIn    int    `json:"expiresIn"`
-	RefreshToken string `json:"refreshToken,omitempty"`
-	Scope        string `json:"scope,omitempty"`
+	AccessToken     string `json:"accessToken"`
+	TokenType       string `json:"tokenType"`
+	ExpiresIn       int    `json:"expiresIn"`
+	RefreshToken    string `json:"refreshToken,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IssuedTokenType string `json:"issuedTokenType,omitempty"` // For token exchange (RFC 8693 Section 2.2.1)
+}
+
+// TokenResponse with the RFC 6749 Section 5.1 field names, sent to form-encoded callers
+type OAuthTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+// Reads a token request from RFC 6749 form parameters
+func (t *TokenRequest) fromForm(form url.Values) {
+	*t = TokenRequest{
+		ClientID:     form.Get("client_id"),
+		ClientSecret: form.Get("client_secret"),
+		GrantType:    form.Get("grant_type"),
+		Scope:        form.Get("scope"),
+		RefreshToken: form.Get("refresh_token"),
+		Code:         form.Get("code"),
+		RedirectURI:  form.Get("redirect_uri"),
+		CodeVerifier: form.Get("code_verifier"),
+		Username:     form.Get("username"),
+		Password:     form.Get("password"),
+
+		SubjectToken:       form.Get("subject_token"),
+		SubjectTokenType:   form.Get("subject_token_type"),
+		ActorToken:         form.Get("actor_token"),
+		ActorTokenType:     form.Get("actor_token_type"),
+		Audience:           form.Get("audience"),
+		RequestedTokenType: form.Get("requested_token_type"),
+	}
+}
+
+func sendTokenResponse(wtr http.ResponseWriter, req *http.Request, resp TokenResponse) {
+	sendOAuthResponse(wtr, req, resp, OAuthTokenResponse(resp))
 }
 
 // Unified OAuth 2.0 token endpoint (RFC 6749 Section 3.2).
+// Accepts form-encoded requests with HTTP Basic or body client credentials, and JSON for compatibility.
 func OAuthTokenHandler(wtr http.ResponseWriter, req *http.Request) {
 	wtr.Header().Set("Content-Type", "application/json")
 	wtr.Header().Set("Cache-Control", "no-store")
 
 	var reqBody TokenRequest
-	if err := json.NewDecoder(req.Body).Decode(&reqBody); err != nil {
+	if err := decodeOAuthRequest(req, &reqBody, reqBody.fromForm); err != nil {
 		logger.Error("failed to parse request body", err)
-		httpErr.SendError(
-			wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse request body"),
-		)
+		sendOAuthError(wtr, req, errInvalidRequest, "failed to parse request body")
+		return
+	}
+	if err := applyBasicAuth(req, &reqBody.ClientID, &reqBody.ClientSecret); err != nil {
+		logger.Error("invalid basic client authentication", err)
+		sendOAuthError(wtr, req, errInvalidRequest, err.Error())
 		return
 	}
 
 	if reqBody.GrantType == "" {
 		logger.Error("missing grant type", fmt.Errorf("missing grant type"))
-		httpErr.SendError(
-			wtr, req, httpErr.Auth.UnsupportedGrantType, httpErr.WithDetail("missing grant type"),
-		)
+		sendOAuthError(wtr, req, errInvalidRequest, "missing grant type")
 		return
 	}
 	if !oauth.IsValidGrantType(reqBody.GrantType) {
 		logger.Error("unsupported grant type: " + reqBody.GrantType, fmt.Errorf("unsupported grant type"))
-		httpErr.SendError(
-			wtr, req, httpErr.Auth.UnsupportedGrantType, httpErr.WithDetail("unsupported grant type"),
-		)
+		sendOAuthError(wtr, req, errUnsupportedGrantType, "unsupported grant type")
 		return
 	}
 
@@ -70,148 +123,241 @@ func OAuthTokenHandler(wtr http.ResponseWriter, req *http.Request) {
 			handleRefreshToken(wtr, req, &reqBody)
 		case "authorization_code":
 			handleAuthorizationCode(wtr, req, &reqBody)
+		case oauth.GrantTypeTokenExchange:
+			handleTokenExchange(wtr, req, &reqBody)
 		default:
 			logger.Error("unsupported grant type: " + reqBody.GrantType, fmt.Errorf("unsupported grant type"))
-			httpErr.SendError(
-				wtr, req, httpErr.Auth.UnsupportedGrantType, httpErr.WithDetail("unsupported grant type"),
-			)
+			sendOAuthError(wtr, req, errUnsupportedGrantType, "unsupported grant type")
 	}
 }
 
 // Handles M2M service authentication (RFC 6749 Section 4.4)
 func handleClientCredentials(wtr http.ResponseWriter, req *http.Request, reqBody *TokenRequest) {
-	// Validate client credentials
-	if reqBody.ClientID == "" || reqBody.ClientSecret == "" {
-		logger.Error("missing client credentials", fmt.Errorf("missing client credentials"))
-		httpErr.SendError(
-			wtr, req, httpErr.Auth.InvalidClientCredentials, httpErr.WithDetail("missing client credentials"),
-		)
-		return
-	}
+	client := authenticateClient(wtr, req, reqBody, "client_credentials")
+	if client == nil { return }
 
-	// TODO: Validate clientId and clientSecret against database/secrets store
-	if reqBody.ClientID != "test-client" || reqBody.ClientSecret != "test-secret" {
-		logger.Error("invalid client credentials", fmt.Errorf("invalid client credentials"))
-		httpErr.SendError(
-			wtr, req, httpErr.Auth.InvalidClientCredentials, httpErr.WithDetail("invalid client credentials"),
-		)
+	// Public clients can't hold a credential, so they can never act on their own behalf
+	if client.IsPublic() {
+		logger.Error("public client requested client_credentials: " + client.ID, fmt.Errorf("unauthorized client"))
+		sendOAuthError(wtr, req, errUnauthorizedClient, "client_credentials requires a confidential client")
 		return
 	}
-	if reqBody.Scope != "" && !oauth.IsValidScope(reqBody.Scope) {
-		logger.Error("invalid grant scope: " + reqBody.Scope, fmt.Errorf("invalid grant scope"))
-		httpErr.SendError(
-			wtr, req, httpErr.Auth.InvalidScope, httpErr.WithDetail("invalid grant scope"),
-		)
+
+	// Default to everything the client was registered for (RFC 6749 Section 3.3)
+	scopes := client.Scopes
+	if reqBody.Scope != "" { scopes = oauth.ParseScopes(reqBody.Scope) }
+	if !client.AllowsScopes(scopes) {
+		logger.Error("invalid grant scope for client " + client.ID + ": " + reqBody.Scope, fmt.Errorf("invalid grant scope"))
+		sendOAuthError(wtr, req, errInvalidScope, "invalid grant scope")
 		return
 	}
 
 	// Issue access token (JWT) - no refresh token for client_credentials
-	accessExpiresIn := int64(3600) // 1 hour
-
-	// Parse scopes from space-separated string
-	var scopes []string
-	if reqBody.Scope != "" {
-		scopes = strings.Fields(reqBody.Scope)
-	}
+	accessExpiresIn := client.AccessTTL()
 
-	accessToken, err := jwt.SignToken(
-		"komodo-auth-api",
-		reqBody.ClientID,
-		"komodo-apis:service",
+	accessToken, err := signAccessToken(
+		client,
+		client.ID,
+		client.TokenAudience(clients.DEFAULT_SERVICE_AUDIENCE),
 		accessExpiresIn,
 		scopes,
 	)
 
 	if err != nil {
 		logger.Error("failed to sign access token", err)
-		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to sign access token"))
+		sendOAuthError(wtr, req, errServerError, "failed to sign access token")
 		return
 	}
 
-	// TODO: Store token JTI in Elasticache for tracking/revocation
-
-	wtr.WriteHeader(http.StatusOK)
-	json.NewEncoder(wtr).Encode(TokenResponse{
+	sendTokenResponse(wtr, req, TokenResponse{
 		AccessToken: accessToken,
 		TokenType:   "Bearer",
 		ExpiresIn:   int(accessExpiresIn),
-		Scope:       reqBody.Scope,
+		Scope:       strings.Join(scopes, " "),
 	})
 
-	logger.Info("issued client_credentials token for: " + reqBody.ClientID)
+	logger.Info("issued client_credentials token for: " + client.ID)
+}
+
+// Signs an access token for the client; the client_id claim lets revocation check who owns the token
+func signAccessToken(client *clients.Client, subject string, audience string, ttl int64, scopes []string) (string, error) {
+	return jwt.SignClaims(jwt.CustomClaims{
+		Scopes:   scopes,
+		ClientID: client.ID,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:  subject,
+			Issuer:   "komodo-auth-api",
+			Audience: gojwt.ClaimStrings{audience},
+		},
+	}, ttl)
+}
+
+// Authenticates the calling client against the registry and checks it may use grantType.
+// Sends the error response and returns nil when it can't.
+func authenticateClient(wtr http.ResponseWriter, req *http.Request, reqBody *TokenRequest, grantType string) *clients.Client {
+	if reqBody.ClientID == "" {
+		logger.Error("missing client credentials", fmt.Errorf("missing client credentials"))
+		sendOAuthError(wtr, req, errInvalidClient, "missing client credentials")
+		return nil
+	}
+
+	client, err := clients.Authenticate(req.Context(), reqBody.ClientID, reqBody.ClientSecret)
+	if err != nil {
+		switch {
+			case errors.Is(err, clients.ErrClientNotFound), errors.Is(err, clients.ErrInvalidSecret):
+				logger.Error("invalid client credentials for: " + reqBody.ClientID, err)
+				sendOAuthError(wtr, req, errInvalidClient, "invalid client credentials")
+			case errors.Is(err, clients.ErrClientDisabled):
+				logger.Error("disabled client attempted authentication: " + reqBody.ClientID, err)
+				sendOAuthError(wtr, req, errUnauthorizedClient, "client is disabled")
+			default:
+				logger.Error("failed to authenticate client", err)
+				sendOAuthError(wtr, req, errServerError, "failed to authenticate client")
+		}
+		return nil
+	}
+
+	if !client.AllowsGrant(grantType) {
+		logger.Error("client " + client.ID + " not allowed grant type " + grantType, fmt.Errorf("unauthorized client"))
+		sendOAuthError(wtr, req, errUnauthorizedClient, "client may not use the " + grantType + " grant")
+		return nil
+	}
+	return client
 }
 
-// Handles token refresh (RFC 6749 Section 6)
+// Handles token refresh (RFC 6749 Section 6).
+// Refresh tokens are opaque and single-use: each exchange returns a new one and retires the old.
 func handleRefreshToken(wtr http.ResponseWriter, req *http.Request, reqBody *TokenRequest) {
 	if reqBody.RefreshToken == "" {
-		httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("missing refresh token"))
+		sendOAuthError(wtr, req, errInvalidRequest, "missing refresh token")
 		return
 	}
+	client := authenticateClient(wtr, req, reqBody, "refresh_token")
+	if client == nil { return }
 
-	// Parse claims from refresh token
-	claims, err := jwt.ParseClaims(reqBody.RefreshToken)
-	if err != nil {
-		logger.Error("failed to parse refresh token", err)
-		httpErr.SendError(wtr, req, httpErr.Auth.InvalidToken, httpErr.WithDetail("failed to parse refresh token"))
+	// Narrowing is allowed, widening is not (RFC 6749 Section 6)
+	current, err := refreshToken.Lookup(req.Context(), reqBody.RefreshToken)
+	if err != nil && !isRefreshRejection(err) {
+		logger.Error("failed to look up refresh token", err)
+		sendOAuthError(wtr, req, errServerError, "failed to refresh token")
 		return
 	}
-
-	// Check if token is expired
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
-		logger.Error("refresh token is expired", fmt.Errorf("refresh token is expired"))
-		httpErr.SendError(wtr, req, httpErr.Auth.InvalidToken, httpErr.WithDetail("refresh token is expired"))
+	if current != nil && reqBody.Scope != "" && len(authz.MissingScopes(current.Scopes, oauth.ParseScopes(reqBody.Scope))) > 0 {
+		logger.Error("refresh scope exceeds original grant: " + reqBody.Scope, fmt.Errorf("invalid refresh scope"))
+		sendOAuthError(wtr, req, errInvalidScope, "scope exceeds original grant")
 		return
 	}
 
-	// TODO: Check if refresh token is revoked in Elasticache
-
-	// Extract subject and scopes from claims
-	clientID := claims.Subject
-	scope := ""
-	if len(claims.Scopes) > 0 {
-		scope = strings.Join(claims.Scopes, " ")
+	newRefreshToken, rec, err := refreshToken.Rotate(req.Context(), reqBody.RefreshToken, client.ID)
+	if err != nil {
+		if !isRefreshRejection(err) {
+			logger.Error("failed to rotate refresh token", err)
+			sendOAuthError(wtr, req, errServerError, "failed to refresh token")
+			return
+		}
+		if errors.Is(err, refreshToken.ErrTokenReused) {
+			logger.Warn("refresh token reuse detected for client " + client.ID + ", token family revoked")
+		}
+		logger.Error("refresh token rejected", err)
+		sendOAuthError(wtr, req, errInvalidGrant, "invalid refresh token")
+		return
 	}
 
+	scopes := rec.Scopes
+	if reqBody.Scope != "" { scopes = oauth.ParseScopes(reqBody.Scope) }
+
 	// Issue new access token
-	accessExpiresIn := int64(3600) // 1 hour
+	accessExpiresIn := client.AccessTTL()
 
-	accessToken, err := jwt.SignToken(
-		"komodo-auth-api",
-		clientID,
-		"komodo-apis:user",
+	accessToken, err := signAccessToken(
+		client,
+		rec.Subject,
+		client.TokenAudience(clients.DEFAULT_USER_AUDIENCE),
 		accessExpiresIn,
-		claims.Scopes,
+		scopes,
 	)
 	if err != nil {
 		logger.Error("failed to sign access token", err)
-		httpErr.SendError(
-			wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to sign access token"),
-		)
+		sendOAuthError(wtr, req, errServerError, "failed to sign access token")
 		return
 	}
 
-	wtr.WriteHeader(http.StatusOK)
-	json.NewEncoder(wtr).Encode(TokenResponse{
+	sendTokenResponse(wtr, req, TokenResponse{
 		AccessToken:  accessToken,
 		TokenType:    "Bearer",
 		ExpiresIn:    int(accessExpiresIn),
-		Scope:        scope,
-		RefreshToken: reqBody.RefreshToken, // Can optionally rotate
+		Scope:        strings.Join(scopes, " "),
+		RefreshToken: newRefreshToken,
 	})
 
-	logger.Info("refreshed token for: " + clientID)
+	logger.Info("refreshed token for: " + rec.Subject + " via client " + rec.ClientID)
+}
+
+// Refresh failures that are the caller's fault rather than a store outage
+func isRefreshRejection(err error) bool {
+	return errors.Is(err, refreshToken.ErrInvalidToken) || errors.Is(err, refreshToken.ErrClientMismatch) ||
+		errors.Is(err, refreshToken.ErrTokenReused) || errors.Is(err, refreshToken.ErrRevoked)
 }
 
-// Handles authorization code exchange (RFC 6749 Section 4.1)
+// Handles authorization code exchange (RFC 6749 Section 4.1.3) with PKCE verification (RFC 7636 Section 4.6)
 func handleAuthorizationCode(wtr http.ResponseWriter, req *http.Request, reqBody *TokenRequest) {
-	// TODO: Implement authorization code flow
-	// 1. Validate code against stored authorization grants
-	// 2. Verify redirect_uri matches original request
-	// 3. Verify client credentials
-	// 4. Issue access + refresh tokens
-	// 5. Delete used authorization code
-
-	httpErr.SendError(
-		wtr, req, httpErr.Global.NotImplemented, httpErr.WithDetail("authorizationcode grant not yet implemented"),
+	if reqBody.Code == "" || reqBody.RedirectURI == "" || reqBody.CodeVerifier == "" {
+		logger.Error("missing code exchange parameters", fmt.Errorf("missing code, redirect uri or code verifier"))
+		sendOAuthError(wtr, req, errInvalidRequest, "missing code, redirect uri or code verifier")
+		return
+	}
+
+	// Public clients prove possession through PKCE alone; confidential ones must also authenticate
+	client := authenticateClient(wtr, req, reqBody, "authorization_code")
+	if client == nil { return }
+
+	grant, err := authCode.Redeem(req.Context(), reqBody.Code, client.ID, reqBody.RedirectURI, reqBody.CodeVerifier)
+	if err != nil {
+		switch {
+			case errors.Is(err, authCode.ErrInvalidCode), errors.Is(err, authCode.ErrClientMismatch),
+				errors.Is(err, authCode.ErrRedirectMismatch), errors.Is(err, authCode.ErrPKCEFailed):
+				logger.Error("authorization code rejected", err)
+				sendOAuthError(wtr, req, errInvalidGrant, "invalid authorization code")
+			default:
+				logger.Error("failed to redeem authorization code", err)
+				sendOAuthError(wtr, req, errServerError, "failed to redeem authorization code")
+		}
+		return
+	}
+
+	accessExpiresIn := client.AccessTTL()
+
+	accessToken, err := signAccessToken(
+		client,
+		grant.Subject,
+		client.TokenAudience(clients.DEFAULT_USER_AUDIENCE),
+		accessExpiresIn,
+		grant.Scopes,
 	)
+	if err != nil {
+		logger.Error("failed to sign access token", err)
+		sendOAuthError(wtr, req, errServerError, "failed to sign access token")
+		return
+	}
+
+	// Only clients registered for refresh_token get one, so they can keep the user logged in
+	var newRefreshToken string
+	if client.AllowsGrant("refresh_token") {
+		newRefreshToken, _, err = refreshToken.Issue(req.Context(), client.ID, grant.Subject, grant.Scopes, client.RefreshTTL())
+		if err != nil {
+			logger.Error("failed to issue refresh token", err)
+			sendOAuthError(wtr, req, errServerError, "failed to issue refresh token")
+			return
+		}
+	}
+
+	sendTokenResponse(wtr, req, TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessExpiresIn),
+		Scope:        strings.Join(grant.Scopes, " "),
+		RefreshToken: newRefreshToken,
+	})
+
+	logger.Info("issued authorization_code tokens for: " + grant.Subject + " via client " + client.ID)
 }
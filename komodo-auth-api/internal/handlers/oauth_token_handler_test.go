@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"komodo-auth-api/internal/clients"
+
+	"komodo-forge-sdk-go/crypto/jwt"
+	"komodo-forge-sdk-go/crypto/oauth"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	refreshToken "komodo-forge-sdk-go/http/services/refresh_token"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// Clients registered for every test; secrets are filled in by TestMain
+var testSecrets = map[string]string{}
+
+func TestMain(m *testing.M) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil { panic(err) }
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+
+	os.Setenv("JWT_PRIVATE_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	os.Setenv("JWT_KID", "test-key")
+	os.Setenv("JWT_ISSUER", "komodo-auth-api")
+	os.Setenv("JWT_AUDIENCE", "komodo-auth-api,komodo-apis:service,komodo-apis:user")
+	if err := jwt.InitializeKeys(); err != nil { panic(err) }
+
+	clients.SetRegistry(clients.NewMemoryRegistry())
+	for _, client := range []clients.Client{
+		{ID: "komodo-order-api", GrantTypes: []string{"client_credentials", oauth.GrantTypeTokenExchange},
+			Scopes: []string{"payments:read", "payments:write"}, ExchangeAudiences: []string{"komodo-payments-api"}},
+		{ID: "komodo-shipping-api", GrantTypes: []string{"client_credentials", oauth.GrantTypeTokenExchange},
+			Scopes: []string{"payments:read"}, ExchangeAudiences: []string{"komodo-payments-api"}},
+	} {
+		_, secret, err := clients.Create(context.Background(), client, false)
+		if err != nil { panic(err) }
+		testSecrets[client.ID] = secret
+	}
+	os.Exit(m.Run())
+}
+
+// Posts a form-encoded token request, authenticating with HTTP Basic
+func postTokenForm(t *testing.T, clientID string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" { req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(testSecrets[clientID])) }
+
+	rec := httptest.NewRecorder()
+	OAuthTokenHandler(rec, req)
+	return rec
+}
+
+func signTestToken(t *testing.T, claims jwt.CustomClaims) string {
+	t.Helper()
+	claims.Issuer = "komodo-auth-api"
+	token, err := jwt.SignClaims(claims, 300)
+	if err != nil { t.Fatalf("sign: %v", err) }
+	return token
+}
+
+func userToken(t *testing.T, audience string, scopes ...string) string {
+	return signTestToken(t, jwt.CustomClaims{
+		Scopes:           scopes,
+		ClientID:         "komodo-ssr",
+		RegisteredClaims: gojwt.RegisteredClaims{Subject: "user-1", Audience: gojwt.ClaimStrings{audience}},
+	})
+}
+
+func exchangeForm(subjectToken string, scope string) url.Values {
+	form := url.Values{
+		"grant_type":         {oauth.GrantTypeTokenExchange},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.TokenTypeAccessToken},
+		"audience":           {"komodo-payments-api"},
+	}
+	if scope != "" { form.Set("scope", scope) }
+	return form
+}
+
+func decodeOAuthError(t *testing.T, rec *httptest.ResponseRecorder) OAuthErrorResponse {
+	t.Helper()
+	var body OAuthErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil { t.Fatalf("decode error body: %v", err) }
+	return body
+}
+
+// Sends a raw form body so tests control repeated parameters and the Authorization header
+func postRawTokenForm(t *testing.T, body string, basicUser string, basicPass string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" { req.SetBasicAuth(basicUser, basicPass) }
+
+	rec := httptest.NewRecorder()
+	OAuthTokenHandler(rec, req)
+	return rec
+}
+
+func TestTokenRequestDecoding(t *testing.T) {
+	secret := url.QueryEscape(testSecrets["komodo-order-api"])
+
+	// Basic credentials are form-urlencoded before base64, so an escaped client id still authenticates
+	rec := postRawTokenForm(t, "grant_type=client_credentials&scope=payments%3Aread", "komodo%2Dorder%2Dapi", secret)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("client_credentials with basic auth: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var issued map[string]any
+	json.NewDecoder(rec.Body).Decode(&issued)
+	if issued["access_token"] == nil || issued["token_type"] != "Bearer" || issued["scope"] != "payments:read" {
+		t.Errorf("form response should use RFC 6749 field names: %v", issued)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("token response is cacheable: %v", rec.Header())
+	}
+
+	for _, tc := range []struct {
+		name   string
+		body   string
+		user   string
+		status int
+		error  string
+	}{
+		{"repeated parameter", "grant_type=client_credentials&scope=payments%3Aread&scope=payments%3Awrite", "komodo-order-api", http.StatusBadRequest, "invalid_request"},
+		{"basic and body secret", "grant_type=client_credentials&client_secret=" + secret, "komodo-order-api", http.StatusBadRequest, "invalid_request"},
+		{"client_id differs from basic", "grant_type=client_credentials&client_id=komodo-shipping-api", "komodo-order-api", http.StatusBadRequest, "invalid_request"},
+		{"malformed basic escape", "grant_type=client_credentials", "komodo%zz", http.StatusBadRequest, "invalid_request"},
+		{"unknown grant", "grant_type=magic", "komodo-order-api", http.StatusBadRequest, "unsupported_grant_type"},
+	} {
+		rec := postRawTokenForm(t, tc.body, tc.user, secret)
+		if rec.Code != tc.status || decodeOAuthError(t, rec).Error != tc.error {
+			t.Errorf("%s: status %d, want %d %s", tc.name, rec.Code, tc.status, tc.error)
+		}
+	}
+
+	// A wrong Basic secret gets the matching challenge back
+	rec = postRawTokenForm(t, "grant_type=client_credentials", "komodo-order-api", "wrong")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("bad basic secret: status %d, WWW-Authenticate %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestOAuthErrorFormats(t *testing.T) {
+	// Form callers get an RFC 6749 error body
+	rec := postTokenForm(t, "komodo-order-api", exchangeForm("not-a-jwt", ""))
+	body := decodeOAuthError(t, rec)
+	if rec.Code != http.StatusBadRequest || body.Error != "invalid_grant" || body.ErrorDescription == "" {
+		t.Errorf("form invalid_grant: status %d, body %+v", rec.Code, body)
+	}
+
+	// JSON callers get the Komodo error shape and camelCase success fields
+	send := func(payload map[string]string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/oauth/token", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		OAuthTokenHandler(rec, req)
+		return rec
+	}
+
+	rec = send(map[string]string{
+		"clientId": "komodo-order-api", "clientSecret": testSecrets["komodo-order-api"],
+		"grantType": oauth.GrantTypeTokenExchange, "subjectToken": "not-a-jwt",
+		"subjectTokenType": oauth.TokenTypeAccessToken, "audience": "komodo-payments-api",
+	})
+	var compat httpErr.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&compat)
+	if rec.Code != httpErr.Auth.InvalidToken.Status || compat.Code != httpErr.Auth.InvalidToken.ID {
+		t.Errorf("json invalid_grant: status %d, body %+v", rec.Code, compat)
+	}
+
+	rec = send(map[string]string{
+		"clientId": "komodo-order-api", "clientSecret": testSecrets["komodo-order-api"], "grantType": "client_credentials",
+	})
+	var issued map[string]any
+	json.NewDecoder(rec.Body).Decode(&issued)
+	if rec.Code != http.StatusOK || issued["accessToken"] == nil || issued["tokenType"] != "Bearer" {
+		t.Errorf("json client_credentials: status %d, body %v", rec.Code, issued)
+	}
+}
+
+func TestTokenExchangeScopeNarrowing(t *testing.T) {
+	subject := userToken(t, "komodo-apis:user", "payments:read", "payments:write", "orders:read")
+
+	for _, tc := range []struct {
+		name   string
+		client string
+		scope  string
+		status int
+		want   string
+	}{
+		{"requested subset", "komodo-order-api", "payments:read", http.StatusOK, "payments:read"},
+		{"default drops scopes the client can't hold", "komodo-order-api", "", http.StatusOK, "payments:read payments:write"},
+		{"default limited by a narrower registration", "komodo-shipping-api", "", http.StatusOK, "payments:read"},
+		{"beyond the subject token", "komodo-order-api", "payments:refund", http.StatusBadRequest, "invalid_scope"},
+		{"beyond the client registration", "komodo-shipping-api", "payments:write", http.StatusBadRequest, "invalid_scope"},
+	} {
+		rec := postTokenForm(t, tc.client, exchangeForm(subject, tc.scope))
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d (%s)", tc.name, rec.Code, tc.status, rec.Body.String())
+			continue
+		}
+		if tc.status != http.StatusOK {
+			if got := decodeOAuthError(t, rec).Error; got != tc.want { t.Errorf("%s: error %q, want %q", tc.name, got, tc.want) }
+			continue
+		}
+
+		var issued OAuthTokenResponse
+		json.NewDecoder(rec.Body).Decode(&issued)
+		claims, err := jwt.ParseClaims(issued.AccessToken)
+		if err != nil { t.Fatalf("%s: parse issued token: %v", tc.name, err) }
+		if issued.Scope != tc.want || strings.Join(claims.Scopes, " ") != tc.want {
+			t.Errorf("%s: scope %q, token scopes %v, want %q", tc.name, issued.Scope, claims.Scopes, tc.want)
+		}
+	}
+}
+
+func TestTokenExchangeSubjectAudience(t *testing.T) {
+	// A user token the service received is exchangeable
+	rec := postTokenForm(t, "komodo-order-api", exchangeForm(userToken(t, "komodo-apis:user", "payments:read"), ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("user token: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var issued OAuthTokenResponse
+	json.NewDecoder(rec.Body).Decode(&issued)
+
+	// The exchanged token targets payments; another exchange-enabled client can't re-exchange it
+	rec = postTokenForm(t, "komodo-shipping-api", exchangeForm(issued.AccessToken, ""))
+	if rec.Code != http.StatusBadRequest || decodeOAuthError(t, rec).Error != "invalid_grant" {
+		t.Errorf("token minted for another service: status %d, want invalid_grant", rec.Code)
+	}
+
+	// Service tokens were never meant for the exchanging client
+	serviceToken := signTestToken(t, jwt.CustomClaims{
+		Scopes:           []string{"payments:read"},
+		RegisteredClaims: gojwt.RegisteredClaims{Subject: "komodo-cart-api", Audience: gojwt.ClaimStrings{"komodo-apis:service"}},
+	})
+	rec = postTokenForm(t, "komodo-order-api", exchangeForm(serviceToken, ""))
+	if rec.Code != http.StatusBadRequest || decodeOAuthError(t, rec).Error != "invalid_grant" {
+		t.Errorf("service token: status %d, want invalid_grant", rec.Code)
+	}
+}
+
+func TestTokenExchangeActorToken(t *testing.T) {
+	ownToken := signTestToken(t, jwt.CustomClaims{
+		RegisteredClaims: gojwt.RegisteredClaims{Subject: "komodo-order-api", Audience: gojwt.ClaimStrings{"komodo-apis:service"}},
+	})
+	otherToken := signTestToken(t, jwt.CustomClaims{
+		RegisteredClaims: gojwt.RegisteredClaims{Subject: "komodo-shipping-api", Audience: gojwt.ClaimStrings{"komodo-apis:service"}},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		actor  string
+		status int
+	}{
+		{"own service token", ownToken, http.StatusOK},
+		{"another client's token", otherToken, http.StatusBadRequest},
+		{"user token", userToken(t, "komodo-apis:user"), http.StatusBadRequest},
+	} {
+		form := exchangeForm(userToken(t, "komodo-apis:user", "payments:read"), "")
+		form.Set("actor_token", tc.actor)
+		form.Set("actor_token_type", oauth.TokenTypeAccessToken)
+
+		if rec := postTokenForm(t, "komodo-order-api", form); rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d (%s)", tc.name, rec.Code, tc.status, rec.Body.String())
+		}
+	}
+}
+
+func postRevoke(t *testing.T, clientID string, ctx context.Context, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/oauth/revoke", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" { req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(testSecrets[clientID])) }
+	if ctx != nil { req = req.WithContext(ctx) }
+
+	rec := httptest.NewRecorder()
+	OAuthRevokeHandler(rec, req)
+	return rec
+}
+
+func bearerCaller(userID string, admin bool) context.Context {
+	ctx := context.WithValue(context.Background(), ctxKeys.AUTH_VALID_KEY, true)
+	ctx = context.WithValue(ctx, ctxKeys.USER_ID_KEY, userID)
+	if admin { ctx = context.WithValue(ctx, ctxKeys.IS_ADMIN_KEY, true) }
+	return ctx
+}
+
+func TestRevokeRequiresOwnership(t *testing.T) {
+	orderToken := signTestToken(t, jwt.CustomClaims{
+		ClientID:         "komodo-order-api",
+		RegisteredClaims: gojwt.RegisteredClaims{Subject: "user-1", Audience: gojwt.ClaimStrings{"komodo-apis:user"}},
+	})
+	if rec := postRevoke(t, "komodo-shipping-api", nil, orderToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("client revoking another client's access token: status %d, want 400", rec.Code)
+	}
+	if rec := postRevoke(t, "", bearerCaller("user-2", false), orderToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("bearer revoking another user's access token: status %d, want 400", rec.Code)
+	}
+	if rec := postRevoke(t, "komodo-order-api", nil, orderToken); rec.Code != http.StatusOK {
+		t.Fatalf("client revoking its own access token: status %d", rec.Code)
+	}
+	if _, err := jwt.VerifyToken(orderToken); err == nil {
+		t.Error("revoked access token still verifies")
+	}
+
+	ctx := context.Background()
+	userRefresh, _, err := refreshToken.Issue(ctx, "komodo-order-api", "user-1", []string{"payments:read"}, 0)
+	if err != nil { t.Fatalf("issue refresh token: %v", err) }
+	if rec := postRevoke(t, "", bearerCaller("user-2", false), userRefresh); rec.Code != http.StatusBadRequest {
+		t.Errorf("bearer revoking another user's refresh token: status %d, want 400", rec.Code)
+	}
+	if rec := postRevoke(t, "komodo-shipping-api", nil, userRefresh); rec.Code != http.StatusBadRequest {
+		t.Errorf("client revoking another client's refresh token: status %d, want 400", rec.Code)
+	}
+	if rec := postRevoke(t, "", bearerCaller("user-1", false), userRefresh); rec.Code != http.StatusOK {
+		t.Errorf("bearer revoking its own refresh token: status %d", rec.Code)
+	}
+
+	adminRefresh, _, _ := refreshToken.Issue(ctx, "komodo-order-api", "user-3", nil, 0)
+	if rec := postRevoke(t, "", bearerCaller("admin-1", true), adminRefresh); rec.Code != http.StatusOK {
+		t.Errorf("admin revoking a user's refresh token: status %d", rec.Code)
+	}
+}
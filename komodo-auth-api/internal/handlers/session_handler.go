@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	sessionsvc "komodo-forge-sdk-go/http/services/session"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+type SessionListResponse struct {
+	Sessions []*sessionsvc.Session `json:"sessions"`
+	Current  string                `json:"current,omitempty"`
+}
+
+// Trades a validated user access token for a browser session cookie, so the SSR engine never has to
+// hand the JWT to the browser. An existing session is rotated instead, since its privileges change.
+func SessionCreateHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+	if userID == "" {
+		logger.Error("session requested without a subject", fmt.Errorf("missing token subject"))
+		httpErr.SendError(wtr, req, httpErr.Auth.InvalidToken, httpErr.WithDetail("token has no subject"))
+		return
+	}
+	scopes, _ := req.Context().Value(ctxKeys.SCOPES_KEY).([]string)
+	isAdmin, _ := req.Context().Value(ctxKeys.IS_ADMIN_KEY).(bool)
+	grant := sessionsvc.Grant{UserID: userID, Scopes: scopes, IsAdmin: isAdmin}
+
+	var (
+		token string
+		sess  *sessionsvc.Session
+		err   error
+	)
+	if valid, _ := req.Context().Value(ctxKeys.SESSION_VALID_KEY).(bool); valid {
+		token, sess, err = sessionsvc.Rotate(req.Context(), sessionsvc.TokenFromRequest(req), &grant)
+	} else {
+		token, sess, err = sessionsvc.Create(req.Context(), req, grant)
+	}
+	if err != nil {
+		logger.Error("failed to start session for: " + userID, err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to start session"))
+		return
+	}
+
+	sessionsvc.SetCookie(wtr, token, sess)
+	logger.Info("started session for: " + userID)
+
+	wtr.WriteHeader(http.StatusCreated)
+	json.NewEncoder(wtr).Encode(sess)
+}
+
+// Lists the caller's active sessions (devices)
+func SessionListHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+	sessions, err := sessionsvc.List(req.Context(), userID)
+	if err != nil {
+		logger.Error("failed to list sessions for: " + userID, err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to list sessions"))
+		return
+	}
+
+	current, _ := req.Context().Value(ctxKeys.SESSION_ID_KEY).(string)
+	json.NewEncoder(wtr).Encode(SessionListResponse{Sessions: sessions, Current: current})
+}
+
+// Signs out one of the caller's sessions
+func SessionRevokeHandler(wtr http.ResponseWriter, req *http.Request) {
+	userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+	sessionID := req.PathValue("sessionId")
+
+	if err := sessionsvc.RevokeForUser(req.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, sessionsvc.ErrNotFound) {
+			logger.Error("session not found for: " + userID, err)
+			httpErr.SendError(wtr, req, httpErr.Global.NotFound, httpErr.WithDetail("session not found"))
+			return
+		}
+		logger.Error("failed to revoke session", err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to revoke session"))
+		return
+	}
+
+	if current, _ := req.Context().Value(ctxKeys.SESSION_ID_KEY).(string); current == sessionID {
+		sessionsvc.ClearCookie(wtr)
+	}
+	logger.Info("revoked session for: " + userID)
+	wtr.WriteHeader(http.StatusNoContent)
+}
+
+// Signs the caller out everywhere
+func SessionRevokeAllHandler(wtr http.ResponseWriter, req *http.Request) {
+	userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+
+	count, err := sessionsvc.RevokeAll(req.Context(), userID)
+	if err != nil {
+		logger.Error("failed to revoke sessions for: " + userID, err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to revoke sessions"))
+		return
+	}
+
+	sessionsvc.ClearCookie(wtr)
+	logger.Info(fmt.Sprintf("revoked %d sessions for: %s", count, userID))
+	wtr.WriteHeader(http.StatusNoContent)
+}
@@ -2,6 +2,7 @@ package komodoauthapi
 
 import (
 	"komodo-auth-api/internal/handlers"
+	"komodo-forge-sdk-go/aws/dynamodb"
 	awsEC "komodo-forge-sdk-go/aws/elasticache"
 	awsSM "komodo-forge-sdk-go/aws/secrets-manager"
 	"komodo-forge-sdk-go/config"
@@ -9,6 +10,7 @@ import (
 	mw "komodo-forge-sdk-go/http/middleware"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	logger "komodo-forge-sdk-go/logging/runtime"
@@ -30,6 +32,18 @@ func chain(handler http.Handler, middlewares ...func(http.Handler) http.Handler)
 	return handler
 }
 
+// Runs AuthMiddleware for Bearer callers only; other callers authenticate as OAuth clients in the handler
+func bearerOrClientAuth(next http.Handler) http.Handler {
+	authed := mw.AuthMiddleware(next)
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+			authed.ServeHTTP(wtr, req)
+			return
+		}
+		next.ServeHTTP(wtr, req)
+	})
+}
+
 func main() {
 	smCfg := awsSM.Config{
 		Region:   config.GetConfigValue("AWS_REGION"),
@@ -40,18 +54,41 @@ func main() {
 			"AWS_ELASTICACHE_ENDPOINT",
 			"AWS_ELASTICACHE_PASSWORD",
 			"AWS_ELASTICACHE_DB",
+			"DYNAMODB_ENDPOINT",
+			"DYNAMODB_ACCESS_KEY",
+			"DYNAMODB_SECRET_KEY",
+			"OAUTH_CLIENTS_TABLE",
 			"JWT_PUBLIC_KEY",
 			"JWT_PRIVATE_KEY",
 			"JWT_AUDIENCE",
 			"JWT_ISSUER",
 			"JWT_KID",
+			"JWT_ALG",
+			"JWT_PREVIOUS_KEYS",
 			"IP_WHITELIST",
 			"IP_BLACKLIST",
+			"TRUSTED_PROXIES",
+			"AUTO_BAN_STRIKES",
+			"AUTO_BAN_WINDOW_SEC",
+			"AUTO_BAN_COOLDOWN_SEC",
 			"MAX_CONTENT_LENGTH",
 			"IDEMPOTENCY_TTL_SEC",
+			"REVOCATION_SUBJECT_TTL_SEC",
+			"REFRESH_TOKEN_TTL_SEC",
+			"REFRESH_TOKEN_MAX_TTL_SEC",
+			"LOGIN_URL",
+			"AUTH_CODE_TTL_SEC",
+			"AUTH_REQUEST_TTL_SEC",
+			"TOKEN_EXCHANGE_TTL_SEC",
+			"SESSIONS_TABLE",
+			"SESSION_IDLE_TTL_SEC",
+			"SESSION_MAX_TTL_SEC",
 			"RATE_LIMIT_RPS",
 			"RATE_LIMIT_BURST",
 			"BUCKET_TTL_SECOND",
+			"CSRF_SECRET",
+			"CSRF_TRUSTED_ORIGINS",
+			"CSRF_TOKEN_TTL_SEC",
 		},
 	}
 
@@ -60,11 +97,31 @@ func main() {
 		os.Exit(1)
 	}
 
+	// OAuth clients live in DynamoDB; the registry picks the table up once the client is initialized
+	ddbCfg := dynamodb.Config{
+		Region:    config.GetConfigValue("AWS_REGION"),
+		Endpoint:  config.GetConfigValue("DYNAMODB_ENDPOINT"),
+		AccessKey: config.GetConfigValue("DYNAMODB_ACCESS_KEY"),
+		SecretKey: config.GetConfigValue("DYNAMODB_SECRET_KEY"),
+	}
+	if err := dynamodb.Init(ddbCfg); err != nil {
+		logger.Fatal("failed to initialize dynamodb", err)
+		os.Exit(1)
+	}
+
 	if err := jwt.InitializeKeys(); err != nil {
 		logger.Fatal("failed to initialize JWT keys", err)
 		os.Exit(1)
 	}
 
+	// Pick up signing key rotations from Secrets Manager without a redeploy
+	go jwt.WatchKeys(func() error {
+		_, err := awsSM.GetSecrets(
+			[]string{"JWT_PRIVATE_KEY", "JWT_PUBLIC_KEY", "JWT_KID", "JWT_ALG", "JWT_PREVIOUS_KEYS"}, smCfg.Prefix, smCfg.Batch,
+		)
+		return err
+	})
+
 	ecCfg := awsEC.Config{
 		Endpoint: config.GetConfigValue("AWS_ELASTICACHE_ENDPOINT"),
 		Password: config.GetConfigValue("AWS_ELASTICACHE_PASSWORD"),
@@ -79,17 +136,65 @@ func main() {
 	// Shared middleware stack for all /oauth routes
 	oauthMW := []func(http.Handler) http.Handler{
 		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
 		mw.TelemetryMiddleware,
 		mw.RateLimiterMiddleware,
 		mw.IPAccessMiddleware,
 		mw.SecurityHeadersMiddleware,
 		mw.NormalizationMiddleware,
 		mw.SanitizationMiddleware,
+		mw.ClientTypeMiddleware,
 		mw.RuleValidationMiddleware,
 	}
 
-	// Extended middleware stack for protected /oauth routes
-	protectedMW := append(oauthMW, mw.ClientTypeMiddleware, mw.AuthMiddleware)
+	// Extended middleware stack for protected /oauth routes.
+	// Rule validation runs after auth so scope/admin requirements see the token claims.
+	protectedMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.RateLimiterMiddleware,
+		mw.IPAccessMiddleware,
+		mw.SecurityHeadersMiddleware,
+		mw.NormalizationMiddleware,
+		mw.SanitizationMiddleware,
+		mw.ClientTypeMiddleware,
+		mw.AuthMiddleware,
+		mw.RuleValidationMiddleware,
+	}
+
+	// Introspection and revocation accept client credentials (RFC 7662/7009) as well as Bearer tokens
+	clientAuthMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.RateLimiterMiddleware,
+		mw.IPAccessMiddleware,
+		mw.SecurityHeadersMiddleware,
+		mw.NormalizationMiddleware,
+		mw.SanitizationMiddleware,
+		mw.ClientTypeMiddleware,
+		bearerOrClientAuth,
+		mw.RuleValidationMiddleware,
+	}
+
+	// Browser sessions: the SSR engine trades a user token for a __Host- session cookie.
+	// Cookie-authenticated changes need a CSRF token from GET /csrf.
+	sessionMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.RateLimiterMiddleware,
+		mw.IPAccessMiddleware,
+		mw.SecurityHeadersMiddleware,
+		mw.NormalizationMiddleware,
+		mw.SanitizationMiddleware,
+		mw.ClientTypeMiddleware,
+		mw.SessionMiddleware,
+		mw.RequireSession,
+		mw.CSRFMiddleware,
+		mw.RuleValidationMiddleware,
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", handlers.HealthHandler)
@@ -97,9 +202,28 @@ func main() {
 
 	mux.Handle("POST /oauth/token", chain(http.HandlerFunc(handlers.OAuthTokenHandler), oauthMW...))
 	mux.Handle("GET /oauth/authorize", chain(http.HandlerFunc(handlers.OAuthAuthorizeHandler), oauthMW...))
-
-	mux.Handle("POST /oauth/introspect", chain(http.HandlerFunc(handlers.OAuthIntrospectHandler), protectedMW...))
-	mux.Handle("POST /oauth/revoke", chain(http.HandlerFunc(handlers.OAuthRevokeHandler), protectedMW...))
+	mux.Handle("POST /oauth/authorize/complete", chain(http.HandlerFunc(handlers.OAuthAuthorizeCompleteHandler), protectedMW...))
+
+	mux.Handle("POST /oauth/introspect", chain(http.HandlerFunc(handlers.OAuthIntrospectHandler), clientAuthMW...))
+	mux.Handle("POST /oauth/revoke", chain(http.HandlerFunc(handlers.OAuthRevokeHandler), clientAuthMW...))
+	mux.Handle("POST /admin/revoke-subject", chain(http.HandlerFunc(handlers.RevokeSubjectHandler), protectedMW...))
+
+	mux.Handle("GET /csrf", chain(http.HandlerFunc(mw.CSRFTokenHandler), append(oauthMW, mw.SessionMiddleware)...))
+	mux.Handle("POST /sessions", chain(http.HandlerFunc(handlers.SessionCreateHandler), append(protectedMW, mw.SessionMiddleware, mw.CSRFMiddleware)...))
+	mux.Handle("GET /sessions", chain(http.HandlerFunc(handlers.SessionListHandler), sessionMW...))
+	mux.Handle("DELETE /sessions", chain(http.HandlerFunc(handlers.SessionRevokeAllHandler), sessionMW...))
+	mux.Handle("DELETE /sessions/{sessionId}", chain(http.HandlerFunc(handlers.SessionRevokeHandler), sessionMW...))
+
+	// Admin-only management of the dynamic IP whitelist/blacklist
+	mux.Handle("GET /admin/ip-access", chain(http.HandlerFunc(handlers.IPAccessListHandler), protectedMW...))
+	mux.Handle("POST /admin/ip-access", chain(http.HandlerFunc(handlers.IPAccessAddHandler), protectedMW...))
+	mux.Handle("DELETE /admin/ip-access", chain(http.HandlerFunc(handlers.IPAccessRemoveHandler), protectedMW...))
+
+	// Admin-only OAuth client registry management
+	mux.Handle("POST /admin/clients", chain(http.HandlerFunc(handlers.ClientCreateHandler), protectedMW...))
+	mux.Handle("POST /admin/clients/{clientId}/secret", chain(http.HandlerFunc(handlers.ClientRotateSecretHandler), protectedMW...))
+	mux.Handle("POST /admin/clients/{clientId}/disable", chain(http.HandlerFunc(handlers.ClientDisableHandler), protectedMW...))
+	mux.Handle("POST /admin/clients/{clientId}/enable", chain(http.HandlerFunc(handlers.ClientEnableHandler), protectedMW...))
 
 	server := &http.Server{
 		Addr:              ":" + config.GetConfigValue("PORT"),
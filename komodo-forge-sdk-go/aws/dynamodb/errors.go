@@ -8,7 +8,10 @@ import (
 )
 
 // Sentinel errors for DynamoDB operations
-var ErrClientNotInitialized = fmt.Errorf("dynamodb: client not initialized")
+var (
+	ErrClientNotInitialized = fmt.Errorf("dynamodb: client not initialized")
+	ErrItemNotFound         = fmt.Errorf("dynamodb: item not found")
+)
 
 // Wraps AWS DynamoDB errors with descriptive messages
 func WrapError(err error, operation string) error {
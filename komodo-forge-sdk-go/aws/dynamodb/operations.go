@@ -28,7 +28,7 @@ func getItem(
 	}
 	if result.Item == nil {
 		logger.Error("item not found", fmt.Errorf("dynamodb item not found"))
-		return nil, WrapError(fmt.Errorf("item not found"), "getItem")
+		return nil, WrapError(ErrItemNotFound, "getItem")
 	}
 	return result.Item, nil
 }
@@ -100,6 +100,56 @@ func Set(key string, value string, ttl int64) error {
 	return nil
 }
 
+// TTLSeconds converts d to the whole seconds Set, SetNX and Incr expect, rounding up so a
+// sub-second TTL doesn't become 0 ("no expiration")
+func TTLSeconds(d time.Duration) int64 {
+	sec := int64((d + time.Second - 1) / time.Second)
+	if sec < 1 { sec = 1 }
+	return sec
+}
+
+// SetNX stores a value only when the key doesn't exist yet; reports whether it was stored
+func SetNX(key string, value string, ttl int64) (bool, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return false, fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	var dur time.Duration
+	if ttl > 0 {
+		dur = time.Duration(ttl) * time.Second
+	}
+
+	stored, err := client.SetNX(ctx, key, value, dur).Result()
+	if err != nil {
+		logger.Error("failed to set cache item if absent", err)
+		return false, err
+	}
+	return stored, nil
+}
+
+// GetDel returns the value at key and deletes it in one step, so it can be read at most once
+func GetDel(key string) (string, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return "", fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	val, err := client.GetDel(ctx, key).Result()
+	if err == redis.Nil { return "", nil }
+	if err != nil {
+		logger.Error("failed to get and delete cache item", err)
+		return "", err
+	}
+	return val, nil
+}
+
 // Delete removes a key from the cache
 func Delete(key string) error {
 	if client == nil {
@@ -117,6 +167,80 @@ func Delete(key string) error {
 	return nil
 }
 
+// Incr increments the counter at key, starting its TTL (in seconds) when the key is created
+func Incr(key string, ttl int64) (int64, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return 0, fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	pipe := client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	if ttl > 0 {
+		pipe.ExpireNX(ctx, key, time.Duration(ttl) * time.Second)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("failed to increment cache counter", err)
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// HSet stores value under field in the hash at key
+func HSet(key string, field string, value string) error {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	if err := client.HSet(ctx, key, field, value).Err(); err != nil {
+		logger.Error("failed to set hash field", err)
+		return err
+	}
+	return nil
+}
+
+// HGetAll returns every field of the hash at key (empty when the key doesn't exist)
+func HGetAll(key string) (map[string]string, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return nil, fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	vals, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		logger.Error("failed to get hash", err)
+		return nil, err
+	}
+	return vals, nil
+}
+
+// HDel removes fields from the hash at key
+func HDel(key string, fields ...string) error {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return fmt.Errorf("elasticache client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	if err := client.HDel(ctx, key, fields...).Err(); err != nil {
+		logger.Error("failed to delete hash fields", err)
+		return err
+	}
+	return nil
+}
+
 // Close closes the Elasticache client connection
 func Close() error {
 	if client == nil {
@@ -126,7 +250,7 @@ func Close() error {
 	return client.Close()
 }
 
-// token bucket Lua script (atomic): returns {allowed, wait_ms}
+// token bucket Lua script (atomic): returns {allowed, wait_ms, remaining, reset_ms}
 var tokenBucketScript = redis.NewScript(`
 local now = tonumber(ARGV[1])
 local rate = tonumber(ARGV[2])
@@ -158,54 +282,69 @@ else
 	wait_ms = 0
   end
 end
+local reset_ms = 0
+if rate > 0 then reset_ms = math.ceil(((burst - new_tokens) / rate) * 1000) end
 redis.call('HMSET', KEYS[1], 'tokens', tostring(new_tokens), 'ts', tostring(now))
 redis.call('EXPIRE', KEYS[1], ttl)
-return {allowed, tostring(wait_ms)}
+return {allowed, tostring(wait_ms), tostring(math.floor(new_tokens)), tostring(reset_ms)}
 `)
 
+// Outcome of a distributed limiter call
+type LimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration // until the limit is fully restored
+}
+
 // AllowDistributed attempts to consume a token from a distributed token bucket
 // Returns (allowed, retryAfter, error)
 func AllowDistributed(ctx context.Context, key string, rate, burst float64, ttlSec int) (bool, time.Duration, error) {
+	res, err := AllowDistributedN(ctx, key, rate, burst, 1, ttlSec)
+	return res.Allowed, res.RetryAfter, err
+}
+
+// AllowDistributedN consumes cost tokens from a distributed token bucket
+func AllowDistributedN(ctx context.Context, key string, rate, burst, cost float64, ttlSec int) (LimitResult, error) {
 	if client == nil {
 		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
-		return false, 0, fmt.Errorf("elasticache client not initialized")
+		return LimitResult{}, fmt.Errorf("elasticache client not initialized")
 	}
 
 	now := time.Now().UnixMilli()
-	res, err := tokenBucketScript.Run(ctx, client, []string{key}, now, rate, burst, 1, ttlSec).Result()
+	res, err := tokenBucketScript.Run(ctx, client, []string{key}, now, rate, burst, cost, ttlSec).Result()
 	if err != nil {
 		logger.Error("failed to execute token bucket script", err)
-		return false, 0, err
+		return LimitResult{}, err
 	}
+	return parseLimitResult(res)
+}
 
-	// Script returns [allowed, wait_ms]
+// Parses the {allowed, wait_ms, remaining, reset_ms} reply shared by the limiter scripts
+func parseLimitResult(res any) (LimitResult, error) {
 	arr, ok := res.([]interface{})
-	if !ok || len(arr) < 2 {
+	if !ok || len(arr) < 4 {
 		logger.Error("unexpected script result", fmt.Errorf("unexpected result: %v", res))
-		return false, 0, fmt.Errorf("unexpected script result")
+		return LimitResult{}, fmt.Errorf("unexpected script result")
 	}
 
-	// Parse allowed (may be number or string)
-	var allowed bool
-	switch v := arr[0].(type) {
-		case int64:
-			allowed = v == 1
-		case string:
-			allowed = v == "1"
-		default:
-			allowed = false
-	}
+	return LimitResult{
+		Allowed:    scriptInt(arr[0]) == 1,
+		RetryAfter: time.Duration(scriptInt(arr[1])) * time.Millisecond,
+		Remaining:  int(scriptInt(arr[2])),
+		Reset:      time.Duration(scriptInt(arr[3])) * time.Millisecond,
+	}, nil
+}
 
-	// Parse wait time
-	var waitMs int64
-	switch v := arr[1].(type) {
+// Script replies may be integers or strings depending on how Lua returned them
+func scriptInt(v any) int64 {
+	switch val := v.(type) {
 		case int64:
-			waitMs = v
+			return val
 		case string:
-			if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
-				waitMs = parsed
-			}
+			parsed, _ := strconv.ParseInt(val, 10, 64)
+			return parsed
+		default:
+			return 0
 	}
-
-	return allowed, time.Duration(waitMs) * time.Millisecond, nil
 }
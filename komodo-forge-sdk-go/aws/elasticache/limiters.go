@@ -0,0 +1,156 @@
+package elasticache
+
+import (
+	"context"
+	"fmt"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sliding window log Lua script (atomic): one sorted-set member per unit of cost, scored by arrival time.
+// Returns {allowed, wait_ms, remaining, reset_ms}
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local id = ARGV[5]
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+local wait_ms = 0
+if count + cost <= limit then
+  for i = 1, cost do
+    redis.call('ZADD', KEYS[1], now, id .. ':' .. i)
+  end
+  count = count + cost
+  allowed = 1
+else
+  local need = count + cost - limit
+  local oldest = redis.call('ZRANGE', KEYS[1], need - 1, need - 1, 'WITHSCORES')
+  if oldest[2] then wait_ms = math.max(0, tonumber(oldest[2]) + window - now) end
+end
+local reset_ms = 0
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if newest[2] then reset_ms = math.max(0, tonumber(newest[2]) + window - now) end
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(math.ceil(wait_ms)), tostring(limit - count), tostring(math.ceil(reset_ms))}
+`)
+
+// GCRA Lua script (atomic): stores the theoretical arrival time (TAT) in ms.
+// Returns {allowed, wait_ms, remaining, reset_ms}
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then tat = now end
+local new_tat = tat + interval * cost
+local allow_at = new_tat - interval * burst
+local allowed = 0
+local wait_ms = 0
+if allow_at <= now then
+  allowed = 1
+  tat = new_tat
+  redis.call('SET', KEYS[1], tostring(tat), 'PX', math.ceil(tat - now) + 1)
+else
+  wait_ms = math.ceil(allow_at - now)
+end
+local remaining = math.floor((interval * burst - (tat - now)) / interval)
+if remaining < 0 then remaining = 0 end
+return {allowed, tostring(wait_ms), tostring(remaining), tostring(math.ceil(tat - now))}
+`)
+
+// concurrency slot Lua script (atomic): leases are sorted-set members scored by expiry so crashed holders age out.
+// Returns {acquired, remaining}
+var acquireSlotScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local lease = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local id = ARGV[4]
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+local count = redis.call('ZCARD', KEYS[1])
+if count >= max then
+  return {0, '0'}
+end
+redis.call('ZADD', KEYS[1], now + lease, id)
+redis.call('PEXPIRE', KEYS[1], lease)
+return {1, tostring(max - count - 1)}
+`)
+
+// SlidingWindowAllow records cost hits against a log of the last window, allowing at most limit hits.
+// id must be unique per call.
+func SlidingWindowAllow(ctx context.Context, key string, window time.Duration, limit int, cost int, id string) (LimitResult, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return LimitResult{}, fmt.Errorf("elasticache client not initialized")
+	}
+
+	now := time.Now().UnixMilli()
+	res, err := slidingWindowScript.Run(ctx, client, []string{key}, now, window.Milliseconds(), limit, cost, id).Result()
+	if err != nil {
+		logger.Error("failed to execute sliding window script", err)
+		return LimitResult{}, err
+	}
+	return parseLimitResult(res)
+}
+
+// GCRAAllow applies the generic cell rate algorithm: one token per interval with up to burst tokens banked
+func GCRAAllow(ctx context.Context, key string, interval time.Duration, burst float64, cost float64) (LimitResult, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return LimitResult{}, fmt.Errorf("elasticache client not initialized")
+	}
+
+	now := time.Now().UnixMilli()
+	intervalMs := float64(interval) / float64(time.Millisecond)
+
+	res, err := gcraScript.Run(ctx, client, []string{key}, now, intervalMs, burst, cost).Result()
+	if err != nil {
+		logger.Error("failed to execute gcra script", err)
+		return LimitResult{}, err
+	}
+	return parseLimitResult(res)
+}
+
+// AcquireSlot leases one of max concurrent slots for id; the lease expires on its own if never released
+func AcquireSlot(ctx context.Context, key string, max int, lease time.Duration, id string) (bool, int, error) {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return false, 0, fmt.Errorf("elasticache client not initialized")
+	}
+
+	now := time.Now().UnixMilli()
+	res, err := acquireSlotScript.Run(ctx, client, []string{key}, now, lease.Milliseconds(), max, id).Result()
+	if err != nil {
+		logger.Error("failed to execute concurrency script", err)
+		return false, 0, err
+	}
+
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) < 2 {
+		logger.Error("unexpected script result", fmt.Errorf("unexpected result: %v", res))
+		return false, 0, fmt.Errorf("unexpected script result")
+	}
+	return scriptInt(arr[0]) == 1, int(scriptInt(arr[1])), nil
+}
+
+// ReleaseSlot returns a leased concurrency slot
+func ReleaseSlot(ctx context.Context, key string, id string) error {
+	if client == nil {
+		logger.Error("elasticache client not initialized", fmt.Errorf("elasticache client not initialized"))
+		return fmt.Errorf("elasticache client not initialized")
+	}
+
+	if err := client.ZRem(ctx, key, id).Err(); err != nil {
+		logger.Error("failed to release concurrency slot", err)
+		return err
+	}
+	return nil
+}
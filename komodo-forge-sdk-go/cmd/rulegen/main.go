@@ -0,0 +1,70 @@
+// Generates a service's validation_rules.yml from its OpenAPI 3 document.
+//
+//	rulegen -spec docs/openapi.yaml -out internal/config/validation_rules.yml
+//	rulegen -spec docs/openapi.yaml -out internal/config/validation_rules.yml --check
+//
+// With --check nothing is written; the command exits 1 and prints a diff when the committed file has drifted.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	evalRules "komodo-forge-sdk-go/http/rules"
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Runs rulegen with args and returns the exit code: 0 ok, 1 drift found by --check, 2 failure
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rulegen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	spec := flags.String("spec", "docs/openapi.yaml", "path to the OpenAPI 3 document")
+	out := flags.String("out", "internal/config/validation_rules.yml", "path of the rules file to write or check")
+	check := flags.Bool("check", false, "diff the generated rules against -out instead of writing")
+	if err := flags.Parse(args); err != nil { return 2 }
+
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, "rulegen:", err)
+		return 2
+	}
+
+	data, err := os.ReadFile(*spec)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read spec: %w", err))
+	}
+
+	cfg, err := evalRules.FromOpenAPI(data)
+	if err != nil {
+		return fail(fmt.Errorf("failed to generate rules from %s: %w", *spec, err))
+	}
+
+	generated, err := evalRules.MarshalConfig(cfg, *spec)
+	if err != nil { return fail(err) }
+
+	if !*check {
+		if err := os.WriteFile(*out, generated, 0o644); err != nil {
+			return fail(fmt.Errorf("failed to write rules: %w", err))
+		}
+		fmt.Fprintf(stdout, "wrote %d routes to %s\n", len(cfg), *out)
+		return 0
+	}
+
+	committed, err := os.ReadFile(*out)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(fmt.Errorf("failed to read committed rules: %w", err))
+	}
+	if bytes.Equal(committed, generated) {
+		fmt.Fprintf(stdout, "%s is up to date with %s\n", *out, *spec)
+		return 0
+	}
+
+	fmt.Fprintf(stdout, "%s is out of date with %s (- committed, + generated):\n", *out, *spec)
+	fmt.Fprint(stdout, evalRules.DiffLines(committed, generated))
+	return 1
+}
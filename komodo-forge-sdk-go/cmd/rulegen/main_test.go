@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDetectsDrift(t *testing.T) {
+	// The rules package's fixture spec and its expected output; paths match the generated-file header
+	t.Chdir("../../http/rules/testdata")
+	expected, err := os.ReadFile("validation_rules.yml")
+	if err != nil { t.Fatalf("read expected rules: %v", err) }
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-spec", "openapi.yaml", "-out", "validation_rules.yml", "--check"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected rules: exit %d, output %s%s", code, stdout.String(), stderr.String())
+	}
+
+	drifted := filepath.Join(t.TempDir(), "validation_rules.yml")
+	os.WriteFile(drifted, bytes.Replace(expected, []byte("level: strict"), []byte("level: lenient"), 1), 0o644)
+
+	stdout.Reset()
+	if code := run([]string{"-spec", "openapi.yaml", "-out", drifted, "--check"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("drifted rules: exit %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "-       level: lenient") || !strings.Contains(stdout.String(), "+       level: strict") {
+		t.Errorf("drift output doesn't show the changed line:\n%s", stdout.String())
+	}
+
+	// Writing regenerates the file, after which --check passes again
+	if code := run([]string{"-spec", "openapi.yaml", "-out", drifted}, &stdout, &stderr); code != 0 {
+		t.Fatalf("write: exit %d, stderr %s", code, stderr.String())
+	}
+	if code := run([]string{"-spec", "openapi.yaml", "-out", drifted, "--check"}, &stdout, &stderr); code != 0 {
+		t.Errorf("after rewrite: exit %d", code)
+	}
+
+	if code := run([]string{"-spec", "missing.yaml", "-out", drifted, "--check"}, &stdout, &stderr); code != 2 {
+		t.Errorf("missing spec: exit %d, want 2", code)
+	}
+}
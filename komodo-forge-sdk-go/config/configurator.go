@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Config struct {
@@ -33,6 +36,14 @@ func GetConfigValue(key string) string {
 	return os.Getenv(key)
 }
 
+// Reads key as a whole number of seconds; missing, malformed or non-positive values fall back to dflt
+func GetSeconds(key string, dflt int) time.Duration {
+	if sec, err := strconv.Atoi(strings.TrimSpace(GetConfigValue(key))); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return time.Duration(dflt) * time.Second
+}
+
 // Sets value in local in-memory config
 func SetConfigValue(key, value string) {
 	if value == "" || key == "" || instance == nil { return }
@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+const (
+	DEFAULT_JWKS_CACHE_SEC = 300
+	minJWKSRefresh         = 10 * time.Second // unknown kids can't force refetches faster than this
+)
+
+type JWK struct {
+	Kty string `json:"kty"`           // Key Type (e.g., "RSA")
+	Use string `json:"use,omitempty"` // Public Key Use (e.g., "sig" for signature)
+	Kid string `json:"kid"`           // Key ID
+	Alg string `json:"alg,omitempty"` // Algorithm (e.g., "RS256")
+	N   string `json:"n,omitempty"`   // RSA Modulus (base64url encoded)
+	E   string `json:"e,omitempty"`   // RSA Exponent (base64url encoded)
+	Crv string `json:"crv,omitempty"` // EC/OKP curve (e.g., "P-256", "Ed25519")
+	X   string `json:"x,omitempty"`   // EC x coordinate or OKP public key (base64url encoded)
+	Y   string `json:"y,omitempty"`   // EC y coordinate (base64url encoded)
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Encodes the public half of the key as a JWK
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{Use: "sig", Kid: k.ID, Alg: k.Algorithm}
+
+	switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			if pub.Curve != elliptic.P256() { return JWK{}, fmt.Errorf("unsupported EC curve for kid %q", k.ID) }
+			// Coordinates are fixed-width (32 bytes for P-256), so leading zeros must be kept
+			jwk.Kty, jwk.Crv = "EC", "P-256"
+			jwk.X = b64(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = b64(pub)
+		default:
+			return JWK{}, fmt.Errorf("unsupported public key type %T for kid %q", k.Public, k.ID)
+	}
+	return jwk, nil
+}
+
+// Decodes a JWK into a verification key; the key's algorithm is the declared alg or the key type's default
+func (j JWK) Key() (*Key, error) {
+	if j.Use != "" && j.Use != "sig" { return nil, fmt.Errorf("jwk %q is not a signing key", j.Kid) }
+
+	var pub crypto.PublicKey
+	switch j.Kty {
+		case "RSA":
+			n, err := unb64(j.N)
+			if err != nil { return nil, fmt.Errorf("jwk %q: invalid modulus: %w", j.Kid, err) }
+			e, err := unb64(j.E)
+			if err != nil { return nil, fmt.Errorf("jwk %q: invalid exponent: %w", j.Kid, err) }
+			pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if j.Crv != "P-256" { return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv) }
+			x, err := unb64(j.X)
+			if err != nil { return nil, fmt.Errorf("jwk %q: invalid x: %w", j.Kid, err) }
+			y, err := unb64(j.Y)
+			if err != nil { return nil, fmt.Errorf("jwk %q: invalid y: %w", j.Kid, err) }
+
+			// Round-trip through the uncompressed point encoding so off-curve points are rejected
+			point := append([]byte{4}, append(leftPad(x, 32), leftPad(y, 32)...)...)
+			ecdhKey, err := ecdh.P256().NewPublicKey(point)
+			if err != nil { return nil, fmt.Errorf("jwk %q: invalid EC point: %w", j.Kid, err) }
+			parsed, err := x509.ParsePKIXPublicKey(mustMarshalPKIX(ecdhKey))
+			if err != nil { return nil, fmt.Errorf("jwk %q: %w", j.Kid, err) }
+			pub = parsed
+		case "OKP":
+			if j.Crv != "Ed25519" { return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv) }
+			x, err := unb64(j.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("jwk %q: invalid Ed25519 public key", j.Kid)
+			}
+			pub = ed25519.PublicKey(x)
+		default:
+			return nil, fmt.Errorf("jwk %q: unsupported key type %q", j.Kid, j.Kty)
+	}
+
+	alg, err := algorithmFor(pub, j.Alg)
+	if err != nil { return nil, fmt.Errorf("jwk %q: %w", j.Kid, err) }
+	return &Key{ID: j.Kid, Algorithm: alg, Public: pub}, nil
+}
+
+func b64(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+func unb64(data string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(data) }
+
+func leftPad(data []byte, size int) []byte {
+	if len(data) >= size { return data }
+	return append(make([]byte, size-len(data)), data...)
+}
+
+func mustMarshalPKIX(pub any) []byte {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	return der
+}
+
+// Encodes every key in the source as a JWKS document
+func NewJWKS(keys []*Key) (JWKS, error) {
+	out := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := key.JWK()
+		if err != nil { return JWKS{}, err }
+		out.Keys = append(out.Keys, jwk)
+	}
+	return out, nil
+}
+
+// Verification keys fetched from a JWKS endpoint and cached for ttl.
+// An unknown kid triggers an early refetch so rotated keys are picked up without waiting for the cache.
+// Fetches run outside the lock and one at a time: callers with a cached key keep verifying meanwhile,
+// callers missing their kid wait for the in-flight fetch instead of starting another.
+type RemoteKeySet struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*Key
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	inflight    chan struct{} // closed when the running fetch finishes
+}
+
+func NewRemoteKeySet(url string, ttl time.Duration) *RemoteKeySet {
+	if ttl <= 0 { ttl = DEFAULT_JWKS_CACHE_SEC * time.Second }
+	return &RemoteKeySet{url: url, ttl: ttl, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *RemoteKeySet) Lookup(kid string) (*Key, error) {
+	s.mu.Lock()
+	key, found := s.find(kid)
+	if found && time.Since(s.fetchedAt) <= s.ttl {
+		s.mu.Unlock()
+		return key, nil
+	}
+
+	if wait := s.inflight; wait != nil {
+		s.mu.Unlock()
+		if found { return key, nil }
+		<-wait
+		return s.cached(kid)
+	}
+
+	if time.Since(s.attemptedAt) < minJWKSRefresh {
+		s.mu.Unlock()
+		if found { return key, nil }
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	wait := make(chan struct{})
+	s.inflight, s.attemptedAt = wait, time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys, s.fetchedAt = keys, time.Now()
+		key, found = s.find(kid)
+	}
+	s.inflight = nil
+	close(wait)
+	s.mu.Unlock()
+
+	if err != nil {
+		// Keep verifying with the cached keys while the issuer is unreachable
+		logger.Error("failed to refresh jwks from "+s.url, err)
+		if found { return key, nil }
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	if !found { return nil, fmt.Errorf("unknown signing key %q", kid) }
+	return key, nil
+}
+
+// Looks kid up in the cache without refetching
+func (s *RemoteKeySet) cached(kid string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, found := s.find(kid); found { return key, nil }
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *RemoteKeySet) find(kid string) (*Key, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys { return key, true }
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Fetches the JWKS document; keys that can't be decoded are skipped
+func (s *RemoteKeySet) fetch() (map[string]*Key, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil { return nil, err }
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil { return nil, err }
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK { return nil, fmt.Errorf("jwks endpoint returned %d", res.StatusCode) }
+
+	var doc JWKS
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid jwks document: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		key, err := jwk.Key()
+		if err != nil {
+			logger.Warn("skipping jwk: " + err.Error())
+			continue
+		}
+		keys[key.ID] = key
+	}
+	if len(keys) == 0 { return nil, fmt.Errorf("jwks document has no usable keys") }
+
+	logger.Info(fmt.Sprintf("loaded %d signing keys from %s", len(keys), s.url))
+	return keys, nil
+}
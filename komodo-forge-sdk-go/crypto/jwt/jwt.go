@@ -1,136 +1,286 @@
 package jwt
 
 import (
-	"crypto/rsa"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/http/services/revocation"
+	logger "komodo-forge-sdk-go/logging/runtime"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 var (
-	cachedPrivateKey *rsa.PrivateKey
-	cachedPublicKey  *rsa.PublicKey
-	kid      				 string
+	keyring          *Keyring  // local keys, present when this service issues tokens
+	verifier         KeySource // keyring or the issuer's remote JWKS
 	iss           	 string
-	aud           	 string
+	audiences        []string // JWT_AUDIENCE entries this service accepts
 	keyMutex         sync.RWMutex
 	keysInitialized  bool
 )
 
+const (
+	DEFAULT_KEY_RETENTION_SEC = 86400 // how long a rotated-out key still verifies tokens
+	DEFAULT_KEY_REFRESH_SEC   = 300   // how often issuers re-read key configuration
+)
+
 // CustomClaims defines type-safe claims for your application
 type CustomClaims struct {
-	Scopes []string `json:"scp,omitempty"`
-	IsAdmin bool   `json:"adm,omitempty"`
+	Scopes   []string `json:"scp,omitempty"`
+	IsAdmin  bool     `json:"adm,omitempty"`
+	ClientID string   `json:"client_id,omitempty"` // client the token was issued to (RFC 8693 Section 4.3)
+	Actor    *Actor   `json:"act,omitempty"`       // party acting on the subject's behalf after a token exchange
 	jwt.RegisteredClaims
 }
 
-// Loads RSA keys and assigns a KID for rotation support
-func InitializeKeys() error {
-	if keysInitialized { return nil }
+// Delegation chain (RFC 8693 Section 4.1): the current actor, with earlier actors nested inside
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
 
+// Previously published key kept for verification after a rotation (JWT_PREVIOUS_KEYS entries)
+type previousKey struct {
+	Kid       string `json:"kid"`
+	Alg       string `json:"alg,omitempty"` // defaults by key type
+	PublicKey string `json:"publicKey"`     // PEM
+}
+
+// Loads verification and signing keys.
+// Issuers configure JWT_PRIVATE_KEY/JWT_PUBLIC_KEY/JWT_KID (plus JWT_PREVIOUS_KEYS); services that only
+// verify tokens set JWT_JWKS_URL and fetch the issuer's published keys instead.
+func InitializeKeys() error {
 	keyMutex.Lock()
 	defer keyMutex.Unlock()
+	if keysInitialized { return nil }
 
-	kid = config.GetConfigValue("JWT_KID")
 	iss = config.GetConfigValue("JWT_ISSUER")
-	aud = config.GetConfigValue("JWT_AUDIENCE")
-	privKey := config.GetConfigValue("JWT_PRIVATE_KEY")
-	pubKey := config.GetConfigValue("JWT_PUBLIC_KEY")
+	audiences = parseAudiences(config.GetConfigValue("JWT_AUDIENCE"))
+
+	jwksURL := config.GetConfigValue("JWT_JWKS_URL")
+	if jwksURL != "" && config.GetConfigValue("JWT_PRIVATE_KEY") == "" {
+		verifier = NewRemoteKeySet(jwksURL, config.GetSeconds("JWT_JWKS_CACHE_SEC", DEFAULT_JWKS_CACHE_SEC))
+		keysInitialized = true
+		return nil
+	}
+
+	ring := NewKeyring()
+	if err := loadKeys(ring); err != nil { return err }
+
+	keyring, verifier = ring, ring
+	keysInitialized = true
+	return nil
+}
+
+// Re-reads key configuration and rotates the active key when JWT_KID changes.
+// Rotated-out keys keep verifying tokens for JWT_KEY_RETENTION_SEC.
+func ReloadKeys() error {
+	keyMutex.RLock()
+	ring := keyring
+	keyMutex.RUnlock()
+	if ring == nil { return fmt.Errorf("failed to reload keys: no local keyring") }
+
+	prevKid := ""
+	if active := ring.Active(); active != nil { prevKid = active.ID }
 
-	if privKey == "" || pubKey == "" {
+	if err := loadKeys(ring); err != nil { return err }
+	ring.Prune(config.GetSeconds("JWT_KEY_RETENTION_SEC", DEFAULT_KEY_RETENTION_SEC))
+
+	if active := ring.Active(); active != nil && active.ID != prevKid {
+		logger.Info(fmt.Sprintf("rotated jwt signing key from %q to %q", prevKid, active.ID))
+	}
+	return nil
+}
+
+// Calls fetch (e.g. a Secrets Manager refresh) every JWT_KEY_REFRESH_SEC and reloads the keyring; blocks forever
+func WatchKeys(fetch func() error) {
+	ticker := time.NewTicker(config.GetSeconds("JWT_KEY_REFRESH_SEC", DEFAULT_KEY_REFRESH_SEC))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := fetch(); err != nil {
+			logger.Error("failed to refresh jwt key configuration", err)
+			continue
+		}
+		if err := ReloadKeys(); err != nil {
+			logger.Error("failed to reload jwt keys", err)
+		}
+	}
+}
+
+// Returns the public keys this service signs and verifies with, active key first
+func PublicJWKS() (JWKS, error) {
+	keyMutex.RLock()
+	ring := keyring
+	keyMutex.RUnlock()
+	if ring == nil { return JWKS{}, fmt.Errorf("jwt keys not initialized") }
+
+	return NewJWKS(ring.Keys())
+}
+
+// Builds the active key from JWT_PRIVATE_KEY/JWT_PUBLIC_KEY/JWT_KID/JWT_ALG and adds JWT_PREVIOUS_KEYS.
+// JWT_ALG picks RS256 or PS256 for RSA keys; EC (P-256) and Ed25519 keys imply ES256 and EdDSA.
+func loadKeys(ring *Keyring) error {
+	privPEM := config.GetConfigValue("JWT_PRIVATE_KEY")
+	pubPEM := config.GetConfigValue("JWT_PUBLIC_KEY")
+	if privPEM == "" && pubPEM == "" {
 		return fmt.Errorf("JWT keys not fully configured in environment")
 	}
 
-	var err error
-	cachedPrivateKey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(privKey))
-	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
+	key := &Key{ID: config.GetConfigValue("JWT_KID")}
+	if privPEM != "" {
+		priv, pub, err := parsePrivateKeyPEM(privPEM)
+		if err != nil { return fmt.Errorf("failed to parse private key: %w", err) }
+		key.private, key.Public = priv, pub
 	}
+	if pubPEM != "" {
+		pub, err := parsePublicKeyPEM(pubPEM)
+		if err != nil { return fmt.Errorf("failed to parse public key: %w", err) }
+		key.Public = pub
+	}
+	if key.ID == "" { return fmt.Errorf("JWT_KID not configured") }
 
-	cachedPublicKey, err = jwt.ParseRSAPublicKeyFromPEM([]byte(pubKey))
-	if err != nil {
-		return fmt.Errorf("failed to parse public key: %w", err)
+	alg, err := algorithmFor(key.Public, config.GetConfigValue("JWT_ALG"))
+	if err != nil { return fmt.Errorf("invalid signing key %q: %w", key.ID, err) }
+	key.Algorithm = alg
+
+	if key.CanSign() {
+		if err := ring.SetActive(key); err != nil { return err }
+	} else {
+		ring.Add(key)
 	}
 
-	keysInitialized = true
+	raw := config.GetConfigValue("JWT_PREVIOUS_KEYS")
+	if raw == "" { return nil }
+
+	var previous []previousKey
+	if err := json.Unmarshal([]byte(raw), &previous); err != nil {
+		return fmt.Errorf("failed to parse JWT_PREVIOUS_KEYS: %w", err)
+	}
+	for _, prev := range previous {
+		pub, err := parsePublicKeyPEM(prev.PublicKey)
+		if err != nil { return fmt.Errorf("failed to parse previous key %q: %w", prev.Kid, err) }
+
+		alg, err := algorithmFor(pub, prev.Alg)
+		if err != nil { return fmt.Errorf("invalid previous key %q: %w", prev.Kid, err) }
+		ring.Add(&Key{ID: prev.Kid, Algorithm: alg, Public: pub})
+	}
 	return nil
 }
 
-// SignToken creates a signed JWS with a KID in the header
+// SignToken creates a signed JWS with the active key's KID in the header
 func SignToken(issuer string, subject string, audience string, ttl int64, scopes []string) (string, error) {
+	return SignClaims(CustomClaims{
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  subject,
+			Issuer:   issuer,
+			Audience: jwt.ClaimStrings{audience},
+		},
+	}, ttl)
+}
+
+// Signs caller-built claims (e.g. with an actor chain); expiry, issue time and JTI are set here
+func SignClaims(claims CustomClaims, ttl int64) (string, error) {
 	if !keysInitialized {
 		return "", fmt.Errorf("failed to sign token: jwt keys not initialized")
 	}
 
 	keyMutex.RLock()
-	defer keyMutex.RUnlock()
+	ring := keyring
+	keyMutex.RUnlock()
 
-	claims := CustomClaims{
-		Scopes: scopes,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   subject,
-			Issuer:    issuer,
-			Audience:  jwt.ClaimStrings{audience},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(ttl) * time.Second)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			ID:        uuid.NewString(),
-		},
+	var key *Key
+	if ring != nil { key = ring.Active() }
+	if key == nil {
+		return "", fmt.Errorf("failed to sign token: no active signing key")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = kid
-	return token.SignedString(cachedPrivateKey)
+	now := time.Now()
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(ttl) * time.Second))
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ID = uuid.NewString()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Resolves the verification key from the token's kid and checks it was signed with that key's algorithm
+func keyFunc(src KeySource) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := src.Lookup(kid)
+		if err != nil { return nil, err }
+
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %v for kid %q", t.Header["alg"], kid)
+		}
+		return key.Public, nil
+	}
 }
 
 // Validates token signature, expiration, issuer, and audience.
-// If expectedIssuer or expectedAudience are empty, they will be retrieved from local env.
+// The token must be aimed at one of the audiences in JWT_AUDIENCE (comma separated).
 func ValidateToken(tokenString string) (bool, error) {
+	if len(audiences) == 0 { return false, fmt.Errorf("missing jwt audience") }
+
+	if _, err := VerifyToken(tokenString, audiences...); err != nil { return false, err }
+	return true, nil
+}
+
+// Verifies signature, expiration, issuer and revocation and returns the claims.
+// The token must carry one of the given audiences; with none the audience is not checked,
+// which only suits callers like token exchange that inspect it themselves.
+func VerifyToken(tokenString string, accepted ...string) (*CustomClaims, error) {
 	if !keysInitialized {
-		return false, fmt.Errorf("failed to validate token: jwt keys not initialized")
+		return nil, fmt.Errorf("failed to validate token: jwt keys not initialized")
 	}
 
 	keyMutex.RLock()
-	pub := cachedPublicKey
+	src := verifier
 	keyMutex.RUnlock()
 
-	if iss == "" { return false, fmt.Errorf("missing jwt issuer") }
-	if aud == "" { return false, fmt.Errorf("missing jwt audience") }
+	if iss == "" { return nil, fmt.Errorf("missing jwt issuer") }
 
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&CustomClaims{},
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return pub, nil
-		},
-		jwt.WithIssuer(iss),
-		jwt.WithAudience(aud),
-		jwt.WithValidMethods([]string{"RS256"}),
-	)
+	opts := []jwt.ParserOption{jwt.WithIssuer(iss), jwt.WithValidMethods(supportedAlgorithms)}
+	if len(accepted) > 0 { opts = append(opts, jwt.WithAudience(accepted...)) }
 
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, keyFunc(src), opts...)
 	if err != nil {
-		return false, fmt.Errorf("verification failed: %w", err)
+		return nil, fmt.Errorf("verification failed: %w", err)
 	}
 	if !token.Valid {
-		return false, fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	// If the JTI is in Redis, it means this token was revoked/logged out
-	// if isBlacklisted(context.Background(), claims.ID) {
-	// 	return false, fmt.Errorf("token has been revoked")
-	// }
+	// Revoked JTIs and tokens issued before the subject's last "revoke all" are rejected
+	claims := token.Claims.(*CustomClaims)
+	if err := CheckRevocation(context.Background(), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
 
-	return true, nil
+// Returns the audiences this service accepts (JWT_AUDIENCE)
+func Audiences() []string { return slices.Clone(audiences) }
+
+func parseAudiences(value string) []string {
+	out := make([]string, 0)
+	for _, aud := range strings.Split(value, ",") {
+		if aud = strings.TrimSpace(aud); aud != "" { out = append(out, aud) }
+	}
+	return out
 }
 
 // Parses and returns claims from a token string
@@ -140,18 +290,14 @@ func ParseClaims(tokenString string) (*CustomClaims, error) {
 	}
 
 	keyMutex.RLock()
-	pub := cachedPublicKey
+	src := verifier
 	keyMutex.RUnlock()
 
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&CustomClaims{},
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return pub, nil
-		},
+		keyFunc(src),
+		jwt.WithValidMethods(supportedAlgorithms),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -172,3 +318,15 @@ func ExtractTokenFromRequest(req *http.Request) (string, error) {
 	}
 	return strings.TrimPrefix(auth, "Bearer "), nil
 }
+
+// Returns an error when the token was revoked by JTI or by its subject's not-before watermark
+func CheckRevocation(ctx context.Context, claims *CustomClaims) error {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil { issuedAt = claims.IssuedAt.Time }
+
+	if err := revocation.Check(ctx, claims.ID, claims.Subject, issuedAt); err != nil {
+		if errors.Is(err, revocation.ErrRevoked) { return err }
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"crypto"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A signing or verification key identified by its kid
+type Key struct {
+	ID        string
+	Algorithm string // JWS alg, e.g. RS256
+	Public    crypto.PublicKey
+	private   crypto.PrivateKey // nil for verification-only keys
+	retiredAt time.Time         // when it stopped being the active key
+}
+
+func (k *Key) CanSign() bool { return k.private != nil }
+
+// Looks up verification keys by kid
+type KeySource interface {
+	Lookup(kid string) (*Key, error)
+}
+
+// Holds the active signing key plus previous keys that tokens in circulation may still be signed with
+type Keyring struct {
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]*Key)}
+}
+
+// Makes key the signing key; the previous active key stays available for verification
+func (r *Keyring) SetActive(key *Key) error {
+	if key == nil || !key.CanSign() { return fmt.Errorf("active key must include a private key") }
+	if key.ID == "" { return fmt.Errorf("active key must have a kid") }
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active != nil && r.active.ID != key.ID {
+		retired := *r.active
+		retired.private = nil
+		retired.retiredAt = time.Now()
+		r.keys[retired.ID] = &retired
+	}
+	key.retiredAt = time.Time{}
+	r.active = key
+	r.keys[key.ID] = key
+	return nil
+}
+
+// Adds a verification-only key (e.g. one published before the last rotation)
+func (r *Keyring) Add(key *Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active != nil && r.active.ID == key.ID { return }
+	r.keys[key.ID] = key
+}
+
+// Drops keys retired longer than retention ago
+func (r *Keyring) Prune(retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	for kid, key := range r.keys {
+		if !key.retiredAt.IsZero() && key.retiredAt.Before(cutoff) { delete(r.keys, kid) }
+	}
+}
+
+// Returns the signing key, or nil for a verification-only keyring
+func (r *Keyring) Active() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// Returns the key for kid; tokens without a kid fall back to the active key
+func (r *Keyring) Lookup(kid string) (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if kid == "" {
+		if r.active != nil { return r.active, nil }
+		if len(r.keys) == 1 {
+			for _, key := range r.keys { return key, nil }
+		}
+		return nil, fmt.Errorf("token has no kid")
+	}
+
+	key, ok := r.keys[kid]
+	if !ok { return nil, fmt.Errorf("unknown signing key %q", kid) }
+	return key, nil
+}
+
+// Returns every verification key, active first
+func (r *Keyring) Keys() []*Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*Key, 0, len(r.keys))
+	for _, key := range r.keys { out = append(out, key) }
+	slices.SortFunc(out, func(a, b *Key) int {
+		switch {
+			case r.active != nil && a.ID == r.active.ID:
+				return -1
+			case r.active != nil && b.ID == r.active.ID:
+				return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out
+}
@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil { t.Fatalf("generate key: %v", err) }
+	return &Key{ID: kid, Algorithm: "RS256", Public: &priv.PublicKey, private: priv}
+}
+
+func signWith(t *testing.T, key *Key) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), jwt.MapClaims{"sub": "user-1"})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.private)
+	if err != nil { t.Fatalf("sign: %v", err) }
+	return signed
+}
+
+func TestKeyringRotationAndRemoteJWKS(t *testing.T) {
+	ring := NewKeyring()
+	oldKey, newKey := newTestKey(t, "2026-09"), newTestKey(t, "2026-10")
+
+	ring.SetActive(oldKey)
+	oldToken := signWith(t, oldKey)
+	ring.SetActive(newKey)
+	newToken := signWith(t, newKey)
+
+	if active := ring.Active(); active.ID != "2026-10" {
+		t.Fatalf("active kid = %q, want 2026-10", active.ID)
+	}
+	for _, tok := range []string{oldToken, newToken} {
+		if _, err := jwt.Parse(tok, keyFunc(ring)); err != nil {
+			t.Errorf("keyring rejected token signed before rotation: %v", err)
+		}
+	}
+
+	doc, err := NewJWKS(ring.Keys())
+	if err != nil { t.Fatalf("jwks: %v", err) }
+	if len(doc.Keys) != 2 || doc.Keys[0].Kid != "2026-10" {
+		t.Fatalf("jwks = %+v, want both keys with the active one first", doc.Keys)
+	}
+
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		fetches++
+		json.NewEncoder(wtr).Encode(doc)
+	}))
+	defer srv.Close()
+
+	remote := NewRemoteKeySet(srv.URL, time.Minute)
+	for _, tok := range []string{newToken, oldToken} {
+		if _, err := jwt.Parse(tok, keyFunc(remote)); err != nil {
+			t.Errorf("remote key set rejected token: %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("jwks fetched %d times, want 1 (cached)", fetches)
+	}
+
+	ring.Prune(0)
+	if _, err := ring.Lookup("2026-09"); err == nil {
+		t.Error("retired key still present after prune")
+	}
+}
+
+func TestRemoteKeySetFetchesOutsideLock(t *testing.T) {
+	oldKey, newKey := newTestKey(t, "2026-09"), newTestKey(t, "2026-10")
+	served := []*Key{oldKey}
+
+	var fetches atomic.Int32
+	arrived, release := make(chan struct{}, 8), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if fetches.Add(1) > 1 {
+			arrived <- struct{}{}
+			<-release
+		}
+		doc, _ := NewJWKS(served)
+		json.NewEncoder(wtr).Encode(doc)
+	}))
+	defer srv.Close()
+
+	remote := NewRemoteKeySet(srv.URL, time.Minute)
+	if _, err := remote.Lookup("2026-09"); err != nil { t.Fatalf("initial lookup: %v", err) }
+
+	// Expire the cache and rotate: the next fetch hangs until released
+	remote.mu.Lock()
+	remote.fetchedAt, remote.attemptedAt = time.Time{}, time.Time{}
+	remote.mu.Unlock()
+	served = []*Key{newKey, oldKey}
+
+	results := make(chan error, 4)
+	go func() { _, err := remote.Lookup("2026-09"); results <- err }()
+	<-arrived
+
+	// A cached key verifies while the fetch is in flight
+	done := make(chan error, 1)
+	go func() { _, err := remote.Lookup("2026-09"); done <- err }()
+	select {
+		case err := <-done:
+			if err != nil { t.Errorf("cached key during refresh: %v", err) }
+		case <-time.After(time.Second):
+			t.Fatal("lookup of a cached key blocked on the in-flight fetch")
+	}
+
+	// Callers missing their kid share the running fetch
+	for range 3 {
+		go func() { _, err := remote.Lookup("2026-10"); results <- err }()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for range 4 {
+		if err := <-results; err != nil { t.Errorf("lookup after refresh: %v", err) }
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("jwks fetched %d times, want 2 (one refresh shared by every caller)", got)
+	}
+}
+
+func TestCurveKeysRoundTripThroughJWKS(t *testing.T) {
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil { t.Fatalf("generate ec key: %v", err) }
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil { t.Fatalf("generate ed25519 key: %v", err) }
+
+	ring := NewKeyring()
+	ecKey := &Key{ID: "ec", Algorithm: AlgES256, Public: &ecPriv.PublicKey, private: ecPriv}
+	edKey := &Key{ID: "ed", Algorithm: AlgEdDSA, Public: edPub, private: edPriv}
+	ring.SetActive(ecKey)
+	ring.SetActive(edKey)
+
+	doc, err := NewJWKS(ring.Keys())
+	if err != nil { t.Fatalf("jwks: %v", err) }
+
+	remote := NewKeyring()
+	for _, jwk := range doc.Keys {
+		key, err := jwk.Key()
+		if err != nil { t.Fatalf("decode %s jwk: %v", jwk.Kty, err) }
+		remote.Add(key)
+	}
+
+	for _, key := range []*Key{ecKey, edKey} {
+		if _, err := jwt.Parse(signWith(t, key), keyFunc(remote)); err != nil {
+			t.Errorf("%s token rejected after jwks round trip: %v", key.Algorithm, err)
+		}
+	}
+
+	// A token claiming a different alg than the kid was published with must not verify
+	rsaKey := newTestKey(t, "ec")
+	if _, err := jwt.Parse(signWith(t, rsaKey), keyFunc(remote)); err == nil {
+		t.Error("RS256 token accepted for an ES256 kid")
+	}
+
+	if _, err := (JWK{Kty: "EC", Crv: "P-256", Kid: "x", Alg: AlgRS256, X: doc.Keys[1].X, Y: doc.Keys[1].Y}).Key(); err == nil {
+		t.Error("EC jwk declaring RS256 was accepted")
+	}
+}
+
+func TestVerifyTokenAudiencesAndActor(t *testing.T) {
+	ring := NewKeyring()
+	ring.SetActive(newTestKey(t, "aud-test"))
+
+	keyMutex.Lock()
+	prevRing, prevVerifier, prevIss, prevAud, prevInit := keyring, verifier, iss, audiences, keysInitialized
+	keyring, verifier, iss, audiences, keysInitialized = ring, ring, "komodo-auth-api", parseAudiences("komodo-payments-api, komodo-apis:user"), true
+	keyMutex.Unlock()
+	defer func() {
+		keyMutex.Lock()
+		keyring, verifier, iss, audiences, keysInitialized = prevRing, prevVerifier, prevIss, prevAud, prevInit
+		keyMutex.Unlock()
+	}()
+
+	exchanged, err := SignClaims(CustomClaims{
+		Scopes: []string{"payments:read"},
+		Actor:  &Actor{Subject: "order-api", Actor: &Actor{Subject: "komodo-ssr"}},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "user-1", Issuer: "komodo-auth-api", Audience: jwt.ClaimStrings{"komodo-payments-api"},
+		},
+	}, 60)
+	if err != nil { t.Fatalf("sign: %v", err) }
+
+	if ok, err := ValidateToken(exchanged); !ok {
+		t.Fatalf("token for an accepted audience rejected: %v", err)
+	}
+	claims, err := VerifyToken(exchanged, "komodo-payments-api")
+	if err != nil { t.Fatalf("verify: %v", err) }
+	if claims.Actor == nil || claims.Actor.Subject != "order-api" || claims.Actor.Actor.Subject != "komodo-ssr" {
+		t.Errorf("actor chain = %+v, want order-api acting after komodo-ssr", claims.Actor)
+	}
+
+	other, err := SignToken("komodo-auth-api", "user-1", "komodo-order-api", 60, nil)
+	if err != nil { t.Fatalf("sign: %v", err) }
+	if ok, _ := ValidateToken(other); ok {
+		t.Error("token for another service's audience was accepted")
+	}
+	if _, err := VerifyToken(other); err != nil {
+		t.Errorf("verify without audiences should skip the audience check: %v", err)
+	}
+}
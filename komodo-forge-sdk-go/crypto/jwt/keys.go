@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// Signing algorithms a key may declare
+const (
+	AlgRS256 = "RS256"
+	AlgPS256 = "PS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var supportedAlgorithms = []string{AlgRS256, AlgPS256, AlgES256, AlgEdDSA}
+
+// Parses a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key
+func parsePrivateKeyPEM(data string) (crypto.PrivateKey, crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil { return nil, nil, fmt.Errorf("no PEM block found") }
+
+	var priv any
+	var err error
+	switch block.Type {
+		case "RSA PRIVATE KEY":
+			priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			priv, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil { return nil, nil, err }
+
+	signer, ok := priv.(crypto.Signer)
+	if !ok { return nil, nil, fmt.Errorf("unsupported private key type %T", priv) }
+	return priv, signer.Public(), nil
+}
+
+// Parses a PKIX (or PKCS#1 RSA) public key
+func parsePublicKeyPEM(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil { return nil, fmt.Errorf("no PEM block found") }
+
+	if block.Type == "RSA PUBLIC KEY" { return x509.ParsePKCS1PublicKey(block.Bytes) }
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Resolves the algorithm for a key: the declared one if it fits the key type, else the key type's default
+func algorithmFor(pub crypto.PublicKey, declared string) (string, error) {
+	var allowed []string
+	switch key := pub.(type) {
+		case *rsa.PublicKey:
+			allowed = []string{AlgRS256, AlgPS256}
+		case *ecdsa.PublicKey:
+			if key.Curve != elliptic.P256() { return "", fmt.Errorf("unsupported EC curve %s", key.Curve.Params().Name) }
+			allowed = []string{AlgES256}
+		case ed25519.PublicKey:
+			allowed = []string{AlgEdDSA}
+		default:
+			return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	if declared == "" { return allowed[0], nil }
+	for _, alg := range allowed {
+		if strings.EqualFold(alg, declared) { return alg, nil }
+	}
+	return "", fmt.Errorf("algorithm %s does not match %T key", declared, pub)
+}
@@ -26,6 +26,7 @@ const (
 	IS_ADMIN_KEY          ctxKey = "is_admin"
 	IDEMPOTENCY_KEY       ctxKey = "idempotency_key"
 	IDEMPOTENCY_VALID_KEY ctxKey = "idempotency_key_valid"
+	JSON_BODY_KEY         ctxKey = "json_body"
 	CSRF_TOKEN_KEY      	ctxKey = "csrf_token"
 	CSRF_VALID_KEY      	ctxKey = "csrf_token_valid"
 	LOGGER_KEY          	ctxKey = "logger"
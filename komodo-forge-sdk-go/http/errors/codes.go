@@ -17,6 +17,7 @@ type GlobalErrors struct {
 	Forbidden           ErrorCode
 	NotFound            ErrorCode
 	MethodNotAllowed    ErrorCode
+	NotAcceptable       ErrorCode
 	Conflict            ErrorCode
 	UnprocessableEntity ErrorCode
 	TooManyRequests     ErrorCode
@@ -25,6 +26,7 @@ type GlobalErrors struct {
 	BadGateway          ErrorCode
 	ServiceUnavailable  ErrorCode
 	GatewayTimeout      ErrorCode
+	PayloadTooLarge     ErrorCode
 	MockNotFound        ErrorCode
 }
 
@@ -43,6 +45,8 @@ var Global = GlobalErrors{
 	BadGateway: 					ErrorCode{ID: "10012", Status: http.StatusBadGateway, Message: "Bad gateway"},
 	ServiceUnavailable: 	ErrorCode{ID: "10013", Status: http.StatusServiceUnavailable, Message: "Service unavailable"},
 	GatewayTimeout: 			ErrorCode{ID: "10014", Status: http.StatusGatewayTimeout, Message: "Gateway timeout"},
+	NotAcceptable: 				ErrorCode{ID: "10015", Status: http.StatusNotAcceptable, Message: "Not acceptable"},
+	PayloadTooLarge: 			ErrorCode{ID: "10016", Status: http.StatusRequestEntityTooLarge, Message: "Payload too large"},
 }
 
 // 11xxx errors
@@ -76,6 +80,8 @@ type AuthErrors struct {
 	InvalidRedirectURI       ErrorCode
 	AccessDenied             ErrorCode
 	InsufficientScope        ErrorCode
+	InvalidTarget            ErrorCode
+	InvalidSession           ErrorCode
 }
 
 var Auth = AuthErrors{
@@ -91,6 +97,8 @@ var Auth = AuthErrors{
 	InvalidRedirectURI:       ErrorCode{ID: "20010", Status: http.StatusBadRequest, Message: "Invalid redirect URI"},
 	AccessDenied:             ErrorCode{ID: "20011", Status: http.StatusForbidden, Message: "Access denied"},
 	InsufficientScope:        ErrorCode{ID: "20012", Status: http.StatusForbidden, Message: "Insufficient scope"},
+	InvalidTarget:            ErrorCode{ID: "20013", Status: http.StatusBadRequest, Message: "Invalid target audience"},
+	InvalidSession:           ErrorCode{ID: "20014", Status: http.StatusUnauthorized, Message: "Invalid or expired session"},
 }
 
 // 30xxx errors
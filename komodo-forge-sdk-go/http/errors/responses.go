@@ -13,12 +13,31 @@ type ErrorResponse struct {
 	Detail  string `json:"detail,omitempty"`
 	RequestId string `json:"request_id"`
 	Timestamp string `json:"timestamp"`
+
+	Extensions map[string]any `json:"-"` // RFC 7807 extension members (e.g. invalid-params)
 }
 
 type ErrorOverride struct {
 	Message *string
 	Detail  *string
 	Status  *int
+	Extensions map[string]any
+}
+
+// Flattens extension members alongside the standard fields; standard fields win on collision
+func (e ErrorResponse) MarshalJSON() ([]byte, error) {
+	type plain ErrorResponse
+	data, err := json.Marshal(plain(e))
+	if err != nil || len(e.Extensions) == 0 { return data, err }
+
+	merged := make(map[string]any, len(e.Extensions) + 6)
+	for k, v := range e.Extensions { merged[k] = v }
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil { return nil, err }
+	for k, v := range fields { merged[k] = v }
+
+	return json.Marshal(merged)
 }
 
 // Sends a formatted error response
@@ -28,12 +47,17 @@ func SendError(wtr http.ResponseWriter, req *http.Request, errCode ErrorCode, ov
 	status := errCode.Status
 	message := errCode.Message
 	detail := ""
+	var extensions map[string]any
 
-	if len(overrides) > 0 {
-		override := overrides[0]
+	// Later overrides win, so callers can combine e.g. WithDetail and WithInvalidParams
+	for _, override := range overrides {
 		if override.Status != nil { status = *override.Status }
 		if override.Message != nil { message = *override.Message }
 		if override.Detail != nil { detail = *override.Detail }
+		for k, v := range override.Extensions {
+			if extensions == nil { extensions = make(map[string]any) }
+			extensions[k] = v
+		}
 	}
 
 	wtr.WriteHeader(status)
@@ -44,6 +68,7 @@ func SendError(wtr http.ResponseWriter, req *http.Request, errCode ErrorCode, ov
 		Detail:    detail,
 		RequestId: req.Header.Get("X-Request-ID"),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Extensions: extensions,
 	})
 }
 
@@ -61,6 +86,14 @@ func WithDetail(detail string) ErrorOverride { return ErrorOverride{Detail: &det
 // Returns override for error status
 func WithStatus(status int) ErrorOverride { return ErrorOverride{Status: &status} }
 
+// Returns override adding an RFC 7807 extension member to the error body
+func WithExtension(key string, value any) ErrorOverride {
+	return ErrorOverride{Extensions: map[string]any{key: value}}
+}
+
+// Returns override listing the request fields that failed validation (RFC 7807 invalid-params)
+func WithInvalidParams(params any) ErrorOverride { return WithExtension("invalid-params", params) }
+
 // Returns overrides for error codes
 func WithOverrides(message string, detail string, status int) ErrorOverride { 
 	return ErrorOverride{Message: &message, Detail: &detail, Status: &status} 
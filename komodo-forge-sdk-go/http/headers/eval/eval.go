@@ -3,12 +3,22 @@ package headerEval
 import (
 	"komodo-forge-sdk-go/config"
 	"komodo-forge-sdk-go/crypto/jwt"
+	corssvc "komodo-forge-sdk-go/http/services/cors"
+	csrfsvc "komodo-forge-sdk-go/http/services/csrf"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// Compiled once; these run on every validated request
+var (
+	userAgentRE      = regexp.MustCompile(`^[A-Za-z0-9\-\._ /(),:;]+$`)
+	originURLRE      = regexp.MustCompile(`^https?://[A-Za-z0-9\-.%]+(?::\d{1,5})?(?:/.*)?$`)
+	requestedByRE    = regexp.MustCompile(`^[A-Za-z0-9_\-/]+$`)
+	idempotencyKeyRE = regexp.MustCompile(`^[A-Za-z0-9_\-]{8,64}$`)
+)
+
 // ValidateHeaderValue runs lightweight validation for known header names.
 func ValidateHeaderValue(hdr string, req *http.Request) (bool, error) {
 	val := req.Header.Get(hdr)
@@ -27,6 +37,8 @@ func ValidateHeaderValue(hdr string, req *http.Request) (bool, error) {
 			return isValidContentLength(val), nil
 		case "idempotency-key":
 			return isValidIdempotencyKey(val), nil
+		case "origin":
+			return corssvc.OriginAllowed(req, val), nil
 		case "referer", "referrer":
 			return isValidReferer(val), nil
 		case "user-agent":
@@ -73,13 +85,11 @@ func isValidUserAgent(s string) bool {
 	if s == "" { return false }
 	s = strings.TrimSpace(s)
 	if len(s) > 256 { return false } // max length
-	re := regexp.MustCompile(`^[A-Za-z0-9\-\._ /(),:;]+$`)
-	return re.MatchString(s)
+	return userAgentRE.MatchString(s)
 }
 
 func isValidReferer(s string) bool {
-	re := regexp.MustCompile(`^https?://[A-Za-z0-9\-.%]+(?::\d{1,5})?(?:/.*)?$`)
-	return re.MatchString(strings.TrimSpace(s))
+	return originURLRE.MatchString(strings.TrimSpace(s))
 }
 
 func isValidCacheControl(s string) bool {
@@ -87,22 +97,18 @@ func isValidCacheControl(s string) bool {
 }
 
 func isValidRequestedBy(s string) bool {
-  return s != "" && len(s) <= 64 && regexp.MustCompile(`^[A-Za-z0-9_\-/]+$`).MatchString(s)
+  return s != "" && len(s) <= 64 && requestedByRE.MatchString(s)
 }
 
 func isValidIdempotencyKey(s string) bool {
-	return regexp.MustCompile(`^[A-Za-z0-9_\-]{8,64}$`).MatchString(s)
+	return idempotencyKeyRE.MatchString(s)
 }
 
-func isValidCSRF(s string) bool {
-	if s == "" { return false }
-	// TODO - implement CSRF token validation logic
-	return true
-}
+// Shape check only; CSRFMiddleware verifies the signature and session binding
+func isValidCSRF(s string) bool { return csrfsvc.WellFormed(s) }
 
 func isValidCORS(s string) bool {
-	if s == "" { return false }
 	if s == "*" { return true }
-	re := regexp.MustCompile(`^https?://[A-Za-z0-9\-.%]+(?::\d{1,5})?(?:/.*)?$`)
-	return re.MatchString(strings.TrimSpace(s))
+	_, ok := corssvc.NormalizeOrigin(s)
+	return ok
 }
@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	authzsvc "komodo-forge-sdk-go/http/services/authz"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Enforces the route policies from AUTHZ_POLICIES_PATH. Must run after AuthMiddleware.
+// Without a policy file every request passes through. Core logic lives in services/authz.
+func AuthzMiddleware(next http.Handler) http.Handler {
+	configured, err := authzsvc.LoadPoliciesFromConfig()
+	if err != nil {
+		logger.Error("authorization policies failed to load, denying all requests", err)
+	} else if !configured {
+		logger.Warn("no authorization policies configured (AUTHZ_POLICIES_PATH), routes are not scope checked")
+	}
+
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		// A broken policy file must not leave routes open
+		if err != nil {
+			sendDenied(wtr, req, nil, fmt.Errorf("%w: policies failed to load", authzsvc.ErrNoPolicy))
+			return
+		}
+
+		if err := authzsvc.Authorize(req); err != nil {
+			var required []string
+			if policy := authzsvc.MatchPolicy(req); policy != nil { required = slices.Concat(policy.Scopes, policy.AnyScopes) }
+			sendDenied(wtr, req, required, err)
+			return
+		}
+		next.ServeHTTP(wtr, req)
+	})
+}
+
+// Requires every listed scope, honouring scope hierarchy (orders:write covers orders:read)
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return require(authzsvc.Requirement{Scopes: scopes}, scopes)
+}
+
+// Requires at least one of the listed scopes
+func RequireAnyScope(scopes ...string) func(http.Handler) http.Handler {
+	return require(authzsvc.Requirement{AnyScopes: scopes}, scopes)
+}
+
+// Requires an admin token
+func RequireAdmin() func(http.Handler) http.Handler {
+	return require(authzsvc.Requirement{Admin: true}, nil)
+}
+
+func require(requirement authzsvc.Requirement, advertised []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+			if err := authzsvc.Check(req, requirement); err != nil {
+				sendDenied(wtr, req, advertised, err)
+				return
+			}
+			next.ServeHTTP(wtr, req)
+		})
+	}
+}
+
+// Logs the denial reason and answers 403 (RFC 6750 insufficient_scope for missing scopes)
+func sendDenied(wtr http.ResponseWriter, req *http.Request, required []string, err error) {
+	subject, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+	logger.Error(fmt.Sprintf("authorization denied for %s %s (subject %q)", req.Method, req.URL.Path, subject), err)
+
+	switch {
+		case errors.Is(err, authzsvc.ErrInsufficientScope):
+			wtr.Header().Set(
+				"WWW-Authenticate",
+				fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(required, " ")),
+			)
+			httpErr.SendError(wtr, req, httpErr.Auth.InsufficientScope, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, authzsvc.ErrAdminRequired):
+			httpErr.SendError(wtr, req, httpErr.Auth.AccessDenied, httpErr.WithDetail(err.Error()))
+		default:
+			httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail(err.Error()))
+	}
+}
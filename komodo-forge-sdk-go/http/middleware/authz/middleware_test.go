@@ -0,0 +1,43 @@
+package authz
+
+import (
+	"context"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireScopes(t *testing.T) {
+	handler := RequireScopes("orders:read")(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		wtr.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/me/orders", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without scopes, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `scope="orders:read"`) {
+		t.Errorf("expected insufficient_scope challenge, got %q", got)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), ctxKeys.SCOPES_KEY, []string{"orders:write"}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected orders:write to satisfy orders:read, got %d", rec.Code)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := RequireAdmin()(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for non-admin, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,35 @@
+package clientip
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"komodo-forge-sdk-go/config"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+var (
+	resolverOnce sync.Once
+	resolver     *ipsvc.Resolver
+)
+
+// Resolves the client IP once per request and stores it under CLIENT_IP_KEY.
+// Forwarding headers are only trusted from peers listed in TRUSTED_PROXIES.
+// Must run before any middleware that reads the client IP.
+func ClientIPMiddleware(next http.Handler) http.Handler {
+	// lazy-parse env config once
+	resolverOnce.Do(func() {
+		resolver = ipsvc.NewResolver(config.GetConfigValue("TRUSTED_PROXIES"))
+		logger.Debug("parsed trusted proxies: ", logger.Attr("trustedProxies", config.GetConfigValue("TRUSTED_PROXIES")))
+	})
+
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if ip := resolver.Resolve(req); ip != nil {
+			req = req.WithContext(context.WithValue(req.Context(), ctxKeys.CLIENT_IP_KEY, ip.String()))
+		}
+		next.ServeHTTP(wtr, req)
+	})
+}
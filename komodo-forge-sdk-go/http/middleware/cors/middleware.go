@@ -1,11 +1,83 @@
 package cors
 
-import "net/http"
+import (
+	"fmt"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	"komodo-forge-sdk-go/http/headers"
+	corssvc "komodo-forge-sdk-go/http/services/cors"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
-// Implement CORS security checks
+// Applies the CORS policy matching the route: CORS_POLICIES_PATH overrides, else the CORS_* config values.
+// Preflights are answered here (204, or 403 when the origin, method or headers aren't allowed) and never
+// reach the handler, so services register OPTIONS routes through this middleware.
+// Disallowed origins on other requests get no CORS headers and the browser withholds the response.
+// Core logic lives in services/cors.
 func CORSMiddleware(next http.Handler) http.Handler {
+	configured, err := corssvc.LoadPoliciesFromConfig()
+	if err != nil {
+		logger.Error("cors policies failed to load, cross-origin requests are denied", err)
+		corssvc.SetPolicies(&corssvc.PolicySet{})
+	} else if !configured {
+		logger.Info("no cors policies configured (CORS_POLICIES_PATH), using CORS_* config for all routes")
+	}
+
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
-		// TODO: implement CORS handling
+		// Responses differ per origin, so caches must key on it even when no CORS headers are sent
+		wtr.Header().Add("Vary", headers.HEADER_ORIGIN)
+
+		preflight := corssvc.IsPreflight(req)
+		if preflight {
+			wtr.Header().Add("Vary", corssvc.HEADER_REQUEST_METHOD)
+			wtr.Header().Add("Vary", corssvc.HEADER_REQUEST_HEADERS)
+		}
+
+		origin := req.Header.Get(headers.HEADER_ORIGIN)
+		if origin == "" {
+			next.ServeHTTP(wtr, req)
+			return
+		}
+
+		policy := corssvc.MatchPolicy(req)
+		if !policy.AllowsOrigin(origin) {
+			if preflight {
+				logger.Error("cors preflight rejected", fmt.Errorf("origin %s not allowed by policy %s", origin, policy.Name))
+				httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail("origin not allowed"))
+				return
+			}
+			next.ServeHTTP(wtr, req)
+			return
+		}
+
+		if preflight {
+			if err := policy.CheckPreflight(req); err != nil {
+				logger.Error("cors preflight rejected for origin: " + origin, err)
+				httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail(err.Error()))
+				return
+			}
+
+			setOrigin(wtr, policy, origin)
+			wtr.Header().Set(corssvc.HEADER_ALLOW_METHODS, strings.Join(policy.AllowedMethods, ", "))
+			if requested := req.Header.Get(corssvc.HEADER_REQUEST_HEADERS); requested != "" {
+				wtr.Header().Set(corssvc.HEADER_ALLOW_HEADERS, requested)
+			}
+			wtr.Header().Set(corssvc.HEADER_MAX_AGE, strconv.Itoa(policy.MaxAge()))
+			wtr.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		setOrigin(wtr, policy, origin)
+		if len(policy.ExposedHeaders) > 0 {
+			wtr.Header().Set(corssvc.HEADER_EXPOSE_HEADERS, strings.Join(policy.ExposedHeaders, ", "))
+		}
 		next.ServeHTTP(wtr, req)
 	})
 }
+
+func setOrigin(wtr http.ResponseWriter, policy *corssvc.Policy, origin string) {
+	wtr.Header().Set(corssvc.HEADER_ALLOW_ORIGIN, policy.AllowOriginValue(origin))
+	if policy.Credentials() { wtr.Header().Set(corssvc.HEADER_ALLOW_CREDENTIALS, "true") }
+}
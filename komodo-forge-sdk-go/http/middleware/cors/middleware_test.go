@@ -1 +1,65 @@
 package cors
+
+import (
+	corssvc "komodo-forge-sdk-go/http/services/cors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "http://localhost:7001")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Cleanup(func() { corssvc.SetPolicies(nil) })
+
+	reached := false
+	handler := CORSMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		reached = true
+		wtr.WriteHeader(http.StatusOK)
+	}))
+	send := func(method string, origin string, requestMethod string) *httptest.ResponseRecorder {
+		reached = false
+		req := httptest.NewRequest(method, "/item/suggestion", nil)
+		if origin != "" { req.Header.Set("Origin", origin) }
+		if requestMethod != "" { req.Header.Set(corssvc.HEADER_REQUEST_METHOD, requestMethod) }
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Preflight from the SSR engine is answered without reaching the handler
+	rec := send("OPTIONS", "http://localhost:7001", "POST")
+	if rec.Code != http.StatusNoContent || reached {
+		t.Fatalf("allowed preflight: got %d, reached handler %v", rec.Code, reached)
+	}
+	if got := rec.Header().Get(corssvc.HEADER_ALLOW_ORIGIN); got != "http://localhost:7001" {
+		t.Errorf("preflight allow origin = %q", got)
+	}
+	if rec.Header().Get(corssvc.HEADER_ALLOW_CREDENTIALS) != "true" || rec.Header().Get(corssvc.HEADER_MAX_AGE) == "" {
+		t.Errorf("preflight missing credentials or max-age: %v", rec.Header())
+	}
+	if !slices.Contains(rec.Header().Values("Vary"), "Origin") {
+		t.Errorf("preflight missing Vary: Origin")
+	}
+
+	if rec := send("OPTIONS", "https://evil.example", "POST"); rec.Code != http.StatusForbidden || reached {
+		t.Errorf("foreign preflight: got %d, want 403", rec.Code)
+	}
+	if rec := send("OPTIONS", "http://localhost:7001", "DELETE"); rec.Code != http.StatusForbidden {
+		t.Errorf("disallowed method preflight: got %d, want 403", rec.Code)
+	}
+
+	// Actual requests reach the handler; only allowed origins get CORS headers
+	rec = send("POST", "http://localhost:7001", "")
+	if !reached || rec.Header().Get(corssvc.HEADER_ALLOW_ORIGIN) != "http://localhost:7001" {
+		t.Errorf("allowed request: reached %v, headers %v", reached, rec.Header())
+	}
+	rec = send("POST", "https://evil.example", "")
+	if !reached || rec.Header().Get(corssvc.HEADER_ALLOW_ORIGIN) != "" {
+		t.Errorf("foreign request: reached %v, allow origin %q", reached, rec.Header().Get(corssvc.HEADER_ALLOW_ORIGIN))
+	}
+	if !slices.Contains(rec.Header().Values("Vary"), "Origin") {
+		t.Errorf("foreign request missing Vary: Origin")
+	}
+}
@@ -2,44 +2,102 @@ package csrf
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
 	ctxKeys "komodo-forge-sdk-go/http/context"
 	httpErr "komodo-forge-sdk-go/http/errors"
 	"komodo-forge-sdk-go/http/headers"
-	hdrSrv "komodo-forge-sdk-go/http/headers/eval"
-	httpReq "komodo-forge-sdk-go/http/request"
+	csrfsvc "komodo-forge-sdk-go/http/services/csrf"
+	sessionsvc "komodo-forge-sdk-go/http/services/session"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"net/http"
 )
 
+type TokenResponse struct {
+	CSRFToken string `json:"csrfToken"`
+}
+
+// Protects state-changing requests that ride on ambient browser credentials.
+// The X-CSRF-Token header must match the CSRF cookie (double submit) and carry a valid HMAC bound to the
+// session, and Origin/Referer must be the service itself or in CSRF_TRUSTED_ORIGINS.
+// Run after SessionMiddleware so tokens are checked against the session they were issued for.
 func CSRFMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
 		switch req.Method {
 			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
-				clientType := req.Context().Value(ctxKeys.CLIENT_TYPE_KEY)
-				if clientType == nil {
-					clientType = httpReq.GetClientType(req)
-				}
-
-				if clientType == "api" {
+				if isExempt(req) {
 					ctx := context.WithValue(req.Context(), ctxKeys.CSRF_TOKEN_KEY, "api-client-exempt")
 					ctx = context.WithValue(ctx, ctxKeys.CSRF_VALID_KEY, true)
-					req = req.WithContext(ctx)
-					next.ServeHTTP(wtr, req)
+					next.ServeHTTP(wtr, req.WithContext(ctx))
+					return
+				}
+
+				if err := csrfsvc.CheckOrigin(req); err != nil {
+					logger.Error("cross-site request rejected", err)
+					httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail("request origin not allowed"))
+					return
+				}
+
+				token := req.Header.Get(headers.HEADER_X_CSRF_TOKEN)
+				cookie := csrfsvc.TokenFromCookie(req)
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cookie)) != 1 {
+					logger.Error("csrf token missing or not matching cookie", fmt.Errorf("double submit mismatch"))
+					httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail("invalid CSRF token"))
 					return
 				}
-				
-				// Browser client - require CSRF token
-				if ok, err := hdrSrv.ValidateHeaderValue(headers.HEADER_X_CSRF_TOKEN, req); !ok || err != nil {
-					logger.Error("invalid or missing CSRF token for browser client", err)
-					httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("invalid CSRF token"))
+
+				sessionID, _ := req.Context().Value(ctxKeys.SESSION_ID_KEY).(string)
+				if !sessionValid(req) { sessionID = "" }
+				if err := csrfsvc.Verify(token, sessionID); err != nil {
+					logger.Error("csrf token rejected", err)
+					httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail("invalid CSRF token"))
 					return
 				}
+
+				ctx := context.WithValue(req.Context(), ctxKeys.CSRF_TOKEN_KEY, token)
+				ctx = context.WithValue(ctx, ctxKeys.CSRF_VALID_KEY, true)
+				next.ServeHTTP(wtr, req.WithContext(ctx))
+				return
 		}
 
 		ctx := context.WithValue(req.Context(), ctxKeys.CSRF_TOKEN_KEY, "")
 		ctx = context.WithValue(ctx, ctxKeys.CSRF_VALID_KEY, true)
-		req = req.WithContext(ctx)
-
-		next.ServeHTTP(wtr, req)
+		next.ServeHTTP(wtr, req.WithContext(ctx))
 	})
 }
+
+// Serves GET /csrf: issues a token bound to the caller's session, sets it as the CSRF cookie
+// and returns it for the page to send back in X-CSRF-Token. Run after SessionMiddleware.
+func CSRFTokenHandler(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Cache-Control", "no-store")
+
+	sessionID, _ := req.Context().Value(ctxKeys.SESSION_ID_KEY).(string)
+	if !sessionValid(req) { sessionID = "" }
+
+	token, err := csrfsvc.Issue(sessionID)
+	if err != nil {
+		logger.Error("failed to issue csrf token", err)
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to issue CSRF token"))
+		return
+	}
+
+	csrfsvc.SetCookie(wtr, token)
+	json.NewEncoder(wtr).Encode(TokenResponse{CSRFToken: token})
+}
+
+// CSRF needs credentials the browser attaches on its own. Requests without the session cookie that
+// authenticate with a Bearer token AuthMiddleware verified can't be forged cross-site, so they skip the check.
+// Client type heuristics (Referer, Cookie, unverified claims) are not trusted here: they can be spoofed.
+func isExempt(req *http.Request) bool {
+	if sessionsvc.TokenFromRequest(req) != "" { return false }
+
+	authed, _ := req.Context().Value(ctxKeys.AUTH_VALID_KEY).(bool)
+	return authed
+}
+
+func sessionValid(req *http.Request) bool {
+	valid, _ := req.Context().Value(ctxKeys.SESSION_VALID_KEY).(bool)
+	return valid
+}
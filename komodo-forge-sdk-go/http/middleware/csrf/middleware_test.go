@@ -1 +1,94 @@
 package csrf
+
+import (
+	"context"
+	"encoding/json"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	csrfsvc "komodo-forge-sdk-go/http/services/csrf"
+	sessionsvc "komodo-forge-sdk-go/http/services/session"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func withSession(req *http.Request, sessionID string) *http.Request {
+	ctx := context.WithValue(req.Context(), ctxKeys.SESSION_ID_KEY, sessionID)
+	return req.WithContext(context.WithValue(ctx, ctxKeys.SESSION_VALID_KEY, true))
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	handler := CSRFMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		wtr.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Issue a token for the session through the GET /csrf helper
+	rec := httptest.NewRecorder()
+	CSRFTokenHandler(rec, withSession(httptest.NewRequest("GET", "/csrf", nil), "session-a"))
+	var issued TokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&issued); err != nil || issued.CSRFToken == "" {
+		t.Fatalf("GET /csrf returned no token: %v", err)
+	}
+
+	send := func(header string, cookie string, sessionID string, origin string) int {
+		req := httptest.NewRequest("POST", "http://api.komodo.dev/me/orders", nil)
+		req.AddCookie(&http.Cookie{Name: sessionsvc.COOKIE_NAME, Value: "session-cookie"})
+		if cookie != "" { req.AddCookie(&http.Cookie{Name: csrfsvc.COOKIE_NAME, Value: cookie}) }
+		if header != "" { req.Header.Set("X-CSRF-Token", header) }
+		if origin != "" { req.Header.Set("Origin", origin) }
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withSession(req, sessionID))
+		return rec.Code
+	}
+
+	token := issued.CSRFToken
+	if code := send(token, token, "session-a", "http://api.komodo.dev"); code != http.StatusNoContent {
+		t.Errorf("valid token: got %d", code)
+	}
+	if code := send(token, token, "session-b", ""); code != http.StatusForbidden {
+		t.Errorf("token from another session: got %d, want 403", code)
+	}
+	if code := send(token, "", "session-a", ""); code != http.StatusForbidden {
+		t.Errorf("missing cookie: got %d, want 403", code)
+	}
+	if code := send("well-formed-looking-token", "well-formed-looking-token", "session-a", ""); code != http.StatusForbidden {
+		t.Errorf("unsigned token: got %d, want 403", code)
+	}
+	if code := send(token, token, "session-a", "https://evil.example"); code != http.StatusForbidden {
+		t.Errorf("foreign origin: got %d, want 403", code)
+	}
+}
+
+func TestCSRFExemption(t *testing.T) {
+	handler := CSRFMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		wtr.WriteHeader(http.StatusNoContent)
+	}))
+	authed := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), ctxKeys.AUTH_VALID_KEY, true))
+	}
+
+	// A verified Bearer token without ambient credentials can't be forged cross-site
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, authed(httptest.NewRequest("POST", "/me/orders", nil)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("bearer request: got %d", rec.Code)
+	}
+
+	// Spoofed API-looking headers without a verified token are not exempt
+	req := httptest.NewRequest("POST", "/me/orders", nil)
+	req.Header.Set("Authorization", "Bearer not-verified")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unverified bearer: got %d, want 403", rec.Code)
+	}
+
+	// The session cookie rides along automatically, so its presence always requires a token
+	req = httptest.NewRequest("POST", "/me/orders", nil)
+	req.AddCookie(&http.Cookie{Name: sessionsvc.COOKIE_NAME, Value: "session-cookie"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, authed(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("bearer with session cookie: got %d, want 403", rec.Code)
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"komodo-forge-sdk-go/http/middleware/auth"
+	"komodo-forge-sdk-go/http/middleware/authz"
+	clientip "komodo-forge-sdk-go/http/middleware/client-ip"
 	clienttype "komodo-forge-sdk-go/http/middleware/client-type"
 
 	// "komodo-forge-sdk-go/http/middleware/context"
@@ -13,26 +15,37 @@ import (
 	ratelimiter "komodo-forge-sdk-go/http/middleware/rate-limiter"
 	"komodo-forge-sdk-go/http/middleware/redaction"
 	requestid "komodo-forge-sdk-go/http/middleware/request-id"
+	responsevalidation "komodo-forge-sdk-go/http/middleware/response-validation"
 	rulevalidation "komodo-forge-sdk-go/http/middleware/rule-validation"
 	"komodo-forge-sdk-go/http/middleware/sanitization"
 	securityheaders "komodo-forge-sdk-go/http/middleware/security-headers"
+	"komodo-forge-sdk-go/http/middleware/session"
 	telemetry "komodo-forge-sdk-go/http/middleware/telemetry"
 )
 
 var (
 	AuthMiddleware = auth.AuthMiddleware
+	AuthzMiddleware = authz.AuthzMiddleware
+	RequireScopes = authz.RequireScopes
+	RequireAnyScope = authz.RequireAnyScope
+	RequireAdmin = authz.RequireAdmin
+	ClientIPMiddleware = clientip.ClientIPMiddleware
 	ClientTypeMiddleware = clienttype.ClientTypeMiddleware
 	// ContextMiddleware = context.ContextMiddleware
 	CORSMiddleware = cors.CORSMiddleware
 	CSRFMiddleware = csrf.CSRFMiddleware
+	CSRFTokenHandler = csrf.CSRFTokenHandler
 	IdempotencyMiddleware = idempotency.IdempotencyMiddleware
 	IPAccessMiddleware = ipaccess.IPAccessMiddleware
 	NormalizationMiddleware = normalization.NormalizationMiddleware
 	RateLimiterMiddleware = ratelimiter.RateLimiterMiddleware
 	RedactionMiddleware = redaction.RedactionMiddleware
 	RequestIDMiddleware = requestid.RequestIDMiddleware
+	ResponseValidationMiddleware = responsevalidation.ResponseValidationMiddleware
 	RuleValidationMiddleware = rulevalidation.RuleValidationMiddleware
 	SanitizationMiddleware = sanitization.SanitizationMiddleware
 	SecurityHeadersMiddleware = securityheaders.SecurityHeadersMiddleware
+	SessionMiddleware = session.SessionMiddleware
+	RequireSession = session.RequireSession
 	TelemetryMiddleware = telemetry.TelemetryMiddleware
 )
@@ -1,24 +1,30 @@
 package idempotency
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"komodo-forge-sdk-go/config"
+	"io"
 	ctxKeys "komodo-forge-sdk-go/http/context"
 	httpErr "komodo-forge-sdk-go/http/errors"
 	"komodo-forge-sdk-go/http/headers"
 	hdrSrv "komodo-forge-sdk-go/http/headers/eval"
 	httpReq "komodo-forge-sdk-go/http/request"
+	idemStore "komodo-forge-sdk-go/http/services/idempotency"
+	httpUtils "komodo-forge-sdk-go/http/utils"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"net/http"
-	"sync"
+	"slices"
 	"time"
 )
 
-const DEFAULT_IDEM_TTL_SEC int64 = 300 // 5 minutes
-
-var idemStore sync.Map
+const maxFingerprintBody = 1 << 20 // 1 MiB
 
+// Guards unsafe methods with the Idempotency-Key header.
+// The first request claims the key and its response is stored; retries with the same payload get that
+// response replayed (Idempotency-Replayed: true), concurrent retries get 409 and a different payload gets 422.
+// Browser clients must send a key; API clients opt in by sending one.
 func IdempotencyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
 		// Only guard unsafe, state-changing methods
@@ -27,61 +33,147 @@ func IdempotencyMiddleware(next http.Handler) http.Handler {
 				next.ServeHTTP(wtr, req)
 				return
 		}
-		
-		clientType := req.Context().Value(ctxKeys.CLIENT_TYPE_KEY)
-		if clientType == nil {
+
+		clientType, _ := req.Context().Value(ctxKeys.CLIENT_TYPE_KEY).(string)
+		if clientType == "" {
 			clientType = httpReq.GetClientType(req)
 		}
-		
-		if clientType == "api" {
-			ctx := context.WithValue(req.Context(), ctxKeys.IDEMPOTENCY_VALID_KEY, true)
-			req = req.WithContext(ctx)
+
+		key := req.Header.Get(headers.HEADER_IDEMPOTENCY)
+		if key == "" && clientType == "api" {
+			req = req.WithContext(context.WithValue(req.Context(), ctxKeys.IDEMPOTENCY_VALID_KEY, true))
 			next.ServeHTTP(wtr, req)
 			return
 		}
 
-		key := req.Header.Get("Idempotency-Key")
-
 		if ok, err := hdrSrv.ValidateHeaderValue(headers.HEADER_IDEMPOTENCY, req); !ok || err != nil {
-			logger.Error("invalid idempotency key for browser client: " + key, err)
+			logger.Error("invalid idempotency key for "+clientType+" client: "+key, err)
 			httpErr.SendError(
 				wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("invalid idempotency key"),
 			)
 			return
 		}
 
-		// Load existing entry
-		if exp, ok := idemStore.Load(key); ok {
-			// If expired, evict and continue; else reject as duplicate
-			if until, _ := exp.(int64); until > time.Now().Unix() {
-				wtr.Header().Set("Idempotency-Replayed", "true")
-				logger.Error("duplicate request: " + key, fmt.Errorf("duplicate request"))
-				httpErr.SendError(
-					wtr, req, httpErr.Global.Conflict, httpErr.WithDetail("duplicate request"),
-				)
+		body, err := readBody(wtr, req)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				logger.Error("request body too large for idempotency fingerprint", err)
+				httpErr.SendError(wtr, req, httpErr.Global.PayloadTooLarge, httpErr.WithDetail("request body too large"))
 				return
 			}
-			idemStore.Delete(key)
+			logger.Error("failed to read request body for idempotency fingerprint", err)
+			httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to read request body"))
+			return
+		}
+
+		// Keys are scoped per user so two users can't collide on the same value
+		userID, _ := req.Context().Value(ctxKeys.USER_ID_KEY).(string)
+		storeKey := userID + ":" + key
+		fingerprint := idemStore.Fingerprint(req.Method, httpReq.GetAPIRoute(req), body, userID)
+
+		store := idemStore.Default()
+		existing, err := store.Begin(req.Context(), storeKey, fingerprint, idemStore.LockTTL())
+		if err != nil {
+			logger.Error("idempotency store unavailable for key: "+key, err)
+			httpErr.SendError(
+				wtr, req, httpErr.Global.ServiceUnavailable, httpErr.WithDetail("idempotency store unavailable"),
+			)
+			return
+		}
+
+		if existing != nil {
+			handleDuplicate(wtr, req, key, fingerprint, existing)
+			return
 		}
 
 		req = req.WithContext(context.WithValue(
-			req.Context(), ctxKeys.IDEMPOTENCY_VALID_KEY, true,
+			context.WithValue(req.Context(), ctxKeys.IDEMPOTENCY_KEY, key), ctxKeys.IDEMPOTENCY_VALID_KEY, true,
 		))
 
-		// Store key with expiration
-		// elasticacheClient.SetCacheItem("idem-" + key, "1", getIdemTTL())
+		// Release the claim if the handler panics so the client can retry
+		completed := false
+		defer func() {
+			if !completed { store.Release(context.Background(), storeKey) }
+		}()
+
+		// Outer middleware (CORS, rate limits, deprecation) has already set headers for this request only
+		outer := wtr.Header().Clone()
+		bw := httpUtils.NewBufferedResponseWriter(wtr)
+		next.ServeHTTP(bw, req)
+
+		// Server errors aren't stored so the client can retry them
+		if bw.Status >= http.StatusInternalServerError {
+			logger.Warn(fmt.Sprintf("releasing idempotency key %s after %d response", key, bw.Status))
+		} else if err := store.Complete(req.Context(), storeKey, idemStore.Record{
+			Fingerprint: fingerprint,
+			Status:      bw.Status,
+			Header:      replayHeaders(outer, bw.Header()),
+			Body:        bytes.Clone(bw.Buffer.Bytes()),
+			CreatedAt:   time.Now(),
+		}, idemStore.TTL()); err != nil {
+			logger.Error("failed to store idempotent response for key: "+key, err)
+		} else {
+			completed = true
+		}
 
-		next.ServeHTTP(wtr, req)
+		if err := bw.Commit(); err != nil {
+			logger.Error("failed to write buffered response", err)
+		}
 	})
 }
 
-func getIdemTTL() int64 {
-	// Parse env only once per process would be ideal, but keep simple/fast
-	if ttl := config.GetConfigValue("IDEMPOTENCY_TTL_SEC"); ttl != "" {
-		if dur, err := time.ParseDuration(ttl + "s"); err == nil {
-			if dur <= 0 { return 300 }
-			return int64(dur.Seconds())
-		}
+// Answers a request whose key was already claimed: 422 on payload mismatch, 409 while in flight, else replay
+func handleDuplicate(wtr http.ResponseWriter, req *http.Request, key string, fingerprint string, rec *idemStore.Record) {
+	switch {
+		case rec.Fingerprint != fingerprint:
+			logger.Error("idempotency key reused with a different payload: "+key, fmt.Errorf("fingerprint mismatch"))
+			httpErr.SendError(
+				wtr, req, httpErr.Global.UnprocessableEntity,
+				httpErr.WithDetail("idempotency key was already used with a different request"),
+			)
+		case rec.State != idemStore.StateCompleted:
+			logger.Warn("request with idempotency key still in progress: " + key)
+			wtr.Header().Set("Retry-After", "1")
+			httpErr.SendError(
+				wtr, req, httpErr.Global.Conflict, httpErr.WithDetail("a request with this idempotency key is in progress"),
+			)
+		default:
+			logger.Info("replaying stored response for idempotency key: " + key)
+			// Headers set for this request by outer middleware win over the stored ones
+			for name, values := range rec.Header {
+				if _, set := wtr.Header()[name]; !set { wtr.Header()[name] = values }
+			}
+			wtr.Header().Set("Idempotency-Replayed", "true")
+			wtr.WriteHeader(rec.Status)
+			wtr.Write(rec.Body)
+	}
+}
+
+// Reads the body for fingerprinting, preferring the copy cached by SanitizationMiddleware, and restores it.
+// Bodies over maxFingerprintBody fail with *http.MaxBytesError rather than being cut short, since the
+// handler would otherwise process a truncated request under a stored key.
+func readBody(wtr http.ResponseWriter, req *http.Request) ([]byte, error) {
+	if cached, ok := httpReq.GetJSONBody(req); ok { return cached.Raw, nil }
+	if req.Body == nil { return nil, nil }
+
+	body, err := io.ReadAll(http.MaxBytesReader(wtr, req.Body, maxFingerprintBody))
+	if err != nil { return nil, err }
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Copies the headers the handler set or changed (those not already in outer); cookies and per-request
+// values are left out
+func replayHeaders(outer http.Header, hdr http.Header) http.Header {
+	out := http.Header{}
+	for name, values := range hdr {
+		if !slices.Equal(outer[name], values) { out[name] = slices.Clone(values) }
+	}
+	for _, name := range []string{"Set-Cookie", "Date", "Content-Length", "X-Request-Id"} {
+		out.Del(name)
 	}
-	return DEFAULT_IDEM_TTL_SEC
+	return out
 }
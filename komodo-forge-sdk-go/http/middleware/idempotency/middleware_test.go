@@ -1 +1,184 @@
 package idempotency
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	idemStore "komodo-forge-sdk-go/http/services/idempotency"
+)
+
+func TestIdempotencyMiddlewareReplaysAndRejectsMismatch(t *testing.T) {
+	idemStore.SetStore(idemStore.NewMemoryStore())
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		wtr.Header().Set("Content-Type", "application/json")
+		wtr.WriteHeader(http.StatusCreated)
+		wtr.Write([]byte(`{"orderId":"o-1"}`))
+	}))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "order-key-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send(`{"sku":"A"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotency-Replayed") != "" {
+		t.Fatalf("first request: status %d, replayed %q", first.Code, first.Header().Get("Idempotency-Replayed"))
+	}
+
+	replay := send(`{"sku":"A"}`)
+	if replay.Code != http.StatusCreated || replay.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("replay: status %d, replayed %q", replay.Code, replay.Header().Get("Idempotency-Replayed"))
+	}
+	if replay.Body.String() != first.Body.String() {
+		t.Errorf("replay body = %q, want %q", replay.Body.String(), first.Body.String())
+	}
+
+	if mismatch := send(`{"sku":"B"}`); mismatch.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different payload: status %d, want 422", mismatch.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareReplaysOnlyHandlerHeaders(t *testing.T) {
+	idemStore.SetStore(idemStore.NewMemoryStore())
+
+	inner := IdempotencyMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		wtr.Header().Set("Content-Type", "application/json")
+		wtr.Header().Set("Location", "/v1/orders/o-1")
+		wtr.WriteHeader(http.StatusCreated)
+	}))
+
+	// Stands in for CORS and rate limiting, whose headers describe the current request
+	var remaining atomic.Int32
+	remaining.Store(10)
+	handler := http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		wtr.Header().Set("Access-Control-Allow-Origin", req.Header.Get("Origin"))
+		wtr.Header().Set("RateLimit-Remaining", fmt.Sprint(remaining.Add(-1)))
+		inner.ServeHTTP(wtr, req)
+	})
+
+	send := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"sku":"A"}`))
+		req.Header.Set("Idempotency-Key", "header-key-123")
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	send("https://shop.komodo.dev")
+	replay := send("https://admin.komodo.dev")
+	if replay.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("second request was not replayed: %v", replay.Header())
+	}
+	if got := replay.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.komodo.dev" {
+		t.Errorf("replayed allow origin = %q, want the current request's origin", got)
+	}
+	if got := replay.Header().Get("RateLimit-Remaining"); got != "8" {
+		t.Errorf("replayed RateLimit-Remaining = %q, want the current value 8", got)
+	}
+	if replay.Header().Get("Location") != "/v1/orders/o-1" || replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("handler headers not replayed: %v", replay.Header())
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsInFlightRetry(t *testing.T) {
+	idemStore.SetStore(idemStore.NewMemoryStore())
+
+	entered := make(chan struct{})
+	finish := make(chan struct{})
+	handler := IdempotencyMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		close(entered)
+		<-finish
+		wtr.WriteHeader(http.StatusCreated)
+	}))
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"sku":"A"}`))
+		req.Header.Set("Idempotency-Key", "inflight-key-123")
+		return req
+	}
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newReq())
+		done <- rec.Code
+	}()
+	<-entered
+
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, newReq())
+	if retry.Code != http.StatusConflict || retry.Header().Get("Retry-After") == "" {
+		t.Errorf("retry while in flight: status %d, Retry-After %q", retry.Code, retry.Header().Get("Retry-After"))
+	}
+
+	close(finish)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("original request: status %d, want 201", code)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesKeyOnServerError(t *testing.T) {
+	idemStore.SetStore(idemStore.NewMemoryStore())
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) == 1 {
+			wtr.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		wtr.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"sku":"A"}`))
+		req.Header.Set("Idempotency-Key", "release-key-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if first := send(); first.Code != http.StatusBadGateway {
+		t.Fatalf("first request: status %d, want 502", first.Code)
+	}
+	// The 5xx wasn't stored, so the retry runs the handler instead of replaying the failure
+	retry := send()
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotency-Replayed") != "" {
+		t.Errorf("retry after 5xx: status %d, replayed %q", retry.Code, retry.Header().Get("Idempotency-Replayed"))
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsOversizedBody(t *testing.T) {
+	idemStore.SetStore(idemStore.NewMemoryStore())
+
+	reached := false
+	handler := IdempotencyMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		reached = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", bytes.NewReader(make([]byte, maxFingerprintBody+1)))
+	req.Header.Set("Idempotency-Key", "oversized-key-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge || reached {
+		t.Errorf("oversized body: status %d, reached handler %v", rec.Code, reached)
+	}
+}
@@ -4,36 +4,21 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"sync"
 
-	"komodo-forge-sdk-go/config"
 	httpErr "komodo-forge-sdk-go/http/errors"
 	httpReq "komodo-forge-sdk-go/http/request"
 	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
 	logger "komodo-forge-sdk-go/logging/runtime"
 )
 
-var (
-	ipOnce sync.Once
-	lists ipsvc.Lists
-)
-
 // Enforces allow/deny rules based on client IP.
+// Reads the IP resolved by ClientIPMiddleware, so forged forwarding headers can't dodge the blacklist.
+// Lists combine IP_WHITELIST/IP_BLACKLIST with dynamic entries (admin and auto-ban) that expire on their own.
+// Only a configured IP_WHITELIST denies unlisted clients; dynamic whitelist entries just bypass the blacklist.
 func IPAccessMiddleware(next http.Handler) http.Handler {
-	// lazy-parse env config once
-	ipOnce.Do(func() {
-		wlIPs, wlNets := ipsvc.ParseList(config.GetConfigValue("IP_WHITELIST"))
-		blIPs, blNets := ipsvc.ParseList(config.GetConfigValue("IP_BLACKLIST"))
-		logger.Debug("parsed IP whitelist: ", logger.Attr("whitelist", wlIPs))
-		logger.Debug("parsed IP blacklist: ", logger.Attr("blacklist", blIPs))
-	
-		lists = ipsvc.Lists{
-			WhitelistIPs: wlIPs,
-			WhitelistNets: wlNets,
-			BlacklistIPs: blIPs,
-			BlacklistNets: blNets,
-		}
-	})
+	lists := ipsvc.Default()
+	logger.Debug("loaded IP whitelist: ", logger.Attr("whitelist", lists.Entries(ipsvc.ListWhitelist)))
+	logger.Debug("loaded IP blacklist: ", logger.Attr("blacklist", lists.Entries(ipsvc.ListBlacklist)))
 
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
 		client := httpReq.GetClientKey(req)
@@ -46,13 +31,6 @@ func IPAccessMiddleware(next http.Handler) http.Handler {
 		}
 
 		ip := net.ParseIP(client)
-		if ip == nil {
-			// Try to trim potential port if present
-			host, _, err := net.SplitHostPort(client)
-			if err == nil {
-				ip = net.ParseIP(host)
-			}
-		}
 		if ip == nil {
 			logger.Error("invalid client IP: " + client, fmt.Errorf("invalid client IP"))
 			httpErr.SendError(
@@ -61,7 +39,7 @@ func IPAccessMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		allowed := ipsvc.Evaluate(ip, &lists)
+		allowed := ipsvc.Evaluate(ip, ipsvc.Default())
 		if !allowed {
 			logger.Error("access denied for client ip: " + client, fmt.Errorf("access denied for client IP"))
 			httpErr.SendError(
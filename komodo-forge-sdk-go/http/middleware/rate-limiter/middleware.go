@@ -4,33 +4,64 @@ import (
 	"fmt"
 	httpErr "komodo-forge-sdk-go/http/errors"
 	httpReq "komodo-forge-sdk-go/http/request"
+	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
 	rl "komodo-forge-sdk-go/http/services/rate_limiter"
 	logger "komodo-forge-sdk-go/logging/runtime"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-// RateLimiterMiddleware delegates core logic to services/rate_limiter.
+// RateLimiterMiddleware applies the first matching rate limit policy and reports it via RateLimit-* headers.
+// Rate-based policies use the policy's algorithm; concurrency policies cap in-flight requests instead.
+// Core logic lives in services/rate_limiter.
 func RateLimiterMiddleware(next http.Handler) http.Handler {
+	if err := rl.LoadPoliciesFromConfig(); err != nil {
+		logger.Error("rate limit policies failed to load, using global limit", err)
+	}
+
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
-		key := httpReq.GetClientKey(req)
-		allowed, wait, err := rl.Allow(req.Context(), key)
+		policy := rl.MatchPolicy(req)
+		key := policy.Key(req)
+
+		var dec rl.Decision
+		var err error
+		release := func() {}
+
+		if svc := policy.Service(); svc != nil {
+			dec, err = svc.AllowN(req.Context(), key, policy.Limit(), policy.Cost)
+		} else {
+			// Concurrency policies hold a slot until the handler returns
+			release, dec, err = rl.Concurrency().Acquire(req.Context(), key, policy.Concurrency)
+		}
+		defer release()
 
 		if err != nil {
 			if rl.ShouldFailOpen() {
 				logger.Error("rate limiter failing open for client: " + key, err)
-			} else {
-				logger.Error("rate limiter failed for client: " + key, err)
-				httpErr.SendError(
-					wtr, req, httpErr.Global.Internal, httpErr.WithDetail("internal rate limiter error"),
-				)
+				next.ServeHTTP(wtr, req)
 				return
 			}
-		} else if !allowed {
-			if wait > 0 {
-				wtr.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds() + 0.5)))
+			logger.Error("rate limiter failed for client: " + key, err)
+			httpErr.SendError(
+				wtr, req, httpErr.Global.Internal, httpErr.WithDetail("internal rate limiter error"),
+			)
+			return
+		}
+
+		wtr.Header().Set("RateLimit-Limit", strconv.Itoa(dec.Limit))
+		wtr.Header().Set("RateLimit-Remaining", strconv.Itoa(max(dec.Remaining, 0)))
+		if policy.Algorithm != rl.AlgorithmConcurrency {
+			wtr.Header().Set("RateLimit-Reset", ceilSeconds(dec.Reset))
+		}
+
+		if !dec.Allowed {
+			if dec.RetryAfter > 0 {
+				wtr.Header().Set("Retry-After", ceilSeconds(dec.RetryAfter))
 			}
-			logger.Error("rate limit exceeded for client: " + key, fmt.Errorf("rate limit exceeded"))
+			logger.Error("rate limit exceeded for client: " + key, fmt.Errorf("policy %s exceeded", policy.Name))
+			ipsvc.RecordStrike(req.Context(), httpReq.GetClientKey(req), ipsvc.StrikeRateLimit)
 			httpErr.SendError(
 				wtr, req, httpErr.Global.TooManyRequests, httpErr.WithDetail("rate limit exceeded"),
 			)
@@ -40,3 +71,8 @@ func RateLimiterMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wtr, req)
 	})
 }
+
+// Header values are whole seconds, rounded up so clients never retry early
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
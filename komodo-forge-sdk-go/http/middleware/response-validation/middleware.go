@@ -0,0 +1,56 @@
+package responsevalidation
+
+import (
+	"fmt"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	evalRules "komodo-forge-sdk-go/http/rules"
+	httpUtils "komodo-forge-sdk-go/http/utils"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+)
+
+// Buffers handler output and checks it against the rule's response contract.
+// Lenient logs violations and sends the response as is; strict replaces it with a 500.
+func ResponseValidationMiddleware(next http.Handler) http.Handler {
+	if !evalRules.LoadConfig() {
+		logger.Error("validation rules failed to load", fmt.Errorf("failed to load validation rules"))
+	}
+
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		rule := evalRules.Resolve(req, evalRules.GetRule(req.URL.Path, req.Method))
+
+		level := rule.ResponseLevel()
+		if level == evalRules.LevelIgnore {
+			next.ServeHTTP(wtr, req)
+			return
+		}
+
+		bw := httpUtils.NewBufferedResponseWriter(wtr)
+		next.ServeHTTP(bw, req)
+
+		report := evalRules.ValidateResponse(bw.Status, bw.Header(), bw.Buffer.Bytes(), rule)
+		if report.Valid() {
+			if err := bw.Commit(); err != nil {
+				logger.Error("failed to write buffered response", err)
+			}
+			return
+		}
+
+		logger.Error(
+			"response does not comply with contract",
+			fmt.Errorf("%d response violation(s) for %s %s: %+v", len(report.Violations), req.Method, req.URL.Path, report.Violations),
+		)
+
+		if level != evalRules.LevelStrict {
+			if err := bw.Commit(); err != nil {
+				logger.Error("failed to write buffered response", err)
+			}
+			return
+		}
+
+		// Violations describe server internals, so they are logged but never returned to the caller
+		bw.Discard()
+		wtr.Header().Del("Content-Length")
+		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("response failed contract validation"))
+	})
+}
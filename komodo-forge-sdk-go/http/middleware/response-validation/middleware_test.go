@@ -0,0 +1,88 @@
+package responsevalidation
+
+import (
+	evalRules "komodo-forge-sdk-go/http/rules"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+rules:
+  /widgets/{id}:
+    GET:
+      params:
+        id:
+          type: "string"
+      response:
+        status: [200, 404]
+        body:
+          "id":
+            required: true
+            type: "string"
+  /orders:
+    POST:
+      response:
+        level: "strict"
+        status: [201, 404]
+        body:
+          "orderId":
+            required: true
+            type: "string"
+`
+
+// Serves whatever status and body the test sets, with headers a client should see untouched
+func send(t *testing.T, method string, path string, status int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := ResponseValidationMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		wtr.Header().Set("Content-Type", "application/json")
+		wtr.Header().Set("Content-Length", "999")
+		wtr.Header().Set("X-Trace", "abc")
+		wtr.WriteHeader(status)
+		wtr.Write([]byte(body))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestResponseValidationMiddleware(t *testing.T) {
+	evalRules.LoadConfigWithData([]byte(testRules))
+
+	// Lenient contracts only log, so the handler's response goes out as written
+	rec := send(t, "GET", "/widgets/w1", http.StatusOK, `{"name":"missing id"}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"name":"missing id"}` || rec.Header().Get("X-Trace") != "abc" {
+		t.Errorf("lenient violation: got %d %q, headers %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	// Strict contracts replace a violating response with a 500 that doesn't leak the handler's body
+	rec = send(t, "POST", "/orders", http.StatusCreated, `{"secret":"internal"}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("strict violation: status %d, want 500", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "internal") || rec.Header().Get("Content-Length") == "999" {
+		t.Errorf("strict violation leaked the original response: %q, headers %v", rec.Body.String(), rec.Header())
+	}
+
+	// Error bodies aren't checked against the route's body schema
+	rec = send(t, "POST", "/orders", http.StatusNotFound, `{"message":"not found"}`)
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `{"message":"not found"}` {
+		t.Errorf("non-2xx response: got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// A valid response is committed with the handler's status and headers
+	rec = send(t, "POST", "/orders", http.StatusCreated, `{"orderId":"o-1"}`)
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"orderId":"o-1"}` {
+		t.Errorf("valid response: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Trace") != "abc" || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("valid response lost headers: %v", rec.Header())
+	}
+
+	// A status outside the contract fails even when the body is valid
+	if rec := send(t, "POST", "/orders", http.StatusAccepted, `{"orderId":"o-1"}`); rec.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected status: got %d, want 500", rec.Code)
+	}
+}
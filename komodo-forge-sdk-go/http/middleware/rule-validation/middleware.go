@@ -1,14 +1,19 @@
 package rulevalidation
 
 import (
+	"errors"
 	"fmt"
 	httpErr "komodo-forge-sdk-go/http/errors"
+	httpReq "komodo-forge-sdk-go/http/request"
 	evalRules "komodo-forge-sdk-go/http/rules"
+	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"net/http"
+	"strings"
 )
 
 // Enforces request validation rules based on predefined configurations.
+// Invalid requests count as auto-ban strikes against the client IP.
 func RuleValidationMiddleware(next http.Handler) http.Handler {
 	// Ensure config is loaded
 	if !evalRules.LoadConfig() {
@@ -17,14 +22,28 @@ func RuleValidationMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
 		if rule := evalRules.GetRule(req.URL.Path, req.Method); rule != nil {
-			if !evalRules.IsRuleValid(req, rule) {
-				logger.Error("request does not comply with validation rule", fmt.Errorf("validation rule failed: %v", rule))
+			rule = evalRules.Resolve(req, rule)
+
+			if err := evalRules.Authorize(req, rule); err != nil {
+				logger.Error("request does not meet rule access requirements", err)
+				sendAccessError(wtr, req, rule, err)
+				return
+			}
+			if report := evalRules.Validate(req, rule); !report.Valid() {
+				logger.Error(
+					"request does not comply with validation rule",
+					fmt.Errorf("%d validation rule violation(s) for %s %s", len(report.Violations), req.Method, req.URL.Path),
+				)
+				ipsvc.RecordStrike(req.Context(), httpReq.GetClientKey(req), ipsvc.StrikeRuleValidation)
 				httpErr.SendError(
-					wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("request contents invalid"),
+					wtr, req, httpErr.Global.BadRequest,
+					httpErr.WithDetail("request contents invalid"),
+					httpErr.WithInvalidParams(report.Violations),
 				)
 				return
 			}
 		} else {
+			// A missing rule is a gap in the rules file, not the caller misbehaving, so it earns no strike
 			logger.Error("no validation rule found", fmt.Errorf("no validation rule found for path: %s and method: %s", req.URL.Path, req.Method))
 			httpErr.SendError(
 				wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to validate request"),
@@ -34,3 +53,19 @@ func RuleValidationMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wtr, req)
 	})
 }
+
+// Maps rule access failures onto auth errors (RFC 6750 insufficient_scope for missing scopes)
+func sendAccessError(wtr http.ResponseWriter, req *http.Request, rule *evalRules.EvalRule, err error) {
+	switch {
+		case errors.Is(err, evalRules.ErrInsufficientScope):
+			wtr.Header().Set(
+				"WWW-Authenticate",
+				fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(rule.Scopes, " ")),
+			)
+			httpErr.SendError(wtr, req, httpErr.Auth.InsufficientScope, httpErr.WithDetail(err.Error()))
+		case errors.Is(err, evalRules.ErrAdminRequired):
+			httpErr.SendError(wtr, req, httpErr.Auth.AccessDenied, httpErr.WithDetail(err.Error()))
+		default:
+			httpErr.SendError(wtr, req, httpErr.Global.Forbidden, httpErr.WithDetail(err.Error()))
+	}
+}
@@ -1 +1,105 @@
 package rulevalidation
+
+import (
+	"context"
+	"encoding/json"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	evalRules "komodo-forge-sdk-go/http/rules"
+	ipsvc "komodo-forge-sdk-go/http/services/ip_access"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+rules:
+  /orders:
+    POST:
+      requiredVersion: 1
+      scopes: ["orders:write"]
+      body:
+        sku:
+          type: "string"
+          required: true
+  /admin/orders:
+    GET:
+      requiredVersion: 1
+      admin: true
+`
+
+func TestRuleValidationMiddleware(t *testing.T) {
+	evalRules.LoadConfigWithData([]byte(testRules))
+	ipsvc.SetStore(ipsvc.NewMemoryStore())
+	defer ipsvc.SetStore(nil)
+
+	reached := false
+	handler := RuleValidationMiddleware(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		reached = true
+		wtr.WriteHeader(http.StatusNoContent)
+	}))
+
+	send := func(method string, path string, body string, scopes []string, admin bool) (*httptest.ResponseRecorder, httpErr.ErrorResponse) {
+		reached = false
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		ctx := context.WithValue(req.Context(), ctxKeys.SCOPES_KEY, scopes)
+		ctx = context.WithValue(ctx, ctxKeys.IS_ADMIN_KEY, admin)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+
+		var errBody httpErr.ErrorResponse
+		if rec.Code >= 400 { json.Unmarshal(rec.Body.Bytes(), &errBody) }
+		return rec, errBody
+	}
+
+	cases := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		scopes    []string
+		admin     bool
+		status    int
+		code      string
+		challenge string
+	}{
+		{"allowed", "POST", "/v1/orders", `{"sku":"A-1"}`, []string{"orders:write"}, false, http.StatusNoContent, "", ""},
+		{
+			"missing scope", "POST", "/v1/orders", `{"sku":"A-1"}`, []string{"orders:read"}, false,
+			http.StatusForbidden, httpErr.Auth.InsufficientScope.ID, `Bearer error="insufficient_scope", scope="orders:write"`,
+		},
+		{"not admin", "GET", "/v1/admin/orders", "", nil, false, http.StatusForbidden, httpErr.Auth.AccessDenied.ID, ""},
+		{"admin", "GET", "/v1/admin/orders", "", nil, true, http.StatusNoContent, "", ""},
+		{"invalid body", "POST", "/v1/orders", `{"sku":7}`, []string{"orders:write"}, false, http.StatusBadRequest, httpErr.Global.BadRequest.ID, ""},
+		{"no rule", "DELETE", "/v1/orders", "", nil, true, http.StatusBadRequest, httpErr.Global.BadRequest.ID, ""},
+	}
+	for _, tc := range cases {
+		rec, errBody := send(tc.method, tc.path, tc.body, tc.scopes, tc.admin)
+		if rec.Code != tc.status || errBody.Code != tc.code {
+			t.Errorf("%s: got %d %q, want %d %q (%s)", tc.name, rec.Code, errBody.Code, tc.status, tc.code, rec.Body.String())
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tc.challenge {
+			t.Errorf("%s: WWW-Authenticate = %q, want %q", tc.name, got, tc.challenge)
+		}
+		if reached != (tc.status < 400) {
+			t.Errorf("%s: reached handler = %v", tc.name, reached)
+		}
+	}
+
+	// Only rule violations count towards an auto-ban; a route missing from the rules file doesn't
+	t.Setenv("AUTO_BAN_STRIKES", "1")
+	client := net.ParseIP("192.0.2.1")
+
+	send("DELETE", "/v1/orders", "", nil, true)
+	if _, banned := ipsvc.Default().Lookup(ipsvc.ListBlacklist, client); banned {
+		t.Error("a request without a validation rule struck the client")
+	}
+	send("POST", "/v1/orders", `{"sku":7}`, []string{"orders:write"}, false)
+	if _, banned := ipsvc.Default().Lookup(ipsvc.ListBlacklist, client); !banned {
+		t.Error("a validation violation did not strike the client")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"html"
 	"io"
 	httpErr "komodo-forge-sdk-go/http/errors"
+	httpReq "komodo-forge-sdk-go/http/request"
 	"net/http"
 	"net/url"
 	"strings"
@@ -18,9 +19,9 @@ func SanitizationMiddleware(next http.Handler) http.Handler {
 		sanitizePathParams(req)
 		sanitizeQueryParams(req)
 
-		if req.Body != nil && req.Header.Get("Content-Type") == "application/json" {
-			sanitizeBody(wtr, req)
-			if req.Body == nil { return }
+		if req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			var ok bool
+			if req, ok = sanitizeBody(wtr, req); !ok { return }
 		}
 
 		next.ServeHTTP(wtr, req)
@@ -69,20 +70,27 @@ func sanitizeQueryParams(req *http.Request) {
 	req.URL.RawQuery = sanitized.Encode()
 }
 
-// Sanitizes JSON request body
-func sanitizeBody(wtr http.ResponseWriter, req *http.Request) {
+// Sanitizes the JSON request body and caches the decoded result so later middleware skips re-parsing.
+// Returns false once an error response has been sent.
+func sanitizeBody(wtr http.ResponseWriter, req *http.Request) (*http.Request, bool) {
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
 		httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to read request body"))
-		return
+		return req, false
 	}
 	req.Body.Close()
 
+	// Empty bodies pass through untouched
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return req, true
+	}
+
 	// Parse JSON
 	var data interface{}
 	if err := json.Unmarshal(body, &data); err != nil {
 		httpErr.SendError(wtr, req, httpErr.Global.BadRequest, httpErr.WithDetail("failed to parse JSON body"))
-		return
+		return req, false
 	}
 
 	// Sanitize the data recursively
@@ -92,12 +100,14 @@ func sanitizeBody(wtr http.ResponseWriter, req *http.Request) {
 	sanitizedBody, err := json.Marshal(sanitized)
 	if err != nil {
 		httpErr.SendError(wtr, req, httpErr.Global.Internal, httpErr.WithDetail("failed to marshal JSON body"))
-		return
+		return req, false
 	}
 
 	// Replace request body with sanitized version
 	req.Body = io.NopCloser(bytes.NewBuffer(sanitizedBody))
 	req.ContentLength = int64(len(sanitizedBody))
+
+	return httpReq.WithJSONBody(req, sanitizedBody, sanitized), true
 }
 
 // Recursively sanitizes JSON data structures
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"context"
+	"errors"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	sessionsvc "komodo-forge-sdk-go/http/services/session"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+)
+
+// Resolves the __Host- session cookie and fills SESSION_ID_KEY and SESSION_VALID_KEY.
+// Requests without a live session pass through with SESSION_VALID_KEY false; use RequireSession to enforce one.
+// When no Bearer token was validated, the session's user, scopes and admin flag are placed in the context too.
+func SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), ctxKeys.SESSION_VALID_KEY, false)
+
+		token := sessionsvc.TokenFromRequest(req)
+		if token == "" {
+			next.ServeHTTP(wtr, req.WithContext(ctx))
+			return
+		}
+
+		sess, err := sessionsvc.Validate(req.Context(), token)
+		if err != nil {
+			if !errors.Is(err, sessionsvc.ErrNotFound) && !errors.Is(err, sessionsvc.ErrExpired) {
+				logger.Error("failed to load session", err)
+				httpErr.SendError(wtr, req, httpErr.Global.ServiceUnavailable, httpErr.WithDetail("failed to load session"))
+				return
+			}
+			// Stale cookie: drop it so the browser stops presenting it
+			sessionsvc.ClearCookie(wtr)
+			next.ServeHTTP(wtr, req.WithContext(ctx))
+			return
+		}
+
+		ctx = context.WithValue(ctx, ctxKeys.SESSION_VALID_KEY, true)
+		ctx = context.WithValue(ctx, ctxKeys.SESSION_ID_KEY, sess.ID)
+
+		// A Bearer token takes precedence; never mix its identity with the session's
+		if authed, _ := ctx.Value(ctxKeys.AUTH_VALID_KEY).(bool); !authed {
+			ctx = context.WithValue(ctx, ctxKeys.USER_ID_KEY, sess.UserID)
+			ctx = context.WithValue(ctx, ctxKeys.SCOPES_KEY, sess.Scopes)
+			if sess.IsAdmin { ctx = context.WithValue(ctx, ctxKeys.IS_ADMIN_KEY, true) }
+		}
+
+		next.ServeHTTP(wtr, req.WithContext(ctx))
+	})
+}
+
+// Rejects requests without a live session. Must run after SessionMiddleware.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if valid, _ := req.Context().Value(ctxKeys.SESSION_VALID_KEY).(bool); !valid {
+			logger.Error("request without a valid session", errors.New("invalid session"))
+			httpErr.SendError(wtr, req, httpErr.Auth.InvalidSession)
+			return
+		}
+		next.ServeHTTP(wtr, req)
+	})
+}
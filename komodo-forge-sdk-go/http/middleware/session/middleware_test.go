@@ -0,0 +1,47 @@
+package session
+
+import (
+	"context"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	sessionsvc "komodo-forge-sdk-go/http/services/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	sessionsvc.SetStore(sessionsvc.NewMemoryStore())
+	defer sessionsvc.SetStore(nil)
+
+	token, sess, err := sessionsvc.Create(context.Background(), httptest.NewRequest("POST", "/", nil), sessionsvc.Grant{
+		UserID: "user-1", Scopes: []string{"orders:read"},
+	})
+	if err != nil { t.Fatalf("create: %v", err) }
+
+	var gotID, gotUser any
+	handler := SessionMiddleware(RequireSession(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		gotID = req.Context().Value(ctxKeys.SESSION_ID_KEY)
+		gotUser = req.Context().Value(ctxKeys.USER_ID_KEY)
+	})))
+
+	req := httptest.NewRequest("GET", "/me/orders", nil)
+	req.AddCookie(&http.Cookie{Name: sessionsvc.COOKIE_NAME, Value: token})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || gotID != sess.ID || gotUser != "user-1" {
+		t.Fatalf("valid session: code %d, session %v, user %v", rec.Code, gotID, gotUser)
+	}
+
+	// Unknown cookies are cleared and rejected by RequireSession
+	req = httptest.NewRequest("GET", "/me/orders", nil)
+	req.AddCookie(&http.Cookie{Name: sessionsvc.COOKIE_NAME, Value: "forged"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for unknown session, got %d", rec.Code)
+	}
+	if cookie := rec.Header().Get("Set-Cookie"); !strings.Contains(cookie, "Max-Age=0") {
+		t.Errorf("expected the stale cookie to be cleared, got %q", cookie)
+	}
+}
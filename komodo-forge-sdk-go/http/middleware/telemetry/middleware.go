@@ -3,6 +3,7 @@ package telemetry
 import (
 	"fmt"
 	httpErr "komodo-forge-sdk-go/http/errors"
+	httpReq "komodo-forge-sdk-go/http/request"
 	httpUtils "komodo-forge-sdk-go/http/utils"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"net/http"
@@ -51,7 +52,7 @@ func TelemetryMiddleware(next http.Handler) http.Handler {
 				"status":     status,
 				"bytes":     	bytesWritten,
 				"latency_ms": ms,
-				"ip":         req.RemoteAddr,
+				"ip":         httpReq.GetClientKey(req),
 				"user_agent": req.UserAgent(),
 				"referer":    req.Referer(),
 				"proto":     	req.Proto,
@@ -2,6 +2,7 @@ package httprequest
 
 import (
 	"context"
+	ctxKeys "komodo-forge-sdk-go/http/context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
@@ -10,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -80,15 +82,32 @@ func GetAPIVersion(req *http.Request) string {
 	// Priority 3: Fallback to URL path versioning (e.g., /v1/resource)
 	if req.URL != nil {
 		trimmed := strings.TrimPrefix(req.URL.Path, "/")
-		segments := strings.Split(trimmed, "/")
+		first, _, _ := strings.Cut(trimmed, "/")
 
-		if len(segments) > 0 && len(segments[0]) > 0 && segments[0][0] == 'v' {
-			return "/" + segments[0]
+		// Require a digit so routes like /vendors aren't mistaken for a version
+		if len(first) > 1 && first[0] == 'v' && first[1] >= '0' && first[1] <= '9' {
+			return "/" + first
 		}
 	}
 	return ""
 }
 
+// Returns the major version requested by the client (e.g. 2 for "/v2", "v=2" or "version=2.1").
+// ok is false when no version was given or it isn't numeric.
+func GetAPIMajorVersion(req *http.Request) (major int, ok bool) {
+	return ParseMajorVersion(GetAPIVersion(req))
+}
+
+// Parses the major number out of a version string as returned by GetAPIVersion
+func ParseMajorVersion(version string) (int, bool) {
+	v := strings.TrimPrefix(strings.TrimPrefix(version, "/"), "v")
+	v, _, _ = strings.Cut(v, ".")
+
+	major, err := strconv.Atoi(v)
+	if err != nil || major < 1 { return 0, false }
+	return major, true
+}
+
 // Extracts version from media type header (e.g., "application/json;v=1" or "application/json; version=2")
 func extractVersionFromMediaType(mediaType string) string {
 	parts := strings.Split(mediaType, ";")
@@ -127,7 +146,7 @@ func GetAPIRoute(req *http.Request) string {
 
 	var pathSegments = []string{}
 
-	if len(segments) > 0 && len(segments[0]) > 0 && segments[0][0] == 'v' {
+	if first := segments[0]; len(first) > 1 && first[0] == 'v' && first[1] >= '0' && first[1] <= '9' {
 		pathSegments = segments[1:]
 	} else {
 		pathSegments = segments // No explicit version prefix
@@ -166,18 +185,36 @@ func GetQueryParams(req *http.Request) map[string]string {
 	return out
 }
 
-// Extracts a client identifier from the request, preferring
+// Reports whether the request body is application/x-www-form-urlencoded (e.g. standard OAuth clients)
+func IsFormRequest(req *http.Request) bool {
+	if req == nil { return false }
+	mediaType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";")
+	return strings.EqualFold(strings.TrimSpace(mediaType), "application/x-www-form-urlencoded")
+}
+
+// Request body decoded once and shared by the middleware that inspects JSON
+type JSONBody struct {
+	Raw   []byte // bytes currently set as the request body
+	Value any    // decoded form of Raw
+}
+
+// Attaches a decoded JSON body to the request context
+func WithJSONBody(req *http.Request, raw []byte, value any) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), ctxKeys.JSON_BODY_KEY, &JSONBody{Raw: raw, Value: value}))
+}
+
+// Returns the decoded JSON body cached by an earlier middleware, if any
+func GetJSONBody(req *http.Request) (*JSONBody, bool) {
+	if req == nil { return nil, false }
+	body, ok := req.Context().Value(ctxKeys.JSON_BODY_KEY).(*JSONBody)
+	return body, ok && body != nil
+}
+
+// Returns the client IP resolved by ClientIPMiddleware, falling back to the peer address.
+// Forwarding headers are never read here; only the resolver knows which proxies to trust.
 func GetClientKey(req *http.Request) string {
-	// prefer first X-Forwarded-For entry when present
-	if xf := req.Header.Get("X-Forwarded-For"); xf != "" {
-		parts := strings.Split(xf, ",")
-		if len(parts) > 0 {
-			if ip := strings.TrimSpace(parts[0]); ip != "" {
-				return ip
-			}
-		}
-	}
-	// fallback to remote addr host
+	if ip, ok := req.Context().Value(ctxKeys.CLIENT_IP_KEY).(string); ok && ip != "" { return ip }
+
 	host, _, err := net.SplitHostPort(req.RemoteAddr)
 	if err == nil && host != "" {
 		return host
@@ -215,6 +252,12 @@ func GetClientType(req *http.Request) string {
 	}
 	
 	authHeader := req.Header.Get("Authorization")
+
+	// HTTP Basic client credentials come from server-side OAuth clients (RFC 6749 Section 2.3.1)
+	if strings.HasPrefix(authHeader, "Basic ") {
+		return "api"
+	}
+
 	if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 		parts := strings.Split(strings.TrimPrefix(authHeader, "Bearer "), ".")
 
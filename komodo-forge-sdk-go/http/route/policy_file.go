@@ -0,0 +1,26 @@
+package route
+
+import (
+	"fmt"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"os"
+)
+
+// Reads a route policy file and hands it to parse, logging why it was rejected; kind names the
+// policies in messages, e.g. "cors policies"
+func LoadPolicyFile[T any](path string, kind string, parse func([]byte) (T, error)) (T, error) {
+	var zero T
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("failed to read "+kind, err)
+		return zero, fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+
+	parsed, err := parse(data)
+	if err != nil {
+		logger.Error("rejected "+kind+" from "+path, err)
+		return zero, err
+	}
+	return parsed, nil
+}
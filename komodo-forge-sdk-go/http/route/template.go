@@ -0,0 +1,132 @@
+package route
+
+import "strings"
+
+// Segment kinds for compiled route templates
+const (
+	Static = iota
+	Param
+	Wildcard
+)
+
+type Segment struct {
+	Kind  int
+	Value string // literal text, or the param name for Param
+}
+
+// Route template compiled into segments. Validation rules and the authz, cors and rate limit
+// policy files all match through it, so {param}, :param and * mean the same thing everywhere.
+type Template struct {
+	Raw      string
+	Segments []Segment
+	Dynamic  bool // has a param or wildcard segment
+}
+
+// Splits a template like /item/{sku} or /users/:id/* into typed segments
+func Parse(tpl string) *Template {
+	t := &Template{Raw: tpl}
+
+	np := normalizeTemplate(tpl)
+	if np == "/" { return t }
+
+	for _, p := range strings.Split(strings.TrimPrefix(np, "/"), "/") {
+		switch {
+			case p == "*":
+				t.Segments = append(t.Segments, Segment{Kind: Wildcard})
+				t.Dynamic = true
+			case strings.HasPrefix(p, ":"):
+				t.Segments = append(t.Segments, Segment{Kind: Param, Value: p[1:]})
+				t.Dynamic = true
+			case len(p) > 2 && p[0] == '{' && p[len(p)-1] == '}':
+				t.Segments = append(t.Segments, Segment{Kind: Param, Value: p[1 : len(p)-1]})
+				t.Dynamic = true
+			default:
+				t.Segments = append(t.Segments, Segment{Kind: Static, Value: p})
+		}
+	}
+	return t
+}
+
+// Compiles a policy's route; empty and "*" cover every path and compile to nil
+func ParsePolicyRoute(tpl string) *Template {
+	if trimmed := strings.Trim(tpl, "/"); trimmed == "" || trimmed == "*" { return nil }
+	return Parse(tpl)
+}
+
+// Reports whether a request path fits the template; a nil template matches every path
+func (t *Template) Match(path string) bool {
+	if t == nil { return true }
+	return t.walk(Normalize(path), nil)
+}
+
+// Returns named segment values when the normalized path fits the template
+func (t *Template) Extract(np string) (map[string]string, bool) {
+	if t == nil { return nil, false }
+
+	var params map[string]string
+	if !t.walk(np, &params) { return nil, false }
+	return params, true
+}
+
+// Walks the path segment by segment, collecting params only when asked to
+func (t *Template) walk(np string, params *map[string]string) bool {
+	rest := strings.TrimPrefix(np, "/")
+
+	for i, seg := range t.Segments {
+		if seg.Kind == Wildcard && i == len(t.Segments)-1 { return rest != "" }
+
+		var part string
+		part, rest, _ = strings.Cut(rest, "/")
+		if seg.Kind != Static && part == "" { return false }
+
+		switch seg.Kind {
+			case Static:
+				if part != seg.Value { return false }
+			case Param:
+				if params == nil { continue }
+				if *params == nil { *params = make(map[string]string, len(t.Segments)) }
+				(*params)[seg.Value] = part
+		}
+	}
+	return rest == ""
+}
+
+// Normalizes a configured template the same way request paths are normalized
+func normalizeTemplate(tpl string) string {
+	if idx := strings.Index(tpl, "?"); idx != -1 {
+		tpl = tpl[:idx]
+	}
+	return Normalize(tpl)
+}
+
+// Strips the query, trailing slash and version prefix (/v1, /v1.2) and ensures a leading slash.
+// Only re-slices the input, so request paths are normalized without allocating.
+func Normalize(p string) string {
+	if p == "" { return p }
+
+	if idx := strings.IndexByte(p, '?'); idx != -1 {
+		p = p[:idx]
+	}
+	p = strings.TrimSpace(p)
+	if p == "" { return "/" }
+
+	// remove trailing slash (but keep root)
+	if len(p) > 1 { p = strings.TrimSuffix(p, "/") }
+
+	// strip version prefix like /v1 or /v1.2
+	trimmed := strings.TrimPrefix(p, "/")
+	first, _, _ := strings.Cut(trimmed, "/")
+
+	if isVersionSegment(first) {
+		p = trimmed[len(first):]
+		if p == "" { return "/" }
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	return p
+}
+
+func isVersionSegment(seg string) bool {
+	return len(seg) > 1 && seg[0] == 'v' && seg[1] >= '0' && seg[1] <= '9'
+}
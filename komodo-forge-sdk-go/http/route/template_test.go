@@ -0,0 +1,42 @@
+package route
+
+import "testing"
+
+func TestTemplateMatch(t *testing.T) {
+	cases := []struct {
+		tpl  string
+		path string
+		want bool
+	}{
+		{"/item/{sku}", "/v1/item/ABC-1", true},
+		{"/item/{sku}", "/item/ABC-1/", true},
+		{"/users/:id/posts", "/users/42/posts", true},
+		{"/files/*", "/files/a/b/c.txt?x=1", true},
+		{"/oauth/*", "/oauth", false},
+		{"/item/{sku}", "/item", false},
+		{"/item/{sku}", "/item/a/b", false},
+		{"/users/:id/posts", "/users//posts", false},
+		{"/item/inventory", "/v2/item/inventory", true},
+		{"/item/inventory", "/item/suggestion", false},
+	}
+	for _, tc := range cases {
+		if got := Parse(tc.tpl).Match(tc.path); got != tc.want {
+			t.Errorf("%s matching %q = %v, want %v", tc.tpl, tc.path, got, tc.want)
+		}
+	}
+
+	// Catch-all policy routes compile to nil, which matches everything including the root
+	for _, tpl := range []string{"", "/", "*", "/*"} {
+		if rt := ParsePolicyRoute(tpl); rt != nil || !rt.Match("/") || !rt.Match("/any/path") {
+			t.Errorf("policy route %q should match every path", tpl)
+		}
+	}
+
+	params, ok := Parse("/users/:id/items/{sku}").Extract(Normalize("/v1/users/42/items/ABC"))
+	if !ok || params["id"] != "42" || params["sku"] != "ABC" {
+		t.Errorf("extract: got %v, %v", params, ok)
+	}
+	if params, ok := Parse("/health").Extract("/health"); !ok || params != nil {
+		t.Errorf("static extract should match without params, got %v, %v", params, ok)
+	}
+}
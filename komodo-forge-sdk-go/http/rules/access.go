@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpReq "komodo-forge-sdk-go/http/request"
+	"komodo-forge-sdk-go/http/services/authz"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrOriginNotAllowed  = errors.New("client type not permitted for route")
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrAdminRequired     = errors.New("admin privileges required")
+)
+
+// Returns the request's client type, preferring the value set by ClientTypeMiddleware
+func ClientType(req *http.Request) string {
+	if ct, ok := req.Context().Value(ctxKeys.CLIENT_TYPE_KEY).(string); ok && ct != "" {
+		return ct
+	}
+	return httpReq.GetClientType(req)
+}
+
+// Returns the rule with the overrides for the request's client type and API version merged in.
+// The base rule is never mutated; rules without a matching override are returned as is.
+func Resolve(req *http.Request, rule *EvalRule) *EvalRule {
+	if req == nil || rule == nil { return rule }
+	if len(rule.Origins) == 0 && len(rule.Versions) == 0 { return rule }
+
+	out := rule
+	if override, ok := rule.Origins[ClientType(req)]; ok {
+		out = out.merge(override)
+	}
+	if major, ok := httpReq.GetAPIMajorVersion(req); ok {
+		if override, ok := rule.Versions[major]; ok {
+			out = out.merge(override)
+			out.RequiredVersion = major
+		}
+	}
+	return out
+}
+
+// Reports whether the rule accepts the major version, either as its required version or a version override
+func (r *EvalRule) AcceptsVersion(major int) bool {
+	if major == r.RequiredVersion { return true }
+	_, ok := r.Versions[major]
+	return ok
+}
+
+// Checks client type, scope and admin requirements against the request context.
+// Errors wrap ErrOriginNotAllowed, ErrInsufficientScope or ErrAdminRequired.
+func Authorize(req *http.Request, rule *EvalRule) error {
+	if req == nil || rule == nil { return nil }
+
+	if len(rule.OriginTypes) > 0 {
+		if ct := ClientType(req); !slices.Contains(rule.OriginTypes, ct) {
+			return fmt.Errorf("%w: %q not in %v", ErrOriginNotAllowed, ct, rule.OriginTypes)
+		}
+	}
+
+	if len(rule.Scopes) > 0 {
+		granted, _ := req.Context().Value(ctxKeys.SCOPES_KEY).([]string)
+		if missing := MissingScopes(granted, rule.Scopes); len(missing) > 0 {
+			return fmt.Errorf("%w: missing %s", ErrInsufficientScope, strings.Join(missing, " "))
+		}
+	}
+
+	if rule.Admin {
+		if isAdmin, _ := req.Context().Value(ctxKeys.IS_ADMIN_KEY).(bool); !isAdmin {
+			return ErrAdminRequired
+		}
+	}
+	return nil
+}
+
+// Returns the required scopes that were not granted, honouring scope hierarchy (orders:write covers orders:read)
+func MissingScopes(granted []string, required []string) []string {
+	return authz.MissingScopes(granted, required)
+}
+
+// Overlays an origin override onto a copy of the rule: maps merge per key, scopes append, admin is sticky
+func (r *EvalRule) merge(o EvalRule) *EvalRule {
+	out := *r
+	out.Origins = nil
+	out.Versions = nil
+
+	if o.Level != "" { out.Level = o.Level }
+	if o.RequiredVersion > 0 { out.RequiredVersion = o.RequiredVersion }
+	if o.AdditionalBody != nil { out.AdditionalBody = o.AdditionalBody }
+	if len(o.OriginTypes) > 0 { out.OriginTypes = o.OriginTypes }
+
+	out.Admin = r.Admin || o.Admin
+	out.Scopes = append(slices.Clone(r.Scopes), o.Scopes...)
+	out.Headers = mergeMap(r.Headers, o.Headers)
+	out.PathParams = mergeMap(r.PathParams, o.PathParams)
+	out.QueryParams = mergeMap(r.QueryParams, o.QueryParams)
+	out.Body = mergeMap(r.Body, o.Body)
+	out.Form = mergeMap(r.Form, o.Form)
+	if o.Response != nil { out.Response = o.Response }
+
+	return &out
+}
+
+func mergeMap[M ~map[string]V, V any](base M, over M) M {
+	if len(over) == 0 { return base }
+
+	out := make(M, len(base) + len(over))
+	for k, v := range base { out[k] = v }
+	for k, v := range over { out[k] = v }
+	return out
+}
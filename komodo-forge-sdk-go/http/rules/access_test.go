@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+const accessTestRules = `
+rules:
+  /orders:
+    POST:
+      requiredVersion: 1
+      scopes: ["orders:write"]
+      body:
+        sku:
+          type: "string"
+          required: true
+      origins:
+        browser:
+          headers:
+            "X-CSRF-Token":
+              required: true
+      versions:
+        2:
+          scopes: ["orders:v2"]
+          body:
+            quantity:
+              type: "integer"
+              required: true
+`
+
+func withClientType(req *http.Request, clientType string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), ctxKeys.CLIENT_TYPE_KEY, clientType))
+}
+
+func TestResolveMergesOriginAndVersionOverrides(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.LoadData([]byte(accessTestRules)); err != nil { t.Fatalf("load: %v", err) }
+
+	cases := []struct {
+		clientType string
+		path       string
+		version    int
+		scopes     []string
+		body       []string
+		csrf       bool
+	}{
+		{OriginAPI, "/v1/orders", 1, []string{"orders:write"}, []string{"sku"}, false},
+		{OriginBrowser, "/v1/orders", 1, []string{"orders:write"}, []string{"sku"}, true},
+		{OriginAPI, "/v2/orders", 2, []string{"orders:write", "orders:v2"}, []string{"quantity", "sku"}, false},
+		{OriginBrowser, "/v2/orders", 2, []string{"orders:write", "orders:v2"}, []string{"quantity", "sku"}, true},
+	}
+	for _, tc := range cases {
+		base := reg.GetRule(tc.path, "POST")
+		if base == nil { t.Fatalf("%s: no rule", tc.path) }
+
+		rule := Resolve(withClientType(httptest.NewRequest("POST", tc.path, nil), tc.clientType), base)
+		name := tc.clientType + " " + tc.path
+
+		if rule.RequiredVersion != tc.version {
+			t.Errorf("%s: required version %d, want %d", name, rule.RequiredVersion, tc.version)
+		}
+		if !slices.Equal(rule.Scopes, tc.scopes) {
+			t.Errorf("%s: scopes %v, want %v", name, rule.Scopes, tc.scopes)
+		}
+		body := make([]string, 0, len(rule.Body))
+		for field := range rule.Body { body = append(body, field) }
+		slices.Sort(body)
+		if !slices.Equal(body, tc.body) {
+			t.Errorf("%s: body fields %v, want %v", name, body, tc.body)
+		}
+		if _, csrf := rule.Headers["X-CSRF-Token"]; csrf != tc.csrf {
+			t.Errorf("%s: csrf header required = %v, want %v", name, csrf, tc.csrf)
+		}
+
+		// Overrides never leak into the shared base rule
+		if len(base.Scopes) != 1 || len(base.Body) != 1 || len(base.Headers) != 0 {
+			t.Fatalf("%s: base rule was mutated: %+v", name, base)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	rule := &EvalRule{OriginTypes: []string{OriginAPI}, Scopes: []string{"orders:read"}, Admin: true}
+
+	cases := []struct {
+		name       string
+		clientType string
+		scopes     []string
+		admin      bool
+		want       error
+	}{
+		{"allowed", OriginAPI, []string{"orders:read"}, true, nil},
+		{"write covers read", OriginAPI, []string{"orders:write"}, true, nil},
+		{"wrong origin", OriginBrowser, []string{"orders:read"}, true, ErrOriginNotAllowed},
+		{"missing scope", OriginAPI, []string{"payments:read"}, true, ErrInsufficientScope},
+		{"no scopes", OriginAPI, nil, true, ErrInsufficientScope},
+		{"not admin", OriginAPI, []string{"orders:read"}, false, ErrAdminRequired},
+	}
+	for _, tc := range cases {
+		req := withClientType(httptest.NewRequest("GET", "/orders", nil), tc.clientType)
+		ctx := context.WithValue(req.Context(), ctxKeys.SCOPES_KEY, tc.scopes)
+		ctx = context.WithValue(ctx, ctxKeys.IS_ADMIN_KEY, tc.admin)
+
+		err := Authorize(req.WithContext(ctx), rule)
+		if (tc.want == nil && err != nil) || (tc.want != nil && !errors.Is(err, tc.want)) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
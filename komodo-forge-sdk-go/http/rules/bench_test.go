@@ -0,0 +1,294 @@
+// Rule lookup and validation against komodo-auth-api's validation_rules.yml.
+//
+//	go test ./http/rules -run xxx -bench . -count=3
+//
+// Lookups run against the radix tree and, as a baseline, the regex route walk it replaced.
+// Validation baselines also drop the matchers compiled at load, so every pattern is compiled per
+// request and enums are scanned linearly, the way validation ran before.
+// ValidateTokenRequest decodes the body itself; the SharedBody variant reuses the map decoded by
+// SanitizationMiddleware, which is what services run.
+package rules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	httpReq "komodo-forge-sdk-go/http/request"
+	logger "komodo-forge-sdk-go/logging/runtime"
+)
+
+const authRulesPath = "../../../komodo-auth-api/internal/config/validation_rules.yml"
+
+// Carries codeChallengeMethod so the query's enum check runs
+const authorizeURL = "/v1/oauth/authorize?responseType=code&clientId=web&redirectUri=https%3A%2F%2Fshop.local%2Fcb" +
+	"&codeChallenge=E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM&codeChallengeMethod=S256"
+
+const tokenBody = `{"clientId":"svc-orders","clientSecret":"s3cr3t-value","grantType":"client_credentials"}`
+
+// Loads the auth-api rules file into a fresh registry, skipping when the service isn't checked out alongside
+func loadAuthRules(b *testing.B) *Registry {
+	b.Helper()
+	logger.Init("rules-bench", "error", "bench")
+
+	if _, err := os.Stat(authRulesPath); err != nil {
+		b.Skip("auth-api rules file not found: " + authRulesPath)
+	}
+
+	reg := NewRegistry()
+	if err := reg.Load(authRulesPath); err != nil {
+		b.Fatalf("failed to load auth rules: %v", err)
+	}
+	return reg
+}
+
+func newTokenRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/oauth/token", strings.NewReader(tokenBody))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Requested-By", "bench")
+	req.Header.Set("User-Agent", "bench/1.0")
+	return req
+}
+
+func BenchmarkGetRuleStatic(b *testing.B) {
+	reg := loadAuthRules(b)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if reg.GetRule("/v1/oauth/token", http.MethodPost) == nil {
+			b.Fatal("expected rule")
+		}
+	}
+}
+
+func BenchmarkGetRuleMiss(b *testing.B) {
+	reg := loadAuthRules(b)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if reg.GetRule("/v1/oauth/unknown/route", http.MethodPost) != nil {
+			b.Fatal("expected miss")
+		}
+	}
+}
+
+func BenchmarkGetRuleStaticBaseline(b *testing.B) {
+	walk := newRegexWalk(loadAuthRules(b).GetRules())
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if walk.GetRule("/v1/oauth/token", http.MethodPost) == nil {
+			b.Fatal("expected rule")
+		}
+	}
+}
+
+func BenchmarkGetRuleMissBaseline(b *testing.B) {
+	walk := newRegexWalk(loadAuthRules(b).GetRules())
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if walk.GetRule("/v1/oauth/unknown/route", http.MethodPost) != nil {
+			b.Fatal("expected miss")
+		}
+	}
+}
+
+func BenchmarkValidateAuthorizeQuery(b *testing.B) {
+	reg := loadAuthRules(b)
+	rule := reg.GetRule("/v1/oauth/authorize", http.MethodGet)
+	req := httptest.NewRequest(http.MethodGet, authorizeURL, nil)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if !Validate(req, rule).Valid() {
+			b.Fatal("expected valid request")
+		}
+	}
+}
+
+func BenchmarkValidateAuthorizeQueryBaseline(b *testing.B) {
+	rule := uncompiled(*loadAuthRules(b).GetRule("/v1/oauth/authorize", http.MethodGet))
+	req := httptest.NewRequest(http.MethodGet, authorizeURL, nil)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if !Validate(req, &rule).Valid() {
+			b.Fatal("expected valid request")
+		}
+	}
+}
+
+func BenchmarkValidateTokenRequest(b *testing.B) {
+	reg := loadAuthRules(b)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		req := newTokenRequest()
+		if !Validate(req, reg.GetRule(req.URL.Path, req.Method)).Valid() {
+			b.Fatal("expected valid request")
+		}
+	}
+}
+
+func BenchmarkValidateTokenRequestSharedBody(b *testing.B) {
+	reg := loadAuthRules(b)
+	decoded := map[string]any{"clientId": "svc-orders", "clientSecret": "s3cr3t-value", "grantType": "client_credentials"}
+	b.ReportAllocs()
+
+	for b.Loop() {
+		req := httpReq.WithJSONBody(newTokenRequest(), []byte(tokenBody), decoded)
+		if !Validate(req, reg.GetRule(req.URL.Path, req.Method)).Valid() {
+			b.Fatal("expected valid request")
+		}
+	}
+}
+
+func BenchmarkValidateTokenRequestBaseline(b *testing.B) {
+	cfg := RuleConfig{}
+	for tpl, methods := range loadAuthRules(b).GetRules() {
+		cfg[tpl] = make(map[string]EvalRule, len(methods))
+		for method, rule := range methods { cfg[tpl][method] = uncompiled(rule) }
+	}
+	walk := newRegexWalk(cfg)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		req := newTokenRequest()
+		if !Validate(req, walk.GetRule(req.URL.Path, req.Method)).Valid() {
+			b.Fatal("expected valid request")
+		}
+	}
+}
+
+// The lookup GetRule did before the radix tree: normalize the path, try an exact match, then walk
+// every templated route's regexp from most to least specific. Only kept to benchmark against.
+type regexWalk struct {
+	rules    RuleConfig
+	patterns []regexRoute
+}
+
+type regexRoute struct {
+	template string
+	re       *regexp.Regexp
+	methods  map[string]EvalRule
+}
+
+var walkVersionRE = regexp.MustCompile(`^v[0-9]`)
+
+func newRegexWalk(cfg RuleConfig) *regexWalk {
+	walk := &regexWalk{rules: cfg}
+	for tpl, methods := range cfg {
+		if !strings.ContainsAny(tpl, ":{*") { continue }
+
+		parts := strings.Split(strings.TrimPrefix(strings.TrimSuffix(tpl, "/"), "/"), "/")
+		for i, p := range parts {
+			switch {
+				case p == "*":
+					parts[i] = ".*"
+				case strings.HasPrefix(p, ":"):
+					parts[i] = `(?P<` + p[1:] + `>[^/]+)`
+				case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+					parts[i] = `(?P<` + p[1:len(p)-1] + `>[^/]+)`
+				default:
+					parts[i] = regexp.QuoteMeta(p)
+			}
+		}
+		re := regexp.MustCompile("^/" + strings.Join(parts, "/") + "$")
+		walk.patterns = append(walk.patterns, regexRoute{template: tpl, re: re, methods: methods})
+	}
+
+	slices.SortStableFunc(walk.patterns, func(a, b regexRoute) int {
+		return walkSpecificity(b.template) - walkSpecificity(a.template)
+	})
+	return walk
+}
+
+func (w *regexWalk) GetRule(path string, method string) *EvalRule {
+	np := walkNormalize(path)
+	if rules, ok := w.rules[np]; ok {
+		if rule, exists := rules[method]; exists { return &rule }
+	}
+	for _, rp := range w.patterns {
+		if rp.re.MatchString(np) {
+			if rule, exists := rp.methods[method]; exists { return &rule }
+		}
+	}
+	return nil
+}
+
+func walkNormalize(p string) string {
+	if idx := strings.Index(p, "?"); idx != -1 { p = p[:idx] }
+	if len(p) > 1 { p = strings.TrimSuffix(p, "/") }
+
+	segs := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if len(segs) > 0 && walkVersionRE.MatchString(segs[0]) {
+		p = "/" + strings.Join(segs[1:], "/")
+	}
+	if !strings.HasPrefix(p, "/") { p = "/" + p }
+	return p
+}
+
+func walkSpecificity(tpl string) int {
+	literal, wild := 0, 0
+	for _, p := range strings.Split(strings.TrimPrefix(tpl, "/"), "/") {
+		if p == "*" || strings.HasPrefix(p, ":") || (strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")) {
+			wild++
+		} else if p != "" {
+			literal++
+		}
+	}
+	return literal*10 - wild
+}
+
+// Copies a loaded rule without its compiled matchers, so checkString falls back to compiling each
+// pattern and scanning each enum on every request
+func uncompiled(rule EvalRule) EvalRule {
+	if rule.Headers != nil {
+		headers := make(Headers, len(rule.Headers))
+		for name, spec := range rule.Headers {
+			spec.re, spec.enumSet = nil, nil
+			headers[name] = spec
+		}
+		rule.Headers = headers
+	}
+	rule.PathParams = uncompiledParams(rule.PathParams)
+	rule.QueryParams = uncompiledParams(rule.QueryParams)
+	rule.Form = uncompiledParams(rule.Form)
+	rule.Body = uncompiledProperties(rule.Body)
+	return rule
+}
+
+func uncompiledParams[M ~map[string]ParamSpec](params M) M {
+	if params == nil { return nil }
+
+	out := make(M, len(params))
+	for name, spec := range params {
+		spec.re, spec.enumSet = nil, nil
+		out[name] = spec
+	}
+	return out
+}
+
+func uncompiledProperties[M ~map[string]Schema](props M) M {
+	if props == nil { return nil }
+
+	out := make(M, len(props))
+	for name, spec := range props { out[name] = uncompiledSchema(spec) }
+	return out
+}
+
+func uncompiledSchema(s Schema) Schema {
+	s.re, s.enumSet = nil, nil
+	s.Properties = uncompiledProperties(s.Properties)
+	if s.Items != nil {
+		items := uncompiledSchema(*s.Items)
+		s.Items = &items
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Precompiled matchers shared by every string-valued constraint
+type stringCheck struct {
+	pattern string
+	re      *regexp.Regexp
+	enum    []string
+	enumSet map[string]struct{}
+	minLen  int
+	maxLen  int
+}
+
+func (s HeaderSpec) check() stringCheck {
+	return stringCheck{s.Pattern, s.re, s.Enum, s.enumSet, s.MinLen, s.MaxLen}
+}
+
+func (s ParamSpec) check() stringCheck {
+	return stringCheck{s.Pattern, s.re, s.Enum, s.enumSet, s.MinLen, s.MaxLen}
+}
+
+func (s *Schema) check() stringCheck {
+	return stringCheck{s.Pattern, s.re, s.Enum, s.enumSet, s.MinLen, s.MaxLen}
+}
+
+// Compiles patterns and enum sets for every field in the rule, in place, so requests never compile
+func compileRule(rule *EvalRule) error {
+	if err := compileHeaders(rule.Headers, "header"); err != nil { return err }
+	if err := compileParams(rule.PathParams, "param"); err != nil { return err }
+	if err := compileParams(rule.QueryParams, "query"); err != nil { return err }
+	if err := compileParams(rule.Form, "form"); err != nil { return err }
+	if err := compileBody(rule.Body); err != nil { return err }
+
+	if res := rule.Response; res != nil {
+		if err := compileHeaders(res.Headers, "response header"); err != nil { return err }
+		if err := compileBody(res.Body); err != nil { return fmt.Errorf("response: %w", err) }
+	}
+	return nil
+}
+
+func compileHeaders(headers Headers, label string) error {
+	for name, spec := range headers {
+		re, set, err := compileMatchers(spec.Pattern, spec.Enum)
+		if err != nil { return fmt.Errorf("invalid pattern for %s %s: %w", label, name, err) }
+
+		spec.re, spec.enumSet = re, set
+		headers[name] = spec
+	}
+	return nil
+}
+
+func compileParams[M ~map[string]ParamSpec](params M, label string) error {
+	for name, spec := range params {
+		re, set, err := compileMatchers(spec.Pattern, spec.Enum)
+		if err != nil { return fmt.Errorf("invalid pattern for %s %s: %w", label, name, err) }
+
+		spec.re, spec.enumSet = re, set
+		params[name] = spec
+	}
+	return nil
+}
+
+func compileBody(body Body) error {
+	for name, spec := range body {
+		if err := validateSchemaConfig(name, &spec); err != nil { return err }
+		body[name] = spec
+	}
+	return nil
+}
+
+// Returns the compiled pattern (nil when empty) and a lookup set for the enum (nil when empty)
+func compileMatchers(pattern string, enum []string) (*regexp.Regexp, map[string]struct{}, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil { return nil, nil, err }
+	}
+
+	var set map[string]struct{}
+	if len(enum) > 0 {
+		set = make(map[string]struct{}, len(enum))
+		for _, e := range enum { set[e] = struct{}{} }
+	}
+	return re, set, nil
+}
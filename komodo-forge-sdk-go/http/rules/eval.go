@@ -7,71 +7,98 @@ import (
 	"io"
 	headers "komodo-forge-sdk-go/http/headers/eval"
 	httpReq "komodo-forge-sdk-go/http/request"
+	"komodo-forge-sdk-go/http/services/redaction"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"net/http"
+	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
+const maxViolationValueLen = 64
+
 // Checks if the request complies with all aspects of the provided EvalRule.
-func IsRuleValid(req *http.Request, rule *EvalRule) bool {
+func IsRuleValid(req *http.Request, rule *EvalRule) bool { return Validate(req, rule).Valid() }
+
+// Evaluates the request against the rule and collects every violation instead of stopping at the first.
+func Validate(req *http.Request, rule *EvalRule) *ValidationReport {
+	report := &ValidationReport{}
+
 	if req == nil || rule == nil {
 		logger.Error("api request or eval rule is nil", fmt.Errorf("request or rule is nil"))
-		return false
+		report.add(Violation{Constraint: ConstraintRequired, Reason: "request or rule is missing"})
+		return report
 	}
 	if rule.Level == LevelIgnore {
-		logger.Info("rule level is IGNORE - skipping all validations")
-		return true
+		logger.Debug("rule level is IGNORE - skipping all validations")
+		return report
 	}
 
-	if !isValidVersion(req, rule) {
-		logger.Error("validation failure: version check", fmt.Errorf("version validation failed"))
-		return false
-	}
-	if !areValidHeaders(req, rule) {
-		logger.Error("validation failure: headers check", fmt.Errorf("headers validation failed"))
-		return false
-	}
-	if !areValidPathParams(req, rule) {
-		logger.Error("validation failure: path params check", fmt.Errorf("path params validation failed"))
-		return false
-	}
-	if !areValidQueryParams(req, rule) {
-		logger.Error("validation failure: query params check", fmt.Errorf("query params validation failed"))
-		return false
+	validateVersion(req, rule, report)
+	validateHeaders(req, rule, report)
+	validatePathParams(req, rule, report)
+	validateQueryParams(req, rule, report)
+	validateBody(req, rule, report)
+
+	if report.Valid() {
+		logger.Debug("all validations passed")
 	}
-	if !isValidBody(req, rule) {
-		logger.Error("validation failure: body check", fmt.Errorf("body validation failed"))
-		return false
+	return report
+}
+
+// Reports whether no violations were collected
+func (r *ValidationReport) Valid() bool { return r == nil || len(r.Violations) == 0 }
+
+// Records a violation and logs it with the (already redacted) value
+func (r *ValidationReport) add(v Violation) {
+	r.Violations = append(r.Violations, v)
+	logger.Error(
+		fmt.Sprintf("validation failure: %s %q failed %s check", v.Location, v.Field, v.Constraint),
+		fmt.Errorf("%s", v.Reason),
+	)
+}
+
+// Builds a violation with the offending value redacted and truncated
+func newViolation(location string, field string, constraint string, value any, reason string) Violation {
+	v := Violation{Location: location, Field: field, Constraint: constraint, Reason: reason}
+	if value == nil { return v }
+
+	// Redact on the leaf property name so nested paths like "card.cvv" or "tokens[0]" still match
+	key := field[strings.LastIndex(field, ".")+1:]
+	if idx := strings.Index(key, "["); idx != -1 { key = key[:idx] }
+
+	str := fmt.Sprint(redaction.RedactPair(key, value))
+	if len(str) > maxViolationValueLen {
+		str = str[:maxViolationValueLen] + "..."
 	}
-	
-	logger.Info("all validations passed")
-	return true
+	v.Value = str
+	return v
 }
 
 // Checks if the request version matches the required version in the rule.
-func isValidVersion(req *http.Request, rule *EvalRule) bool {
+func validateVersion(req *http.Request, rule *EvalRule, report *ValidationReport) {
+	versionStr := httpReq.GetAPIVersion(req)
+
 	// Lenient mode: version validation is optional
 	if rule.Level == LevelLenient {
-		versionStr := httpReq.GetAPIVersion(req)
 		if versionStr == "" {
 			logger.Warn("version not provided in request using lenient mode - allowing")
-			return true
+			return
 		}
 
-		versionStr = strings.TrimPrefix(versionStr, "/v")
-		version, err := strconv.Atoi(versionStr)
-		if err != nil {
+		version, ok := httpReq.ParseMajorVersion(versionStr)
+		if !ok {
 			logger.Warn(fmt.Sprintf("invalid version format: %s (lenient mode - allowing)", versionStr))
-			return true
+			return
 		}
-		if rule.RequiredVersion > 0 && version != rule.RequiredVersion {
+		if rule.RequiredVersion > 0 && !rule.AcceptsVersion(version) {
 			logger.Warn(fmt.Sprintf("version mismatch: required %d, got %d (lenient mode - allowing)", rule.RequiredVersion, version))
-			return true
+			return
 		}
-		logger.Info(fmt.Sprintf("version validation passed (lenient): v%d", version))
-		return true
+		logger.Debug("version validation passed", "mode", LevelLenient, "version", version)
+		return
 	}
 
 	// Strict mode: version is mandatory
@@ -80,304 +107,143 @@ func isValidVersion(req *http.Request, rule *EvalRule) bool {
 			"rule configuration error: requiredVersion must be >= 1 for strict validation",
 			fmt.Errorf("invalid requiredVersion"),
 		)
-		return false
+		report.add(newViolation(LocationPath, "version", ConstraintVersion, nil, "route has no valid version configured"))
+		return
 	}
-
-	versionStr := httpReq.GetAPIVersion(req)
 	if versionStr == "" {
-		logger.Error(
-			fmt.Sprintf("version required (v%d) but not found in request", rule.RequiredVersion), 
-			fmt.Errorf("version not found"),
-		)
-		return false
+		report.add(newViolation(
+			LocationPath, "version", ConstraintRequired, nil,
+			fmt.Sprintf("API version v%d is required", rule.RequiredVersion),
+		))
+		return
 	}
 
-	// Parse version number (e.g., "/v1" -> 1)
-	versionStr = strings.TrimPrefix(versionStr, "/v")
-	version, err := strconv.Atoi(versionStr)
-	if err != nil {
-		logger.Error(
-			fmt.Sprintf("invalid version format: %s", versionStr),
-			fmt.Errorf("invalid version format"),
-		)
-		return false
+	// Parse major version number (e.g., "/v1" -> 1, "/v2.1" -> 2)
+	version, ok := httpReq.ParseMajorVersion(versionStr)
+	if !ok {
+		report.add(newViolation(LocationPath, "version", ConstraintType, versionStr, "API version must be numeric"))
+		return
 	}
-
-	if version != rule.RequiredVersion {
-		logger.Error(
-			fmt.Sprintf("version mismatch: required %d, got %d", rule.RequiredVersion, version),
-			fmt.Errorf("version mismatch"),
-		)
-		return false
+	if !rule.AcceptsVersion(version) {
+		report.add(newViolation(
+			LocationPath, "version", ConstraintVersion, versionStr,
+			fmt.Sprintf("API version must be v%d", rule.RequiredVersion),
+		))
+		return
 	}
 
-	logger.Info(fmt.Sprintf("version validation passed (strict): v%d", version))
-	return true
+	logger.Debug("version validation passed", "mode", LevelStrict, "version", version)
 }
 
 // Checks if the request headers comply with the provided EvalRule.
-func areValidHeaders(req *http.Request, rule *EvalRule) bool {
+func validateHeaders(req *http.Request, rule *EvalRule, report *ValidationReport) {
 	for hName, spec := range rule.Headers {
 		val := req.Header.Get(hName)
 
-		// Check if required and missing
-		if spec.Required && val == "" {
-			logger.Error(
-				fmt.Sprintf("header %q is required but missing", hName),
-				fmt.Errorf("header missing"),
-			)
-			return false
+		if val == "" {
+			if spec.Required {
+				report.add(newViolation(LocationHeader, hName, ConstraintRequired, nil, "header is required"))
+			}
+			continue
 		}
-		if val == "" { continue }
 
-		// Check exact value match if specified
+		// Check exact value match if specified (supports wildcard suffix e.g. "Bearer *")
 		if spec.Value != "" {
-			// Support wildcard matching for "Bearer *" etc
-			if spec.Value[len(spec.Value)-1] == '*' {
-				prefix := spec.Value[:len(spec.Value)-1]
+			if prefix, ok := strings.CutSuffix(spec.Value, "*"); ok {
 				if !strings.HasPrefix(val, prefix) {
-					logger.Error(
-						fmt.Sprintf("header %q value %q does not match required prefix %q", hName, val, prefix),
-						fmt.Errorf("header value mismatch"),
-					)
-					return false
+					report.add(newViolation(
+						LocationHeader, hName, ConstraintValue, val, fmt.Sprintf("must start with %q", prefix),
+					))
+					continue
 				}
 			} else if val != spec.Value {
-				logger.Error(
-					fmt.Sprintf("header %q value %q does not match required value %q", hName, val, spec.Value),
-					fmt.Errorf("header value mismatch"),
-				)
-				return false
+				report.add(newViolation(
+					LocationHeader, hName, ConstraintValue, val, fmt.Sprintf("must equal %q", spec.Value),
+				))
+				continue
 			}
 		}
 
-		// if pattern provided, verify
-		if spec.Pattern != "" {
-			re, err := regexp.Compile(spec.Pattern)
-			if err != nil || !re.MatchString(val) {
-				logger.Error(
-					fmt.Sprintf("header %q value %q does not match pattern %q", hName, val, spec.Pattern),
-					fmt.Errorf("header pattern mismatch"),
-				)
-				return false
-			}
-		}
-
-		// enum check
-		if len(spec.Enum) > 0 {
-			ok := false
-			for _, e := range spec.Enum {
-				if e == val {
-					ok = true
-					break
-				}
-			}
-			if !ok {
-				logger.Error(
-					fmt.Sprintf("header %q value %q not in enum %v", hName, val, spec.Enum),
-					fmt.Errorf("header enum mismatch"),
-				)
-				return false
-			}
-		}
+		before := len(report.Violations)
+		checkString(report, LocationHeader, hName, val, spec.check())
+		if len(report.Violations) > before { continue }
 
-		// length checks
-		if spec.MinLen > 0 && len(val) < spec.MinLen {
-			logger.Error(
-				fmt.Sprintf("header %q value length %d is less than minLen %d", hName, len(val), spec.MinLen),
-				fmt.Errorf("header length mismatch"),
-			)
-			return false
-		}
-		if spec.MaxLen > 0 && len(val) > spec.MaxLen {
-			logger.Error(
-				fmt.Sprintf("header %q value length %d is greater than maxLen %d", hName, len(val), spec.MaxLen),
-				fmt.Errorf("header length mismatch"),
-			)
-			return false
-		}
-		// header-specific validation (optional - comment out if causing issues)
+		// header-specific validation
 		if ok, err := headers.ValidateHeaderValue(hName, req); !ok || err != nil {
-			logger.Error(
-				fmt.Sprintf("header %q failed ValidateHeaderValue check", hName),
-				err,
-			)
-			return false
+			reason := "invalid header format"
+			if err != nil { reason = err.Error() }
+			report.add(newViolation(LocationHeader, hName, ConstraintFormat, val, reason))
 		}
 	}
-	logger.Info("all headers passed validation")
-	return true
 }
 
 // Checks if the request path parameters comply with the provided EvalRule.
-func areValidPathParams(req *http.Request, rule *EvalRule) bool {
-	// find matching pattern and extract params
-	_, params := matchRouteAndExtractParams(req.URL.Path)
-	if params == nil {
-		// no dynamic params in path; ensure rule does not require any
-		for k, spec := range rule.PathParams {
-			if spec.Required {
-				// required param missing
-				_ = k
-				logger.Error(
-					fmt.Sprintf("path param %q is required but missing", k),
-					fmt.Errorf("path param missing"),
-				)
-				return false
-			}
-		}
-		return true
-	}
+func validatePathParams(req *http.Request, rule *EvalRule, report *ValidationReport) {
+	_, params := matchRouteAndExtractParams(req.URL.Path, rule)
 
-	// validate each rule-specified param
 	for name, spec := range rule.PathParams {
-		val, ok := params[name]
-		if !ok || val == "" {
+		val := params[name]
+		if val == "" {
 			if spec.Required {
-				logger.Error(
-					fmt.Sprintf("path param %q is required but missing", name),
-					fmt.Errorf("path param missing"),
-				)
-				return false
+				report.add(newViolation(LocationPath, name, ConstraintRequired, nil, "path parameter is required"))
 			}
 			continue
 		}
 
-		// pattern check
-		if spec.Pattern != "" {
-			re, err := regexp.Compile(spec.Pattern)
-			if err != nil || !re.MatchString(val) {
-				logger.Error(
-					fmt.Sprintf("path param %q value %q does not match pattern %q", name, val, spec.Pattern),
-					fmt.Errorf("path param pattern mismatch"),
-				)
-				return false
-			}
-		}
-
-		// enum check
-		if len(spec.Enum) > 0 {
-			okEnum := false
-			for _, e := range spec.Enum {
-				if e == val { okEnum = true; break }
-			}
-			if !okEnum {
-				logger.Error(
-					fmt.Sprintf("path param %q value %q not in enum %v", name, val, spec.Enum),
-					fmt.Errorf("path param enum mismatch"),
-				)
-				return false
-			}
-		}
+		before := len(report.Violations)
+		checkString(report, LocationPath, name, val, spec.check())
+		if len(report.Violations) > before { continue }
 
-		// length checks
-		if spec.MinLen > 0 && len(val) < spec.MinLen {
-			logger.Error(
-				fmt.Sprintf("path param %q value length %d is less than minLen %d", name, len(val), spec.MinLen),
-				fmt.Errorf("path param length mismatch"),
-			)
-			return false
-		}
-		if spec.MaxLen > 0 && len(val) > spec.MaxLen {
-			logger.Error(
-				fmt.Sprintf("path param %q value length %d is greater than maxLen %d", name, len(val), spec.MaxLen),
-				fmt.Errorf("path param length mismatch"),
-			)
-			return false
-		}
-
-		// simple type validation for common scalar types
-		switch spec.Type {
-			case "", "string":
-				// already a string
-			case "int":
-				if _, err := strconv.Atoi(val); err != nil {
-					logger.Error(
-						fmt.Sprintf("path param %q value %q is not a valid int", name, val),
-						fmt.Errorf("path param type mismatch"),
-					)
-					return false
-				}
-			case "bool":
-				if val != "true" && val != "false" {
-					logger.Error(
-						fmt.Sprintf("path param %q value %q is not a valid bool", name, val),
-						fmt.Errorf("path param type mismatch"),
-					)
-					return false
-				}
-			default:
-				// unknown types are treated as pass-through for now
-		}
+		checkScalarType(report, LocationPath, name, val, spec.Type)
 	}
-	return true
 }
 
 // Checks if the request query parameters comply with the provided EvalRule.
-func areValidQueryParams(req *http.Request, rule *EvalRule) bool {
+func validateQueryParams(req *http.Request, rule *EvalRule, report *ValidationReport) {
+	if len(rule.QueryParams) == 0 { return }
 	params := httpReq.GetQueryParams(req)
 
 	for name, spec := range rule.QueryParams {
-		val, ok := params[name]
-		if !ok || val == "" {
+		val := params[name]
+		if val == "" {
 			if spec.Required {
-				logger.Error(
-					fmt.Sprintf("query param %q is required but missing", name),
-					fmt.Errorf("query param missing"),
-				)
-				return false
+				report.add(newViolation(LocationQuery, name, ConstraintRequired, nil, "query parameter is required"))
 			}
 			continue
 		}
 
-		if spec.Pattern != "" {
-			re, err := regexp.Compile(spec.Pattern)
-			if err != nil || !re.MatchString(val) {
-				logger.Error(
-					fmt.Sprintf("query param %q value %q does not match pattern %q", name, val, spec.Pattern),
-					fmt.Errorf("query param pattern mismatch"),
-				)
-				return false
-			}
-		}
+		before := len(report.Violations)
+		checkString(report, LocationQuery, name, val, spec.check())
+		if len(report.Violations) > before { continue }
 
-		if len(spec.Enum) > 0 {
-			okv := false
-			for _, e := range spec.Enum {
-				if e == val { okv = true; break }
-			}
-			if !okv {
-				logger.Error(
-					fmt.Sprintf("query param %q value %q not in enum %v", name, val, spec.Enum),
-					fmt.Errorf("query param enum mismatch"),
-				)
-				return false
-			}
-		}
-
-		if spec.MinLen > 0 && len(val) < spec.MinLen {
-			logger.Error(
-				fmt.Sprintf("query param %q value length %d is less than minLen %d", name, len(val), spec.MinLen),
-				fmt.Errorf("query param length mismatch"),
-			)
-			return false
-		}
-		if spec.MaxLen > 0 && len(val) > spec.MaxLen {
-			logger.Error(
-				fmt.Sprintf("query param %q value length %d is greater than maxLen %d", name, len(val), spec.MaxLen),
-				fmt.Errorf("query param length mismatch"),
-			)
-			return false
-		}
+		checkScalarType(report, LocationQuery, name, val, spec.Type)
 	}
-	return true
 }
 
 // Checks if the request body complies with the provided EvalRule.
-func isValidBody(req *http.Request, rule *EvalRule) bool {
+func validateBody(req *http.Request, rule *EvalRule, report *ValidationReport) {
 	switch req.Method {
 		case http.MethodGet, http.MethodHead, http.MethodOptions:
-			return true
+			return
+	}
+	if req.Body == nil { return }
+
+	if httpReq.IsFormRequest(req) {
+		validateForm(req, rule, report)
+		return
+	}
+
+	// Reuse the body already decoded by SanitizationMiddleware when present
+	if cached, ok := httpReq.GetJSONBody(req); ok {
+		if cached.Value == nil { return }
+
+		bodyMap, ok := cached.Value.(map[string]any)
+		if !ok {
+			report.add(newViolation(LocationBody, "", ConstraintType, nil, "request body must be a JSON object"))
+			return
+		}
+		validateObject(report, LocationBody, "", bodyMap, rule.Body, rule.AdditionalBody)
+		return
 	}
 
 	// Read the body (it can only be read once)
@@ -385,7 +251,8 @@ func isValidBody(req *http.Request, rule *EvalRule) bool {
 	bodyBytes, err := io.ReadAll(io.LimitReader(req.Body, maxBody))
 	if err != nil {
 		logger.Error("failed to read request body", err)
-		return false
+		report.add(newViolation(LocationBody, "", ConstraintType, nil, "request body could not be read"))
+		return
 	}
 
 	// Restore the original body for downstream handlers
@@ -393,60 +260,114 @@ func isValidBody(req *http.Request, rule *EvalRule) bool {
 	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 	// If body is empty, that's valid for some requests
-	if len(bodyBytes) == 0 { return true }
+	if len(bodyBytes) == 0 { return }
 
-	// Parse JSON
 	var bodyMap map[string]any
-	dec := json.NewDecoder(bytes.NewReader(bodyBytes))
-	dec.DisallowUnknownFields()
-
-	if err := dec.Decode(&bodyMap); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&bodyMap); err != nil {
 		logger.Error("failed to decode request body as JSON", err)
-		return false
+		report.add(newViolation(LocationBody, "", ConstraintType, nil, "request body must be a JSON object"))
+		return
 	}
 
-	// Validate body fields against rule
-	for name, spec := range rule.Body {
-		v, ok := bodyMap[name]
-		if !ok {
+	validateObject(report, LocationBody, "", bodyMap, rule.Body, rule.AdditionalBody)
+}
+
+// Checks a form-encoded request body against the rule's form fields.
+// Repeated fields are rejected since every form field maps to a single value.
+func validateForm(req *http.Request, rule *EvalRule, report *ValidationReport) {
+	const maxBody = 1 << 20 // 1 MiB
+	bodyBytes, err := io.ReadAll(io.LimitReader(req.Body, maxBody))
+	if err != nil {
+		logger.Error("failed to read request body", err)
+		report.add(newViolation(LocationForm, "", ConstraintType, nil, "request body could not be read"))
+		return
+	}
+
+	// Restore the original body for downstream handlers
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	values, err := url.ParseQuery(string(bodyBytes))
+	if err != nil {
+		logger.Error("failed to decode form request body", err)
+		report.add(newViolation(LocationForm, "", ConstraintType, nil, "request body must be form encoded"))
+		return
+	}
+
+	for name, spec := range rule.Form {
+		if len(values[name]) > 1 {
+			report.add(newViolation(LocationForm, name, ConstraintType, nil, "form field must not be repeated"))
+			continue
+		}
+
+		val := values.Get(name)
+		if val == "" {
 			if spec.Required {
-				logger.Error(
-					fmt.Sprintf("body field %q is required but missing", name),
-					fmt.Errorf("body field missing"),
-				)
-				return false
+				report.add(newViolation(LocationForm, name, ConstraintRequired, nil, "form field is required"))
 			}
 			continue
 		}
 
-		// basic type checks
-		switch spec.Type {
-			case "", "string":
-				if _, ok := v.(string); !ok {
-					logger.Error(
-						fmt.Sprintf("body field %q is not a string", name),
-						fmt.Errorf("body field type mismatch"),
-					)
-					return false
-				}
-			case "int":
-				// JSON numbers are float64 by default
-				if _, ok := v.(float64); !ok {
-					logger.Error(
-						fmt.Sprintf("body field %q is not a number", name),
-						fmt.Errorf("body field type mismatch"),
-					)
-					return false
-				}
-			case "bool":
-				if _, ok := v.(bool); !ok {
-					logger.Error(
-						fmt.Sprintf("body field %q is not a bool", name),
-						fmt.Errorf("body field type mismatch"),
-					)
-					return false
-				}
+		before := len(report.Violations)
+		checkString(report, LocationForm, name, val, spec.check())
+		if len(report.Violations) > before { continue }
+
+		checkScalarType(report, LocationForm, name, val, spec.Type)
+	}
+}
+
+// Applies the pattern, enum and length constraints shared by all string-valued fields.
+// Uses the matchers compiled at load time, falling back to compiling for hand-built rules.
+func checkString(report *ValidationReport, location string, name string, val string, c stringCheck) {
+	if c.pattern != "" {
+		re := c.re
+		if re == nil { re, _ = regexp.Compile(c.pattern) }
+		if re == nil || !re.MatchString(val) {
+			report.add(newViolation(location, name, ConstraintPattern, val, fmt.Sprintf("must match pattern %q", c.pattern)))
+			return
 		}
 	}
-	return true
+
+	if len(c.enum) > 0 {
+		found := false
+		if c.enumSet != nil {
+			_, found = c.enumSet[val]
+		} else {
+			found = slices.Contains(c.enum, val)
+		}
+		if !found {
+			report.add(newViolation(
+				location, name, ConstraintEnum, val, fmt.Sprintf("must be one of [%s]", strings.Join(c.enum, ", ")),
+			))
+			return
+		}
+	}
+
+	if c.minLen > 0 && len(val) < c.minLen {
+		report.add(newViolation(location, name, ConstraintMinLen, val, fmt.Sprintf("must be at least %d characters", c.minLen)))
+		return
+	}
+	if c.maxLen > 0 && len(val) > c.maxLen {
+		report.add(newViolation(location, name, ConstraintMaxLen, val, fmt.Sprintf("must be at most %d characters", c.maxLen)))
+	}
+}
+
+// Validates simple scalar types for values that arrive as strings (path and query)
+func checkScalarType(report *ValidationReport, location string, name string, val string, typ string) {
+	switch typ {
+		case "int":
+			if _, err := strconv.Atoi(val); err != nil {
+				report.add(newViolation(location, name, ConstraintType, val, "must be an integer"))
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(val, 64); err != nil {
+				report.add(newViolation(location, name, ConstraintType, val, "must be a number"))
+			}
+		case "bool":
+			if val != "true" && val != "false" {
+				report.add(newViolation(location, name, ConstraintType, val, "must be a boolean"))
+			}
+		default:
+			// strings and unknown types are treated as pass-through for now
+	}
 }
@@ -1 +1,206 @@
-package rules
\ No newline at end of file
+package rules
+
+import (
+	"encoding/json"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const nestedBodyRules = `
+rules:
+  /orders:
+    POST:
+      level: "lenient"
+      body:
+        "items":
+          type: "array"
+          minItems: 1
+          items:
+            properties:
+              "sku":
+                type: "string"
+                required: true
+              "qty":
+                type: "int"
+                minimum: 1
+        "email":
+          type: "string"
+          format: "email"
+      additionalProperties: false
+`
+
+func TestValidateNestedBody(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.LoadData([]byte(nestedBodyRules)); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	body := `{"items":[{"sku":"A1","qty":0},{"qty":2}],"email":"nope","extra":true}`
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+
+	report := Validate(req, reg.GetRule("/orders", "POST"))
+
+	got := map[string]string{}
+	for _, v := range report.Violations { got[v.Field] = v.Constraint }
+
+	want := map[string]string{
+		"items[0].qty": ConstraintMinimum,
+		"items[1].sku": ConstraintRequired,
+		"email":        ConstraintFormat,
+		"extra":        ConstraintAdditionalProperties,
+	}
+	for field, constraint := range want {
+		if got[field] != constraint {
+			t.Errorf("field %s: expected %s violation, got %q", field, constraint, got[field])
+		}
+	}
+	if len(report.Violations) != len(want) {
+		t.Errorf("expected %d violations, got %+v", len(want), report.Violations)
+	}
+}
+
+const formBodyRules = `
+rules:
+  /oauth/token:
+    POST:
+      level: "lenient"
+      body:
+        "grantType":
+          type: "string"
+          required: true
+      form:
+        "grant_type":
+          type: "string"
+          required: true
+          enum: ["client_credentials"]
+        "client_id":
+          type: "string"
+          required: true
+        "scope":
+          type: "string"
+`
+
+func TestValidateFormBody(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.LoadData([]byte(formBodyRules)); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	rule := reg.GetRule("/oauth/token", "POST")
+
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader("grant_type=password&scope=a&scope=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	got := map[string]string{}
+	for _, v := range Validate(req, rule).Violations { got[v.Field] = v.Location + ":" + v.Constraint }
+
+	want := map[string]string{
+		"grant_type": LocationForm + ":" + ConstraintEnum,
+		"client_id":  LocationForm + ":" + ConstraintRequired,
+		"scope":      LocationForm + ":" + ConstraintType,
+	}
+	for field, constraint := range want {
+		if got[field] != constraint {
+			t.Errorf("field %s: expected %s violation, got %q", field, constraint, got[field])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d violations, got %v", len(want), got)
+	}
+
+	// The body must still be readable by the handler afterwards
+	req = httptest.NewRequest("POST", "/oauth/token", strings.NewReader("grant_type=client_credentials&client_id=svc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if report := Validate(req, rule); !report.Valid() {
+		t.Fatalf("expected valid form body, got %+v", report.Violations)
+	}
+	if err := req.ParseForm(); err != nil || req.PostForm.Get("client_id") != "svc" {
+		t.Errorf("expected form body to be restored, got %v (%v)", req.PostForm, err)
+	}
+}
+
+const sensitiveBodyRules = `
+rules:
+  /signup:
+    POST:
+      level: "lenient"
+      body:
+        "password":
+          type: "string"
+          min_len: 12
+        "card":
+          properties:
+            "cvv":
+              type: "string"
+              pattern: "^[0-9]{3}$"
+        "contact":
+          type: "string"
+          format: "email"
+        "bio":
+          type: "string"
+          max_len: 10
+`
+
+// The middleware returns every violation in one RFC 7807 body; sensitive values must not echo back
+func TestValidationReportErrorBody(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.LoadData([]byte(sensitiveBodyRules)); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	bio := strings.Repeat("b", 100)
+	body := `{"password":"hunter2","card":{"cvv":"12a4"},"contact":"mail jane@example.com","bio":"` + bio + `"}`
+	req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+	report := Validate(req, reg.GetRule("/signup", "POST"))
+	if len(report.Violations) != 4 {
+		t.Fatalf("expected 4 aggregated violations, got %+v", report.Violations)
+	}
+
+	rec := httptest.NewRecorder()
+	httpErr.SendError(
+		rec, req, httpErr.Global.BadRequest,
+		httpErr.WithDetail("request contents invalid"),
+		httpErr.WithInvalidParams(report.Violations),
+	)
+	if rec.Code != http.StatusBadRequest { t.Fatalf("status %d, want 400", rec.Code) }
+
+	raw := rec.Body.String()
+	for _, secret := range []string{"hunter2", "12a4", "jane@example.com", bio} {
+		if strings.Contains(raw, secret) { t.Errorf("error body leaks %q: %s", secret, raw) }
+	}
+
+	var sent struct {
+		Status int    `json:"status"`
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+		Params []struct {
+			In         string `json:"in"`
+			Name       string `json:"name"`
+			Constraint string `json:"constraint"`
+			Value      string `json:"value"`
+		} `json:"invalid-params"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &sent); err != nil { t.Fatalf("decode body: %v", err) }
+	if sent.Status != http.StatusBadRequest || sent.Code != httpErr.Global.BadRequest.ID || sent.Detail == "" {
+		t.Errorf("standard members missing alongside invalid-params: %s", raw)
+	}
+
+	values := map[string]string{}
+	for _, p := range sent.Params {
+		if p.In != LocationBody { t.Errorf("%s: location %q, want %q", p.Name, p.In, LocationBody) }
+		values[p.Name] = p.Value
+	}
+	want := map[string]string{
+		"password": "[REDACTED]",
+		"card.cvv": "[REDACTED]",
+		"contact":  "mail [REDACTED]",
+		"bio":      strings.Repeat("b", maxViolationValueLen) + "...",
+	}
+	for name, value := range want {
+		if got, ok := values[name]; !ok || got != value {
+			t.Errorf("%s: value %q, want %q", name, got, value)
+		}
+	}
+}
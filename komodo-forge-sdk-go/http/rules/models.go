@@ -1,12 +1,24 @@
 package rules
 
+import (
+	"komodo-forge-sdk-go/http/route"
+	"regexp"
+)
+
+const (
+	OriginAPI     = "api"
+	OriginBrowser = "browser"
+)
+
 const (
 	LevelIgnore = "ignore"
 	LevelLenient = "lenient"
 	LevelStrict  = "strict"
 )
 
-type Headers map[string]struct {
+type Headers map[string]HeaderSpec
+
+type HeaderSpec struct {
 	Type     string 	`yaml:"type,omitempty"`
 	Required bool   	`yaml:"required,omitempty"` // defaults to false (optional)
 	Value    string 	`yaml:"value,omitempty"`    // exact value or pattern (e.g., "Bearer *")
@@ -14,19 +26,19 @@ type Headers map[string]struct {
 	Enum     []string `yaml:"enum,omitempty"`     // list of allowed values
 	MinLen   int    	`yaml:"min_len,omitempty"`
 	MaxLen   int    	`yaml:"max_len,omitempty"`
-}
 
-type PathParams map[string]struct {
-	Type     string 	`yaml:"type,omitempty"` // "string","int","bool","object","array"
-	Required bool   	`yaml:"required,omitempty"` // defaults to false (optional)
-	Value    any    	`yaml:"value,omitempty"`
-	Enum     []string `yaml:"enum,omitempty"` // list of allowed values
-	Pattern  string 	`yaml:"pattern,omitempty"` // regex pattern
-	MinLen   int    	`yaml:"min_len,omitempty"`
-	MaxLen   int    	`yaml:"max_len,omitempty"`
+	re      *regexp.Regexp
+	enumSet map[string]struct{}
 }
 
-type QueryParams map[string]struct {
+type PathParams map[string]ParamSpec
+
+type QueryParams map[string]ParamSpec
+
+// application/x-www-form-urlencoded body fields, validated like query parameters
+type FormParams map[string]ParamSpec
+
+type ParamSpec struct {
 	Type     string 	`yaml:"type,omitempty"` // "string","int","bool","object","array"
 	Required bool   	`yaml:"required,omitempty"` // defaults to false (optional)
 	Value    any    	`yaml:"value,omitempty"`
@@ -34,14 +46,34 @@ type QueryParams map[string]struct {
 	Pattern  string 	`yaml:"pattern,omitempty"` // regex pattern
 	MinLen   int    	`yaml:"min_len,omitempty"`
 	MaxLen   int    	`yaml:"max_len,omitempty"`
+
+	re      *regexp.Regexp
+	enumSet map[string]struct{}
 }
 
-type Body map[string]struct {
-	Type     string   	`yaml:"type,omitempty"` // "string","int","bool","object","array"
-	Required bool     	`yaml:"required,omitempty"` // defaults to false (optional)
-	Value    any     		`yaml:"value,omitempty"`
-	Enum     []string 	`yaml:"enum,omitempty"` // list of allowed values
-	Pattern  string   	`yaml:"pattern,omitempty"` // regex pattern
+// Top-level body properties; each may nest further schemas
+type Body map[string]Schema
+
+// Recursive JSON body schema (a pragmatic subset of JSON Schema)
+type Schema struct {
+	Type                 string            `yaml:"type,omitempty"` // "string","int","number","bool","object","array"
+	Required             bool              `yaml:"required,omitempty"` // defaults to false (optional)
+	Value                any               `yaml:"value,omitempty"`
+	Enum                 []string          `yaml:"enum,omitempty"` // list of allowed values
+	Pattern              string            `yaml:"pattern,omitempty"` // regex pattern
+	Format               string            `yaml:"format,omitempty"` // "email","uuid","date-time","uri"
+	MinLen               int               `yaml:"min_len,omitempty"`
+	MaxLen               int               `yaml:"max_len,omitempty"`
+	Minimum              *float64          `yaml:"minimum,omitempty"`
+	Maximum              *float64          `yaml:"maximum,omitempty"`
+	MinItems             int               `yaml:"minItems,omitempty"`
+	MaxItems             int               `yaml:"maxItems,omitempty"`
+	Items                *Schema           `yaml:"items,omitempty"`
+	Properties           map[string]Schema `yaml:"properties,omitempty"`
+	AdditionalProperties *bool             `yaml:"additionalProperties,omitempty"` // false rejects unknown keys
+
+	re      *regexp.Regexp
+	enumSet map[string]struct{}
 }
 
 type EvalRule struct {
@@ -52,7 +84,71 @@ type EvalRule struct {
 	PathParams   		PathParams   	`yaml:"params,omitempty"`
 	QueryParams  		QueryParams  	`yaml:"query,omitempty"`
 	Body         		Body         	`yaml:"body,omitempty"`
+	Form         		FormParams   	`yaml:"form,omitempty"` // applies instead of body to form-encoded requests
+	AdditionalBody	*bool					`yaml:"additionalProperties,omitempty"` // false rejects unknown top-level body keys
 	RequiredVersion int						`yaml:"requiredVersion,omitempty"`
+	Scopes					[]string			`yaml:"scopes,omitempty"` // all must be granted (ctx SCOPES_KEY)
+	Admin						bool					`yaml:"admin,omitempty"` // requires ctx IS_ADMIN_KEY
+	Origins					map[string]EvalRule `yaml:"origins,omitempty"` // overrides keyed by client type (api|browser)
+	Versions				map[int]EvalRule		`yaml:"versions,omitempty"` // overrides keyed by major version; each key is also accepted
+	Response				*ResponseRule	`yaml:"response,omitempty"` // optional outbound contract
+
+	route *route.Template // compiled template the rule was loaded under (nil for static routes)
+}
+
+// Contract for what a handler sends back; body schemas apply to 2xx JSON responses only
+type ResponseRule struct {
+	Level          string  `yaml:"level,omitempty"` // defaults to the owning rule's level
+	Status         []int   `yaml:"status,omitempty"` // allowed status codes (empty allows any)
+	Headers        Headers `yaml:"headers,omitempty"`
+	Body           Body    `yaml:"body,omitempty"`
+	AdditionalBody *bool   `yaml:"additionalProperties,omitempty"`
 }
 
 type RuleConfig map[string]map[string]EvalRule
+
+// Where in the request a violation was found
+const (
+	LocationHeader = "header"
+	LocationPath   = "path"
+	LocationQuery  = "query"
+	LocationBody   = "body"
+	LocationForm   = "form"
+
+	LocationResponseStatus = "response.status"
+	LocationResponseHeader = "response.header"
+	LocationResponseBody   = "response.body"
+)
+
+// Which rule constraint a field failed
+const (
+	ConstraintRequired = "required"
+	ConstraintPattern  = "pattern"
+	ConstraintEnum     = "enum"
+	ConstraintMinLen   = "min_len"
+	ConstraintMaxLen   = "max_len"
+	ConstraintType     = "type"
+	ConstraintValue    = "value"
+	ConstraintFormat   = "format"
+	ConstraintVersion  = "version"
+	ConstraintMinimum  = "minimum"
+	ConstraintMaximum  = "maximum"
+	ConstraintMinItems = "minItems"
+	ConstraintMaxItems = "maxItems"
+	ConstraintAdditionalProperties = "additionalProperties"
+	ConstraintStatus   = "status"
+)
+
+// A single failed check, shaped as an RFC 7807 invalid-params entry
+type Violation struct {
+	Location   string `json:"in"`
+	Field      string `json:"name"`
+	Constraint string `json:"constraint"`
+	Reason     string `json:"reason"`
+	Value      string `json:"value,omitempty"` // redacted and truncated
+}
+
+// Every violation found while evaluating a request against a rule
+type ValidationReport struct {
+	Violations []Violation `json:"invalid-params"`
+}
@@ -0,0 +1,334 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI vendor extensions understood by the generator (operation > path item > document)
+const (
+	ExtLevel           = "x-komodo-level"
+	ExtRequiredVersion = "x-required-version"
+)
+
+const generatedHeader = "# Code generated by rulegen from %s. DO NOT EDIT.\n\n"
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// Generic view over a decoded OpenAPI document used while resolving $refs
+type openAPIDoc struct {
+	root map[string]any
+}
+
+// Converts an OpenAPI 3 document into a rule config.
+// Paths become route templates, parameters become headers/params/query and JSON request bodies become Body schemas.
+func FromOpenAPI(data []byte) (RuleConfig, error) {
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid openapi yaml: %w", err)
+	}
+	if v, _ := root["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q (expected 3.x)", v)
+	}
+
+	doc := &openAPIDoc{root: root}
+	paths, _ := root["paths"].(map[string]any)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("openapi document has no paths")
+	}
+
+	cfg := make(RuleConfig)
+
+	for route, rawItem := range paths {
+		item, _ := doc.resolve(rawItem).(map[string]any)
+		if item == nil { continue }
+
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok { continue }
+
+			rule, err := doc.ruleFor(item, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), route, err)
+			}
+
+			if cfg[route] == nil { cfg[route] = make(map[string]EvalRule) }
+			cfg[route][strings.ToUpper(method)] = rule
+		}
+	}
+
+	// Run the same checks the registry applies on load so generated files are always loadable
+	if err := validateAndNormalizeConfig(copyConfig(cfg)); err != nil {
+		return nil, fmt.Errorf("generated rules are invalid: %w", err)
+	}
+	return cfg, nil
+}
+
+// Serializes a rule config under the `rules:` root, prefixed with a generated-file header
+func MarshalConfig(cfg RuleConfig, source string) ([]byte, error) {
+	var buf bytes.Buffer
+	if source != "" {
+		fmt.Fprintf(&buf, generatedHeader, source)
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(struct {
+		Rules RuleConfig `yaml:"rules"`
+	}{cfg}); err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %w", err)
+	}
+	if err := enc.Close(); err != nil { return nil, err }
+
+	return buf.Bytes(), nil
+}
+
+// Builds one rule from an operation, inheriting path-level parameters and extensions
+func (d *openAPIDoc) ruleFor(item map[string]any, op map[string]any) (EvalRule, error) {
+	rule := EvalRule{Level: LevelLenient}
+
+	if lvl, ok := d.extension(ExtLevel, op, item).(string); ok {
+		rule.Level = lvl
+	}
+	if ver, ok := d.extension(ExtRequiredVersion, op, item).(int); ok {
+		rule.RequiredVersion = ver
+	}
+
+	// Operation-level parameters override path-level ones with the same name and location
+	params := map[string]map[string]any{}
+	for _, src := range []any{item["parameters"], op["parameters"]} {
+		list, _ := src.([]any)
+		for _, raw := range list {
+			p, _ := d.resolve(raw).(map[string]any)
+			if p == nil { continue }
+
+			name, _ := p["name"].(string)
+			in, _ := p["in"].(string)
+			params[in+":"+name] = p
+		}
+	}
+
+	for _, p := range params {
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		required, _ := p["required"].(bool)
+		schema, _ := d.resolve(p["schema"]).(map[string]any)
+		s := d.schemaFor(schema, 0)
+
+		switch in {
+			case "path":
+				if rule.PathParams == nil { rule.PathParams = make(PathParams) }
+				rule.PathParams[name] = ParamSpec{
+					Type: s.Type, Required: true, Enum: s.Enum, Pattern: s.Pattern, MinLen: s.MinLen, MaxLen: s.MaxLen,
+				}
+			case "query":
+				if rule.QueryParams == nil { rule.QueryParams = make(QueryParams) }
+				rule.QueryParams[name] = ParamSpec{
+					Type: s.Type, Required: required, Enum: s.Enum, Pattern: s.Pattern, MinLen: s.MinLen, MaxLen: s.MaxLen,
+				}
+			case "header":
+				if rule.Headers == nil { rule.Headers = make(Headers) }
+				rule.Headers[name] = HeaderSpec{
+					Type: s.Type, Required: required, Enum: s.Enum, Pattern: s.Pattern, MinLen: s.MinLen, MaxLen: s.MaxLen,
+				}
+		}
+	}
+
+	// Only JSON request bodies map onto Body schemas
+	if body, ok := d.resolve(op["requestBody"]).(map[string]any); ok {
+		content, _ := body["content"].(map[string]any)
+		media, _ := content["application/json"].(map[string]any)
+		schema, _ := d.resolve(media["schema"]).(map[string]any)
+
+		if schema != nil {
+			s := d.schemaFor(schema, 0)
+			if s.kind() != "object" {
+				return rule, fmt.Errorf("request body schema must be an object, got %q", s.Type)
+			}
+			rule.Body = s.Properties
+			rule.AdditionalBody = s.AdditionalProperties
+		}
+	}
+
+	return rule, nil
+}
+
+// Converts an OpenAPI schema object into a rules Schema, following $refs and allOf
+func (d *openAPIDoc) schemaFor(raw map[string]any, depth int) Schema {
+	s := Schema{}
+	if raw == nil || depth > 16 { return s }
+
+	// allOf members are merged into a single object schema
+	if all, ok := raw["allOf"].([]any); ok {
+		merged := Schema{Properties: map[string]Schema{}}
+		for _, member := range all {
+			m, _ := d.resolve(member).(map[string]any)
+			part := d.schemaFor(m, depth+1)
+			for k, v := range part.Properties { merged.Properties[k] = v }
+			if part.AdditionalProperties != nil { merged.AdditionalProperties = part.AdditionalProperties }
+		}
+		merged.Type = "object"
+		return merged
+	}
+
+	s.Type = openAPIType(raw["type"])
+	s.Pattern, _ = raw["pattern"].(string)
+	s.MinLen, _ = raw["minLength"].(int)
+	s.MaxLen, _ = raw["maxLength"].(int)
+	s.MinItems, _ = raw["minItems"].(int)
+	s.MaxItems, _ = raw["maxItems"].(int)
+	s.Minimum = toFloat(raw["minimum"])
+	s.Maximum = toFloat(raw["maximum"])
+
+	if format, _ := raw["format"].(string); knownFormats[format] {
+		s.Format = format
+	}
+	if enum, ok := raw["enum"].([]any); ok {
+		for _, e := range enum {
+			if e != nil { s.Enum = append(s.Enum, fmt.Sprint(e)) }
+		}
+	}
+	if ap, ok := raw["additionalProperties"].(bool); ok && !ap {
+		s.AdditionalProperties = &ap
+	}
+
+	if props, ok := raw["properties"].(map[string]any); ok {
+		required := map[string]bool{}
+		if list, ok := raw["required"].([]any); ok {
+			for _, r := range list {
+				if name, ok := r.(string); ok { required[name] = true }
+			}
+		}
+
+		s.Properties = make(map[string]Schema, len(props))
+		for name, rawProp := range props {
+			// Skip malformed entries (e.g. a property whose schema is a bare scalar)
+			prop, ok := d.resolve(rawProp).(map[string]any)
+			if !ok { continue }
+
+			child := d.schemaFor(prop, depth+1)
+			child.Required = required[name]
+			s.Properties[name] = child
+		}
+		if s.Type == "" { s.Type = "object" }
+	}
+
+	if items, ok := d.resolve(raw["items"]).(map[string]any); ok {
+		child := d.schemaFor(items, depth+1)
+		s.Items = &child
+		if s.Type == "" { s.Type = "array" }
+	}
+	return s
+}
+
+// Looks up a vendor extension on the operation, then the path item, then the document root
+func (d *openAPIDoc) extension(key string, scopes ...map[string]any) any {
+	for _, scope := range append(scopes, d.root) {
+		if v, ok := scope[key]; ok { return v }
+	}
+	return nil
+}
+
+// Follows local "#/..." references; anything else is returned unchanged
+func (d *openAPIDoc) resolve(v any) any {
+	for range 16 {
+		m, ok := v.(map[string]any)
+		if !ok { return v }
+
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") { return v }
+
+		var cur any = d.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			node, _ := cur.(map[string]any)
+			cur = node[part]
+		}
+		v = cur
+	}
+	return v
+}
+
+// Maps OpenAPI types onto rule types; 3.1 type arrays use the first non-null entry
+func openAPIType(v any) string {
+	var typ string
+	switch t := v.(type) {
+		case string:
+			typ = t
+		case []any:
+			for _, entry := range t {
+				if s, ok := entry.(string); ok && s != "null" { typ = s; break }
+			}
+	}
+
+	switch typ {
+		case "integer":
+			return "int"
+		case "boolean":
+			return "bool"
+		default:
+			return typ
+	}
+}
+
+func toFloat(v any) *float64 {
+	var f float64
+	switch n := v.(type) {
+		case int:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			return nil
+	}
+	return &f
+}
+
+// Shallow copy so load-time normalization doesn't inject empty sections into generated output
+func copyConfig(cfg RuleConfig) RuleConfig {
+	out := make(RuleConfig, len(cfg))
+	for route, methods := range cfg {
+		out[route] = make(map[string]EvalRule, len(methods))
+		for m, r := range methods { out[route][m] = r }
+	}
+	return out
+}
+
+// Produces a minimal line diff between two rendered files (used by rulegen -check)
+func DiffLines(want []byte, got []byte) string {
+	a := strings.Split(string(want), "\n")
+	b := strings.Split(string(got), "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs { lcs[i] = make([]int, len(b)+1) }
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				i++; j++
+			case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+				fmt.Fprintf(&out, "%4d - %s\n", i+1, a[i])
+				i++
+			default:
+				fmt.Fprintf(&out, "%4d + %s\n", j+1, b[j])
+				j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/validation_rules.yml is the expected output for testdata/openapi.yaml; regenerating it must be a no-op
+func TestFromOpenAPIMatchesExpectedRules(t *testing.T) {
+	spec, err := os.ReadFile("testdata/openapi.yaml")
+	if err != nil { t.Fatalf("read spec: %v", err) }
+	expected, err := os.ReadFile("testdata/validation_rules.yml")
+	if err != nil { t.Fatalf("read expected rules: %v", err) }
+
+	cfg, err := FromOpenAPI(spec)
+	if err != nil { t.Fatalf("generate: %v", err) }
+	generated, err := MarshalConfig(cfg, "openapi.yaml")
+	if err != nil { t.Fatalf("marshal: %v", err) }
+
+	if string(generated) != string(expected) {
+		t.Fatalf("generated rules differ from the expected file (- expected, + generated):\n%s", DiffLines(expected, generated))
+	}
+
+	// The generated file loads like any hand-written one
+	reg := NewRegistry()
+	if err := reg.LoadData(generated); err != nil { t.Fatalf("load generated rules: %v", err) }
+	if rule := reg.GetRule("/v1/orders/ord_1", "GET"); rule == nil || rule.Level != LevelStrict {
+		t.Errorf("/orders/{orderId} GET rule = %+v, want a strict rule", rule)
+	}
+	if rule := reg.GetRule("/v1/orders", "POST"); rule == nil || rule.Level != LevelLenient || rule.Body["lines"].Items == nil {
+		t.Errorf("/orders POST rule = %+v, want a lenient rule with the resolved line schema", rule)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	want := []byte("rules:\n  /a:\n    level: strict\n  /b:\n")
+	got := []byte("rules:\n  /a:\n    level: lenient\n  /b:\n  /c:\n")
+
+	diff := DiffLines(want, got)
+	for _, line := range []string{"   3 -     level: strict", "   3 +     level: lenient", "   5 +   /c:"} {
+		if !strings.Contains(diff, line) { t.Errorf("diff missing %q:\n%s", line, diff) }
+	}
+	if strings.Contains(diff, "/a:") || strings.Contains(diff, "/b:") {
+		t.Errorf("diff includes unchanged lines:\n%s", diff)
+	}
+	if DiffLines(want, want) != "" { t.Error("identical input produced a diff") }
+}
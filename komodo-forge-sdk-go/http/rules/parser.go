@@ -1,118 +1,144 @@
 package rules
 
 import (
+	"context"
 	"fmt"
+	awsS3 "komodo-forge-sdk-go/aws/s3"
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/http/route"
 	logger "komodo-forge-sdk-go/logging/runtime"
-	"os"
-	"regexp"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	ruleMap       map[string]map[string]EvalRule
-	patternRoutes []routePattern // patternRoutes is a list of compiled route patterns for templates (/:id or /{id})
-	loadOnce      sync.Once
-	configLoaded  bool
+	defaultRegistry = NewRegistry()
+	watchOnce       sync.Once
 )
 
-type routePattern struct {
-	template  string
-	re        *regexp.Regexp
-	methods   map[string]EvalRule
-	paramKeys []string
-}
+// Returns the process-wide registry backing the package-level functions
+func Default() *Registry { return defaultRegistry }
 
-// Loads validation rules from a file path or embedded data
+// Loads validation rules into the default registry from a file path, S3 or EVAL_RULES_PATH.
+// An explicit path always (re)loads; otherwise an already loaded registry is left as is.
 func LoadConfig(path ...string) bool {
-	loadOnce.Do(func() { 
-		var data []byte
-		var err error
-		var source string
-
-		// Try explicit path or env var
-		if len(path) > 0 && path[0] != "" {
-			data, err = os.ReadFile(path[0])
-			source = path[0]
-		} else if envPath := os.Getenv("EVAL_RULES_PATH"); envPath != "" {
-			data, err = os.ReadFile(envPath)
-			source = envPath
-		}
+	if len(path) > 0 && path[0] != "" {
+		return defaultRegistry.Load(path[0]) == nil
+	}
+	if defaultRegistry.IsLoaded() { return true }
 
-		if err != nil || data == nil {
-			logger.Error("failed to load validation rules from" + source, err)
-			configLoaded = false
-			return
-		}
+	var err error
+	bucket, key := config.GetConfigValue("EVAL_RULES_S3_BUCKET"), config.GetConfigValue("EVAL_RULES_S3_KEY")
 
-		rt, patterns, parseErr := parseConfigFromData(data)
-		if parseErr != nil {
-			logger.Error("failed to parse validation rules", parseErr)
-			configLoaded = false
-			return
-		}
-
-		ruleMap = rt
-		patternRoutes = patterns
-		configLoaded = true
+	switch envPath := config.GetConfigValue("EVAL_RULES_PATH"); {
+		case bucket != "" && key != "" && awsS3.IsInitialized():
+			err = defaultRegistry.LoadS3(context.Background(), bucket, key)
+		case envPath != "":
+			err = defaultRegistry.Load(envPath)
+		default:
+			err = fmt.Errorf("no validation rules source configured")
+			logger.Error("failed to load validation rules", err)
+	}
+	if err != nil { return false }
 
-		logger.Info("successfully loaded validation rules from: " + source)
-	})
-	return configLoaded
+	startDefaultWatcher(bucket, key)
+	return true
 }
 
 // Loads validation rules from byte data (for embedding in client services)
 func LoadConfigWithData(data []byte) {
-	loadOnce.Do(func() {
-		rt, patterns, err := parseConfigFromData(data)
-		if err != nil {
-			logger.Error("failed to parse validation rules from embedded config", err)
-			configLoaded = false
-			return
-		}
+	defaultRegistry.LoadData(data)
+}
+
+func IsConfigLoaded() bool { return defaultRegistry.IsLoaded() }
+
+func GetRule(pKey string, method string) *EvalRule { return defaultRegistry.GetRule(pKey, method) }
+
+func GetRules() RuleConfig { return defaultRegistry.GetRules() }
 
-		ruleMap = rt
-		patternRoutes = patterns
-		configLoaded = true
+// Starts polling the default registry source when EVAL_RULES_RELOAD_SEC is set
+func startDefaultWatcher(bucket string, key string) {
+	secs, err := strconv.Atoi(config.GetConfigValue("EVAL_RULES_RELOAD_SEC"))
+	if err != nil || secs <= 0 { return }
 
-		logger.Info("successfully loaded validation rules from embedded config")
+	watchOnce.Do(func() {
+		interval := time.Duration(secs) * time.Second
+		if bucket != "" && key != "" && awsS3.IsInitialized() {
+			defaultRegistry.WatchS3(bucket, key, interval)
+			return
+		}
+		if path := config.GetConfigValue("EVAL_RULES_PATH"); path != "" {
+			defaultRegistry.Watch(path, interval)
+		}
 	})
 }
-	
 
-func IsConfigLoaded() bool { return configLoaded && ruleMap != nil }
+var validMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
 
-func GetRule(pKey string, method string) *EvalRule {
-	if pKey == "" || method == "" || ruleMap == nil {
-		return nil
+// Rejects configs that would fail at request time and fills in empty rule sections
+func validateAndNormalizeConfig(cfg RuleConfig) error {
+	if len(cfg) == 0 {
+		return fmt.Errorf("no rules defined")
 	}
 
-	np := normalizePath(pKey)
-
-	// Direct match
-	if rules, ok := ruleMap[np]; ok {
-		if rule, exists := rules[method]; exists {
-			return &rule
+	for route, methods := range cfg {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("route %q must start with /", route)
 		}
-	}
+		for method, rule := range methods {
+			if !validMethods[method] {
+				return fmt.Errorf("route %s: unsupported method %q", route, method)
+			}
 
-	for _, rp := range patternRoutes {
-		if rp.re.MatchString(np) {
-			if rule, exists := rp.methods[method]; exists {
-				return &rule
+			if err := compileRule(&rule); err != nil {
+				return fmt.Errorf("%s %s: %w", method, route, err)
+			}
+			if err := validateRule(rule); err != nil {
+				return fmt.Errorf("%s %s: %w", method, route, err)
 			}
-		}
-	}
-	return nil
-}
 
-func GetRules() RuleConfig { return ruleMap }
+			for origin, override := range rule.Origins {
+				if origin != OriginAPI && origin != OriginBrowser {
+					return fmt.Errorf("%s %s: unknown origin %q", method, route, origin)
+				}
+				if len(override.Origins) > 0 {
+					return fmt.Errorf("%s %s: origin %q overrides cannot be nested", method, route, origin)
+				}
+				if err := compileRule(&override); err != nil {
+					return fmt.Errorf("%s %s (origin %s): %w", method, route, origin, err)
+				}
+				rule.Origins[origin] = override
+
+				if err := validateRule(*rule.merge(override)); err != nil {
+					return fmt.Errorf("%s %s (origin %s): %w", method, route, origin, err)
+				}
+			}
+
+			for major, override := range rule.Versions {
+				if major < 1 {
+					return fmt.Errorf("%s %s: version override %d must be >= 1", method, route, major)
+				}
+				if len(override.Origins) > 0 || len(override.Versions) > 0 {
+					return fmt.Errorf("%s %s: version %d overrides cannot be nested", method, route, major)
+				}
+				if err := compileRule(&override); err != nil {
+					return fmt.Errorf("%s %s (v%d): %w", method, route, major, err)
+				}
+				rule.Versions[major] = override
+
+				if err := validateRule(*rule.merge(override)); err != nil {
+					return fmt.Errorf("%s %s (v%d): %w", method, route, major, err)
+				}
+			}
 
-func validateAndNormalizeConfig(cfg RuleConfig) error {
-	for _, methods := range cfg {
-		for method, rule := range methods {
 			if rule.Headers == nil {
 				rule.Headers = make(Headers)
 			}
@@ -125,6 +151,9 @@ func validateAndNormalizeConfig(cfg RuleConfig) error {
 			if rule.Body == nil {
 				rule.Body = make(Body)
 			}
+			if rule.Form == nil {
+				rule.Form = make(FormParams)
+			}
 
 			methods[method] = rule
 		}
@@ -132,8 +161,36 @@ func validateAndNormalizeConfig(cfg RuleConfig) error {
 	return nil
 }
 
-// Parses YAML data and returns the rule map plus compiled templates.
-func parseConfigFromData(data []byte) (map[string]map[string]EvalRule, []routePattern, error) {
+// Checks a single rule's level and origin types
+func validateRule(rule EvalRule) error {
+	switch rule.Level {
+		case "", LevelIgnore, LevelLenient:
+		case LevelStrict:
+			if rule.RequiredVersion <= 0 {
+				return fmt.Errorf("requiredVersion must be >= 1 for strict validation")
+			}
+		default:
+			return fmt.Errorf("unknown level %q", rule.Level)
+	}
+
+	for _, origin := range rule.OriginTypes {
+		if origin != OriginAPI && origin != OriginBrowser {
+			return fmt.Errorf("unknown origin type %q", origin)
+		}
+	}
+
+	if res := rule.Response; res != nil {
+		switch res.Level {
+			case "", LevelIgnore, LevelLenient, LevelStrict:
+			default:
+				return fmt.Errorf("response: unknown level %q", res.Level)
+		}
+	}
+	return nil
+}
+
+// Parses YAML data, validates and compiles every rule, and builds the route tree.
+func parseConfigFromData(data []byte) (RuleConfig, *routeNode, error) {
 	var root struct {
 		Rules RuleConfig `yaml:"rules"`
 	}
@@ -144,152 +201,42 @@ func parseConfigFromData(data []byte) (map[string]map[string]EvalRule, []routePa
 	}
 
 	cfg := root.Rules
-	
-	// Validate and normalize the configuration
+
+	// Validate, normalize and precompile the configuration
 	if err := validateAndNormalizeConfig(cfg); err != nil {
 		logger.Error("validation rules configuration is invalid", err)
 		return nil, nil, err
 	}
-	
-	patterns := make([]routePattern, 0)
 
-	// Build patterns for templates with dynamic segments
-	for tpl, methods := range cfg {
-		if strings.Contains(tpl, ":") || strings.Contains(tpl, "{") || strings.Contains(tpl, "*") {
-			reStr, keys := templateToRegex(tpl)
-			re, err := regexp.Compile("^" + reStr + "$")
-			if err != nil {
-				logger.Error("invalid route pattern " + tpl, err)
-				return nil, nil, fmt.Errorf("invalid route pattern %s: %w", tpl, err)
-			}
+	tree := &routeNode{}
 
-			patterns = append(patterns, routePattern{
-				template:  tpl,
-				re:        re,
-				methods:   methods,
-				paramKeys: keys,
-			})
-		}
-	}
+	for tpl, methods := range cfg {
+		rt := route.Parse(tpl)
+		compiled := make(map[string]*EvalRule, len(methods))
 
-	// Sort patterns by specificity
-	specificityScore := func(tpl string) int {
-		parts := strings.Split(strings.TrimPrefix(tpl, "/"), "/")
-		literal, wild := 0, 0
+		// Bind templated rules to their route so params resolve against the owning registry
+		for method, rule := range methods {
+			if rt.Dynamic { rule.route = rt }
+			methods[method] = rule
 
-		for _, p := range parts {
-			if p == "*" || strings.HasPrefix(p, ":") || (strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")) {
-				wild++
-			} else if p != "" {
-				literal++
-			}
+			r := rule
+			compiled[method] = &r
 		}
-		return literal * 10 - wild
-	} // end
 
-	for i := 0; i < len(patterns); i++ {
-		for j := i + 1; j < len(patterns); j++ {
-			if specificityScore(patterns[j].template) > specificityScore(patterns[i].template) {
-				patterns[i], patterns[j] = patterns[j], patterns[i]
-			}
+		if err := tree.insert(rt, compiled); err != nil {
+			logger.Error("invalid route template "+tpl, err)
+			return nil, nil, err
 		}
 	}
 
-	return cfg, patterns, nil
+	return cfg, tree, nil
 }
 
-// Converts a route template into a regex string
-func templateToRegex(tpl string) (string, []string) {
-	// ensure we only operate on path part (strip query if present)
-	if idx := strings.Index(tpl, "?"); idx != -1 {
-		tpl = tpl[:idx]
-	}
-	// trim trailing slash handling
-	tpl = strings.TrimSuffix(tpl, "/")
-
-	parts := strings.Split(strings.TrimPrefix(tpl, "/"), "/")
-	regexParts := make([]string, 0, len(parts))
-	keys := make([]string, 0)
+// Extracts path params using the template the rule was loaded under
+func matchRouteAndExtractParams(path string, rule *EvalRule) (*route.Template, map[string]string) {
+	if rule == nil || rule.route == nil { return nil, nil }
 
-	for _, p := range parts {
-		if p == "*" {
-			regexParts = append(regexParts, ".*")
-			continue
-		}
-		// :param or {param}
-		if strings.HasPrefix(p, ":") {
-			key := strings.TrimPrefix(p, ":")
-			keys = append(keys, key)
-			regexParts = append(regexParts, `(?P<`+key+`>[^/]+)`)
-			continue
-		}
-		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
-			key := strings.TrimSuffix(strings.TrimPrefix(p, "{"), "}")
-			keys = append(keys, key)
-			regexParts = append(regexParts, `(?P<`+key+`>[^/]+)`)
-			continue
-		}
-		// literal segment - escape regexp metacharacters
-		regexParts = append(regexParts, regexp.QuoteMeta(p))
-	}
-	return "/" + strings.Join(regexParts, "/"), keys
-}
-
-// Strips version prefixes and ensures leading slash
-func normalizePath(p string) string {
-	if p == "" {
-		return p
-	}
-	// drop query
-	if idx := strings.Index(p, "?"); idx != -1 {
-		p = p[:idx]
-	}
-	p = strings.TrimSpace(p)
-	if p == "" {
-		return "/"
-	}
-
-	// remove trailing slash (but keep root)
-	if len(p) > 1 && strings.HasSuffix(p, "/") {
-		p = strings.TrimSuffix(p, "/")
-	}
-
-	// strip version prefix like /v1 or /v1.2
-	trimmed := strings.TrimPrefix(p, "/")
-	segs := strings.Split(trimmed, "/")
-
-	if len(segs) > 0 && len(segs[0]) > 0 && segs[0][0] == 'v' {
-		// basic check: next characters are digits or digit+dot
-		if regexp.MustCompile(`^v[0-9]`).MatchString(segs[0]) {
-			// remove first segment
-			segs = segs[1:]
-			p = "/" + strings.Join(segs, "/")
-		}
-	}
-	if !strings.HasPrefix(p, "/") {
-		p = "/" + p
-	}
-	return p
-}
-
-// Finds the first pattern that matches and extracts params
-func matchRouteAndExtractParams(path string) (*routePattern, map[string]string) {
-	np := normalizePath(path)
-
-	for _, rp := range patternRoutes {
-		if !rp.re.MatchString(np) {
-			continue
-		}
-		matches := rp.re.FindStringSubmatch(np)
-		names := rp.re.SubexpNames()
-		params := make(map[string]string)
-
-		for i, name := range names {
-			if i != 0 && name != "" && i < len(matches) {
-				params[name] = matches[i]
-			}
-		}
-		return &rp, params
-	}
-	return nil, nil
+	params, ok := rule.route.Extract(route.Normalize(path))
+	if !ok { return nil, nil }
+	return rule.route, params
 }
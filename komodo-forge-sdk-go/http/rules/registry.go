@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	awsS3 "komodo-forge-sdk-go/aws/s3"
+	"komodo-forge-sdk-go/http/route"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Immutable snapshot of a parsed rules file; swapped as a whole on reload
+type ruleSet struct {
+	rules    RuleConfig
+	tree     *routeNode
+	source   string
+	checksum [sha256.Size]byte
+	loadedAt time.Time
+}
+
+// Holds the active rule set and swaps it atomically when a new source validates.
+// Readers never block; a failed reload keeps serving the previous rules.
+type Registry struct {
+	current atomic.Pointer[ruleSet]
+	mu      sync.Mutex // serializes loads so checksums compare against the latest swap
+}
+
+func NewRegistry() *Registry { return &Registry{} }
+
+// Reads and swaps in rules from a file on disk
+func (r *Registry) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("failed to read validation rules from "+path, err)
+		return fmt.Errorf("failed to read validation rules: %w", err)
+	}
+	return r.swap(data, path)
+}
+
+// Swaps in rules from raw YAML (for embedding in client services)
+func (r *Registry) LoadData(data []byte) error {
+	return r.swap(data, "embedded")
+}
+
+// Fetches and swaps in rules from an S3 object
+func (r *Registry) LoadS3(ctx context.Context, bucket string, key string) error {
+	data, err := awsS3.GetObject(ctx, bucket, key)
+	if err != nil {
+		logger.Error(fmt.Sprintf("failed to fetch validation rules from s3://%s/%s", bucket, key), err)
+		return fmt.Errorf("failed to fetch validation rules: %w", err)
+	}
+	return r.swap(data, fmt.Sprintf("s3://%s/%s", bucket, key))
+}
+
+// Polls a file for changes and reloads it. Returns a func that stops the watcher.
+func (r *Registry) Watch(path string, interval time.Duration) (stop func()) {
+	var lastMod time.Time
+	var lastSize int64
+
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	return r.poll(interval, func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Error("failed to stat validation rules "+path, err)
+			return
+		}
+		if info.ModTime().Equal(lastMod) && info.Size() == lastSize { return }
+
+		lastMod, lastSize = info.ModTime(), info.Size()
+		r.Load(path)
+	})
+}
+
+// Polls an S3 object and reloads it when its contents change
+func (r *Registry) WatchS3(bucket string, key string, interval time.Duration) (stop func()) {
+	return r.poll(interval, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		r.LoadS3(ctx, bucket, key)
+	})
+}
+
+func (r *Registry) IsLoaded() bool { return r.current.Load() != nil }
+
+// Returns where the active rules were loaded from and when
+func (r *Registry) Source() (string, time.Time) {
+	set := r.current.Load()
+	if set == nil { return "", time.Time{} }
+	return set.source, set.loadedAt
+}
+
+// Returns the rule for a request path and method. The rule is shared with the active set and must not be mutated.
+func (r *Registry) GetRule(pKey string, method string) *EvalRule {
+	set := r.current.Load()
+	if pKey == "" || method == "" || set == nil {
+		return nil
+	}
+	return set.tree.lookup(route.Normalize(pKey), method)
+}
+
+func (r *Registry) GetRules() RuleConfig {
+	set := r.current.Load()
+	if set == nil { return nil }
+	return set.rules
+}
+
+// Parses and validates data, then atomically replaces the active set.
+// Unchanged content is a no-op; invalid content leaves the active set untouched.
+func (r *Registry) swap(data []byte, source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	if prev := r.current.Load(); prev != nil && prev.checksum == sum {
+		return nil
+	}
+
+	rt, tree, err := parseConfigFromData(data)
+	if err != nil {
+		logger.Error("rejected validation rules from "+source+", keeping previous rules", err)
+		return err
+	}
+
+	r.current.Store(&ruleSet{
+		rules:    rt,
+		tree:     tree,
+		source:   source,
+		checksum: sum,
+		loadedAt: time.Now(),
+	})
+
+	logger.Info("successfully loaded validation rules from: " + source)
+	return nil
+}
+
+func (r *Registry) poll(interval time.Duration, tick func()) func() {
+	if interval <= 0 { interval = 30 * time.Second }
+
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+				case <-done:
+					return
+				case <-ticker.C:
+					tick()
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,32 @@
+package rules
+
+import "testing"
+
+const registryTestRules = `
+rules:
+  /item/{sku}:
+    GET:
+      level: "lenient"
+      params:
+        sku:
+          type: "string"
+          required: true
+      requiredVersion: 1
+`
+
+func TestRegistryKeepsPreviousRulesOnInvalidReload(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.LoadData([]byte(registryTestRules)); err != nil {
+		t.Fatalf("initial load failed: %v", err)
+	}
+	if reg.GetRule("/v1/item/abc", "GET") == nil {
+		t.Fatal("expected templated rule to match")
+	}
+
+	if err := reg.LoadData([]byte("rules:\n  /item:\n    GET:\n      level: \"paranoid\"\n")); err == nil {
+		t.Fatal("expected unknown level to be rejected")
+	}
+	if reg.GetRule("/item/abc", "GET") == nil {
+		t.Fatal("expected previous rules to remain active after rejected reload")
+	}
+}
@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Returns the level the response contract is enforced at, falling back to the rule's level
+func (r *EvalRule) ResponseLevel() string {
+	if r == nil || r.Response == nil { return LevelIgnore }
+	if r.Response.Level != "" { return r.Response.Level }
+	if r.Level == "" { return LevelLenient }
+	return r.Level
+}
+
+// Checks a buffered handler response against the rule's response contract
+func ValidateResponse(status int, header http.Header, body []byte, rule *EvalRule) *ValidationReport {
+	report := &ValidationReport{}
+	if rule == nil || rule.Response == nil { return report }
+
+	contract := rule.Response
+
+	if len(contract.Status) > 0 && !slices.Contains(contract.Status, status) {
+		report.add(newViolation(
+			LocationResponseStatus, "status", ConstraintStatus, status,
+			fmt.Sprintf("must be one of %v", contract.Status),
+		))
+	}
+
+	for name, spec := range contract.Headers {
+		val := header.Get(name)
+		if val == "" {
+			if spec.Required {
+				report.add(newViolation(LocationResponseHeader, name, ConstraintRequired, nil, "header is required"))
+			}
+			continue
+		}
+		if spec.Value != "" {
+			if prefix, ok := strings.CutSuffix(spec.Value, "*"); (ok && !strings.HasPrefix(val, prefix)) || (!ok && val != spec.Value) {
+				report.add(newViolation(LocationResponseHeader, name, ConstraintValue, val, fmt.Sprintf("must match %q", spec.Value)))
+				continue
+			}
+		}
+		checkString(report, LocationResponseHeader, name, val, spec.check())
+	}
+
+	// Error bodies share the ErrorResponse shape and aren't part of the route contract
+	if status < 200 || status >= 300 || len(contract.Body) == 0 { return report }
+
+	if !strings.HasPrefix(header.Get("Content-Type"), "application/json") {
+		report.add(newViolation(LocationResponseHeader, "Content-Type", ConstraintValue, header.Get("Content-Type"), "must be application/json"))
+		return report
+	}
+
+	var bodyMap map[string]any
+	if err := json.Unmarshal(body, &bodyMap); err != nil {
+		report.add(newViolation(LocationResponseBody, "", ConstraintType, nil, "response body must be a JSON object"))
+		return report
+	}
+
+	validateObject(report, LocationResponseBody, "", bodyMap, contract.Body, contract.AdditionalBody)
+	return report
+}
@@ -0,0 +1,76 @@
+package rules
+
+import (
+	"fmt"
+	"komodo-forge-sdk-go/http/route"
+	"strings"
+)
+
+// Segment-level radix tree. Static children win over params, params over wildcards,
+// with backtracking so a method missing on a static node can still match a templated route.
+type routeNode struct {
+	static   map[string]*routeNode
+	param    *routeNode
+	wildcard *routeNode
+	rules    map[string]*EvalRule // by method, only on terminal nodes
+}
+
+// Adds a template's method rules to the tree, rejecting duplicate method definitions
+func (n *routeNode) insert(rt *route.Template, rules map[string]*EvalRule) error {
+	node := n
+	for _, seg := range rt.Segments {
+		switch seg.Kind {
+			case route.Static:
+				if node.static == nil { node.static = make(map[string]*routeNode) }
+				child, ok := node.static[seg.Value]
+				if !ok {
+					child = &routeNode{}
+					node.static[seg.Value] = child
+				}
+				node = child
+			case route.Param:
+				if node.param == nil { node.param = &routeNode{} }
+				node = node.param
+			case route.Wildcard:
+				if node.wildcard == nil { node.wildcard = &routeNode{} }
+				node = node.wildcard
+		}
+	}
+
+	if node.rules == nil { node.rules = make(map[string]*EvalRule, len(rules)) }
+	for method, rule := range rules {
+		if _, exists := node.rules[method]; exists {
+			return fmt.Errorf("route %s: %s conflicts with another template", rt.Raw, method)
+		}
+		node.rules[method] = rule
+	}
+	return nil
+}
+
+// Finds the rule for a normalized path and method without allocating
+func (n *routeNode) lookup(np string, method string) *EvalRule {
+	if np == "/" { np = "" } else { np = strings.TrimPrefix(np, "/") }
+	return n.match(np, method)
+}
+
+func (n *routeNode) match(rest string, method string) *EvalRule {
+	if rest == "" {
+		return n.rules[method]
+	}
+
+	part, tail, hasTail := strings.Cut(rest, "/")
+	if hasTail && tail == "" { return nil }
+
+	if child := n.static[part]; child != nil {
+		if rule := child.match(tail, method); rule != nil { return rule }
+	}
+	if n.param != nil && part != "" {
+		if rule := n.param.match(tail, method); rule != nil { return rule }
+	}
+	if w := n.wildcard; w != nil && part != "" {
+		// A wildcard matches one segment mid-template, or the remainder of the path when last
+		if rule := w.match(tail, method); rule != nil { return rule }
+		if rule := w.rules[method]; rule != nil { return rule }
+	}
+	return nil
+}
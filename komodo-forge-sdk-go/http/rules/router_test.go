@@ -0,0 +1,40 @@
+package rules
+
+import (
+	"komodo-forge-sdk-go/http/route"
+	"testing"
+)
+
+func TestRouteTreeLookup(t *testing.T) {
+	tree := &routeNode{}
+	for tpl, rule := range map[string]*EvalRule{
+		"/item/suggestion": {Level: "static"},
+		"/item/{sku}":      {Level: "param"},
+		"/files/*":         {Level: "wildcard"},
+		"/users/:id/posts": {Level: "colon"},
+	} {
+		if err := tree.insert(route.Parse(tpl), map[string]*EvalRule{"GET": rule}); err != nil {
+			t.Fatalf("insert %s: %v", tpl, err)
+		}
+	}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/v1/item/suggestion", "static"},
+		{"/v2/item/ABC-123/", "param"},
+		{"/files/a/b/c.txt?x=1", "wildcard"},
+		{"/users/42/posts", "colon"},
+		{"/item", ""},
+		{"/item/a/b", ""},
+		{"/users//posts", ""},
+	}
+	for _, tc := range cases {
+		got := ""
+		if rule := tree.lookup(route.Normalize(tc.path), "GET"); rule != nil { got = rule.Level }
+		if got != tc.want {
+			t.Errorf("lookup(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
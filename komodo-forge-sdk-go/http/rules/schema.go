@@ -0,0 +1,187 @@
+package rules
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var knownFormats = map[string]bool{"email": true, "uuid": true, "date-time": true, "uri": true}
+
+// Resolves the effective type, inferring object/array from properties/items
+func (s *Schema) kind() string {
+	switch {
+		case s.Type != "":
+			return s.Type
+		case s.Properties != nil:
+			return "object"
+		case s.Items != nil:
+			return "array"
+		default:
+			return "string"
+	}
+}
+
+// Validates each declared property of a decoded JSON object, then rejects unknown keys if disallowed
+func validateObject(report *ValidationReport, location string, path string, obj map[string]any, props map[string]Schema, additional *bool) {
+	for name, spec := range props {
+		v, ok := obj[name]
+		if !ok || v == nil {
+			if spec.Required {
+				report.add(newViolation(location, joinField(path, name), ConstraintRequired, nil, "field is required"))
+			}
+			continue
+		}
+		validateSchema(report, location, joinField(path, name), v, &spec)
+	}
+
+	if additional == nil || *additional { return }
+
+	unknown := make([]string, 0)
+	for name := range obj {
+		if _, ok := props[name]; !ok { unknown = append(unknown, name) }
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		report.add(newViolation(
+			location, joinField(path, name), ConstraintAdditionalProperties, nil, "field is not allowed",
+		))
+	}
+}
+
+// Validates a single JSON value against its schema, recursing into objects and arrays
+func validateSchema(report *ValidationReport, location string, field string, v any, spec *Schema) {
+	switch spec.kind() {
+		case "string":
+			str, ok := v.(string)
+			if !ok {
+				report.add(newViolation(location, field, ConstraintType, v, "must be a string"))
+				return
+			}
+			before := len(report.Violations)
+			checkString(report, location, field, str, spec.check())
+			if len(report.Violations) > before || spec.Format == "" { return }
+
+			if !isValidFormat(spec.Format, str) {
+				report.add(newViolation(location, field, ConstraintFormat, str, "must be a valid " + spec.Format))
+			}
+
+		case "int", "integer", "number":
+			// JSON numbers are float64 by default
+			num, ok := v.(float64)
+			if !ok {
+				report.add(newViolation(location, field, ConstraintType, v, "must be a number"))
+				return
+			}
+			if spec.kind() != "number" && num != float64(int64(num)) {
+				report.add(newViolation(location, field, ConstraintType, v, "must be an integer"))
+				return
+			}
+			if spec.Minimum != nil && num < *spec.Minimum {
+				report.add(newViolation(location, field, ConstraintMinimum, v, fmt.Sprintf("must be >= %v", *spec.Minimum)))
+				return
+			}
+			if spec.Maximum != nil && num > *spec.Maximum {
+				report.add(newViolation(location, field, ConstraintMaximum, v, fmt.Sprintf("must be <= %v", *spec.Maximum)))
+			}
+
+		case "bool", "boolean":
+			if _, ok := v.(bool); !ok {
+				report.add(newViolation(location, field, ConstraintType, v, "must be a boolean"))
+			}
+
+		case "object":
+			obj, ok := v.(map[string]any)
+			if !ok {
+				report.add(newViolation(location, field, ConstraintType, nil, "must be an object"))
+				return
+			}
+			validateObject(report, location, field, obj, spec.Properties, spec.AdditionalProperties)
+
+		case "array":
+			arr, ok := v.([]any)
+			if !ok {
+				report.add(newViolation(location, field, ConstraintType, nil, "must be an array"))
+				return
+			}
+			if spec.MinItems > 0 && len(arr) < spec.MinItems {
+				report.add(newViolation(location, field, ConstraintMinItems, nil, fmt.Sprintf("must have at least %d items", spec.MinItems)))
+				return
+			}
+			if spec.MaxItems > 0 && len(arr) > spec.MaxItems {
+				report.add(newViolation(location, field, ConstraintMaxItems, nil, fmt.Sprintf("must have at most %d items", spec.MaxItems)))
+				return
+			}
+			if spec.Items == nil { return }
+
+			for i, item := range arr {
+				itemField := fmt.Sprintf("%s[%d]", field, i)
+				if item == nil {
+					report.add(newViolation(location, itemField, ConstraintType, nil, "must not be null"))
+					continue
+				}
+				validateSchema(report, location, itemField, item, spec.Items)
+			}
+
+		default:
+			// unknown types are treated as pass-through for now
+	}
+}
+
+// Checks a string against one of the supported named formats
+func isValidFormat(format string, val string) bool {
+	switch format {
+		case "email":
+			addr, err := mail.ParseAddress(val)
+			return err == nil && addr.Address == val
+		case "uuid":
+			return uuid.Validate(val) == nil
+		case "date-time":
+			_, err := time.Parse(time.RFC3339, val)
+			return err == nil
+		case "uri":
+			u, err := url.ParseRequestURI(val)
+			return err == nil && u.Scheme != "" && u.Host != ""
+		default:
+			return true
+	}
+}
+
+// Checks every format and range in a schema tree and compiles its patterns and enums in place
+func validateSchemaConfig(field string, spec *Schema) error {
+	re, set, err := compileMatchers(spec.Pattern, spec.Enum)
+	if err != nil {
+		return fmt.Errorf("invalid pattern for body %s: %w", field, err)
+	}
+	spec.re, spec.enumSet = re, set
+
+	if spec.Format != "" && !knownFormats[spec.Format] {
+		return fmt.Errorf("unknown format %q for body %s", spec.Format, field)
+	}
+	if spec.Minimum != nil && spec.Maximum != nil && *spec.Minimum > *spec.Maximum {
+		return fmt.Errorf("minimum exceeds maximum for body %s", field)
+	}
+	if spec.MaxItems > 0 && spec.MinItems > spec.MaxItems {
+		return fmt.Errorf("minItems exceeds maxItems for body %s", field)
+	}
+
+	for name, prop := range spec.Properties {
+		if err := validateSchemaConfig(joinField(field, name), &prop); err != nil { return err }
+		spec.Properties[name] = prop
+	}
+	if spec.Items != nil {
+		return validateSchemaConfig(field+"[]", spec.Items)
+	}
+	return nil
+}
+
+// Builds dotted field paths for nested properties (e.g. "address.zip")
+func joinField(path string, name string) string {
+	if path == "" { return name }
+	return path + "." + name
+}
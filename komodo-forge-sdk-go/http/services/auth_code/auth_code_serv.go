@@ -0,0 +1,205 @@
+package authCode
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_CODE_TTL_SEC    = 60  // authorization codes are exchanged immediately by the client
+	DEFAULT_REQUEST_TTL_SEC = 600 // time the user has to log in
+)
+
+const MethodS256 = "S256"
+
+var (
+	ErrInvalidCode      = errors.New("invalid or expired authorization code")
+	ErrInvalidRequest   = errors.New("invalid or expired authorization request")
+	ErrClientMismatch   = errors.New("authorization code was issued to a different client")
+	ErrRedirectMismatch = errors.New("redirect uri does not match the authorization request")
+	ErrPKCEFailed       = errors.New("code verifier does not match the code challenge")
+)
+
+// RFC 7636 Section 4.1: 43-128 unreserved characters
+var verifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// An authorization request waiting for the user to log in
+type Request struct {
+	ClientID      string    `json:"clientId"`
+	RedirectURI   string    `json:"redirectUri"`
+	Scopes        []string  `json:"scopes,omitempty"`
+	State         string    `json:"state,omitempty"`
+	CodeChallenge string    `json:"codeChallenge"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// What an authorization code stands for once the user has logged in
+type Grant struct {
+	ClientID      string    `json:"clientId"`
+	RedirectURI   string    `json:"redirectUri"`
+	Subject       string    `json:"subject"`
+	Scopes        []string  `json:"scopes,omitempty"`
+	CodeChallenge string    `json:"codeChallenge"`
+	IssuedAt      time.Time `json:"issuedAt"`
+}
+
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// Returns and deletes the value in one step so a key can be taken at most once; nil when absent
+	Take(ctx context.Context, key string) ([]byte, error)
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// The login redirect may land on another instance, so prod/staging keep codes in Redis; memory otherwise
+func Default() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		switch strings.ToLower(config.GetConfigValue("ENV")) {
+			case "prod", "staging":
+				store = NewRedisStore("authcode:")
+			default:
+				store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Sets the code backend; tests install a fresh one
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Parks a validated authorization request while the user logs in; returns its handoff id
+func Begin(ctx context.Context, r Request) (string, error) {
+	if r.CodeChallenge == "" { return "", fmt.Errorf("missing code challenge") }
+
+	id, err := randomToken()
+	if err != nil { return "", err }
+
+	r.CreatedAt = time.Now()
+	if err := put(ctx, "request:"+Hash(id), r, RequestTTL()); err != nil { return "", err }
+	return id, nil
+}
+
+// Finishes the login handoff: consumes the request and issues a code for subject.
+// Returns the grant-bearing redirect (redirect_uri?code=...&state=...).
+func Complete(ctx context.Context, requestID string, subject string) (string, error) {
+	r, err := takeRequest(ctx, requestID)
+	if err != nil { return "", err }
+	if subject == "" { return "", fmt.Errorf("missing subject") }
+
+	code, err := randomToken()
+	if err != nil { return "", err }
+
+	grant := Grant{
+		ClientID:      r.ClientID,
+		RedirectURI:   r.RedirectURI,
+		Subject:       subject,
+		Scopes:        r.Scopes,
+		CodeChallenge: r.CodeChallenge,
+		IssuedAt:      time.Now(),
+	}
+	if err := put(ctx, "code:"+Hash(code), grant, CodeTTL()); err != nil { return "", err }
+
+	return RedirectURL(r.RedirectURI, url.Values{"code": {code}}, r.State)
+}
+
+// Consumes the request after the user declined; returns the access_denied redirect
+func Deny(ctx context.Context, requestID string) (string, error) {
+	r, err := takeRequest(ctx, requestID)
+	if err != nil { return "", err }
+	return RedirectURL(r.RedirectURI, url.Values{"error": {"access_denied"}}, r.State)
+}
+
+// Exchanges a code for its grant. The code is consumed even when the checks fail so it can't be retried.
+func Redeem(ctx context.Context, code string, clientID string, redirectURI string, verifier string) (*Grant, error) {
+	data, err := Default().Take(ctx, "code:"+Hash(code))
+	if err != nil { return nil, err }
+	if data == nil { return nil, ErrInvalidCode }
+
+	var grant Grant
+	if err := json.Unmarshal(data, &grant); err != nil { return nil, fmt.Errorf("corrupt authorization code: %w", err) }
+
+	if grant.ClientID != clientID { return nil, ErrClientMismatch }
+	if grant.RedirectURI != redirectURI { return nil, ErrRedirectMismatch }
+	if !VerifyPKCE(verifier, grant.CodeChallenge) { return nil, ErrPKCEFailed }
+	return &grant, nil
+}
+
+// Checks a PKCE verifier against an S256 challenge (RFC 7636 Section 4.6)
+func VerifyPKCE(verifier string, challenge string) bool {
+	if !verifierPattern.MatchString(verifier) || challenge == "" { return false }
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// Appends params and state to a registered redirect URI, keeping its own query
+func RedirectURL(redirectURI string, params url.Values, state string) (string, error) {
+	target, err := url.Parse(redirectURI)
+	if err != nil { return "", err }
+
+	query := target.Query()
+	for key, vals := range params {
+		for _, val := range vals { query.Add(key, val) }
+	}
+	if state != "" { query.Set("state", state) }
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// Storage key for a code or handoff id; the raw values are never stored
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lifetime of authorization codes (AUTH_CODE_TTL_SEC)
+func CodeTTL() time.Duration { return config.GetSeconds("AUTH_CODE_TTL_SEC", DEFAULT_CODE_TTL_SEC) }
+
+// Time allowed between /oauth/authorize and the login handoff completing (AUTH_REQUEST_TTL_SEC)
+func RequestTTL() time.Duration { return config.GetSeconds("AUTH_REQUEST_TTL_SEC", DEFAULT_REQUEST_TTL_SEC) }
+
+func takeRequest(ctx context.Context, requestID string) (*Request, error) {
+	data, err := Default().Take(ctx, "request:"+Hash(requestID))
+	if err != nil { return nil, err }
+	if data == nil { return nil, ErrInvalidRequest }
+
+	var r Request
+	if err := json.Unmarshal(data, &r); err != nil { return nil, fmt.Errorf("corrupt authorization request: %w", err) }
+	return &r, nil
+}
+
+func put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil { return err }
+	return Default().Put(ctx, key, data, ttl)
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil { return "", err }
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
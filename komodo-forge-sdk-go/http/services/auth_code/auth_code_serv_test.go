@@ -0,0 +1,58 @@
+package authCode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCodeFlowWithPKCE(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	id, err := Begin(ctx, Request{
+		ClientID: "web", RedirectURI: "http://localhost:7001/auth/callback?from=login", State: "xyz", CodeChallenge: challenge,
+	})
+	if err != nil { t.Fatalf("begin: %v", err) }
+
+	redirect, err := Complete(ctx, id, "user-1")
+	if err != nil { t.Fatalf("complete: %v", err) }
+	if _, err := Complete(ctx, id, "user-1"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("second completion: err = %v, want ErrInvalidRequest", err)
+	}
+
+	target, _ := url.Parse(redirect)
+	query := target.Query()
+	if query.Get("state") != "xyz" || query.Get("from") != "login" || query.Get("code") == "" {
+		t.Fatalf("redirect = %s, want code, state and the original query", redirect)
+	}
+	code := query.Get("code")
+
+	grant, err := Redeem(ctx, code, "web", "http://localhost:7001/auth/callback?from=login", verifier)
+	if err != nil { t.Fatalf("redeem: %v", err) }
+	if grant.Subject != "user-1" { t.Errorf("subject = %q, want user-1", grant.Subject) }
+
+	if _, err := Redeem(ctx, code, "web", "http://localhost:7001/auth/callback?from=login", verifier); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("replayed code: err = %v, want ErrInvalidCode", err)
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 Appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if !VerifyPKCE(verifier, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM") {
+		t.Error("RFC 7636 example verifier rejected")
+	}
+	if VerifyPKCE("too-short", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM") {
+		t.Error("malformed verifier accepted")
+	}
+}
@@ -0,0 +1,49 @@
+package authCode
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// Holds codes and pending requests in this process, so it only suits a single instance
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Put(_ context.Context, key string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+	s.entries[key] = memoryEntry{data: data, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	delete(s.entries, key)
+	if !ok || !time.Now().Before(entry.expires) { return nil, nil }
+	return entry.data, nil
+}
+
+// Drops expired entries; called under the lock on every write so the map can't grow unbounded
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) { delete(s.entries, key) }
+	}
+}
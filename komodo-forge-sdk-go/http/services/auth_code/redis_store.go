@@ -0,0 +1,24 @@
+package authCode
+
+import (
+	"context"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"time"
+)
+
+// Distributed store on the shared Elasticache client; GETDEL makes Take single-use across instances
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore { return &RedisStore{prefix: prefix} }
+
+func (s *RedisStore) Put(_ context.Context, key string, data []byte, ttl time.Duration) error {
+	return elasticache.Set(s.prefix+key, string(data), elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) Take(_ context.Context, key string) ([]byte, error) {
+	raw, err := elasticache.GetDel(s.prefix + key)
+	if err != nil || raw == "" { return nil, err }
+	return []byte(raw), nil
+}
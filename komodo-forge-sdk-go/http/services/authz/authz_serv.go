@@ -0,0 +1,108 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrAdminRequired     = errors.New("admin privileges required")
+	ErrNoPolicy          = errors.New("no authorization policy for route")
+)
+
+// Actions ordered by privilege; a granted action covers every weaker one on the same resource
+var actionRank = map[string]int{
+	"read":  1,
+	"write": 2,
+	"admin": 3,
+}
+
+// What a caller must hold to reach a route
+type Requirement struct {
+	Scopes    []string // all required
+	AnyScopes []string // at least one required
+	Admin     bool     // requires ctx IS_ADMIN_KEY
+}
+
+// Checks the requirement against the scopes and admin flag AuthMiddleware put in the context.
+// Errors wrap ErrInsufficientScope or ErrAdminRequired and carry the denial reason.
+func Check(req *http.Request, r Requirement) error {
+	granted := GrantedScopes(req)
+
+	if missing := MissingScopes(granted, r.Scopes); len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInsufficientScope, strings.Join(missing, " "))
+	}
+	if len(r.AnyScopes) > 0 && !HasAnyScope(granted, r.AnyScopes) {
+		return fmt.Errorf("%w: requires one of %s", ErrInsufficientScope, strings.Join(r.AnyScopes, " "))
+	}
+	if r.Admin {
+		if isAdmin, _ := req.Context().Value(ctxKeys.IS_ADMIN_KEY).(bool); !isAdmin { return ErrAdminRequired }
+	}
+	return nil
+}
+
+// Returns the token scopes placed in the context by AuthMiddleware
+func GrantedScopes(req *http.Request) []string {
+	scopes, _ := req.Context().Value(ctxKeys.SCOPES_KEY).([]string)
+	return scopes
+}
+
+// Returns the required scopes the granted ones don't cover
+func MissingScopes(granted []string, required []string) []string {
+	missing := make([]string, 0)
+	if len(required) == 0 { return missing }
+
+	expanded := expand(granted)
+	for _, scope := range required {
+		if !covered(expanded, scope) { missing = append(missing, scope) }
+	}
+	return missing
+}
+
+// Reports whether the granted scopes cover at least one of the candidates
+func HasAnyScope(granted []string, candidates []string) bool {
+	expanded := expand(granted)
+	return slices.ContainsFunc(candidates, func(scope string) bool { return covered(expanded, scope) })
+}
+
+// Reports whether the granted scopes cover the required one
+func Satisfies(granted []string, required string) bool { return covered(expand(granted), required) }
+
+// Adds every scope reachable through the configured implications
+func expand(granted []string) []string {
+	implies := currentImplications()
+	if len(implies) == 0 { return granted }
+
+	out := slices.Clone(granted)
+	for i := 0; i < len(out); i++ {
+		for _, implied := range implies[out[i]] {
+			if !slices.Contains(out, implied) { out = append(out, implied) }
+		}
+	}
+	return out
+}
+
+func covered(granted []string, required string) bool {
+	return slices.ContainsFunc(granted, func(scope string) bool { return implies(scope, required) })
+}
+
+// A scope covers another when they match, it is a "resource:*" wildcard for the resource,
+// or it names a stronger action on the same resource (orders:write covers orders:read)
+func implies(granted string, required string) bool {
+	if granted == required { return true }
+
+	if resource, ok := strings.CutSuffix(granted, ":*"); ok {
+		return strings.HasPrefix(required, resource + ":")
+	}
+
+	gi, ri := strings.LastIndex(granted, ":"), strings.LastIndex(required, ":")
+	if gi < 0 || ri < 0 || granted[:gi] != required[:ri] { return false }
+
+	gRank, rRank := actionRank[granted[gi+1:]], actionRank[required[ri+1:]]
+	return rRank > 0 && gRank > rRank
+}
@@ -0,0 +1,95 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testPolicies = `
+implies:
+  "users:profile": ["profile:write", "addresses:write"]
+policies:
+  - route: /me/orders/{id}
+    methods: [get]
+    scopes: [orders:read]
+  - route: /me/orders/*
+    methods: [POST]
+    scopes: [orders:write]
+  - route: /me/profile
+    anyScopes: [profile:read, support:read]
+  - route: /admin/*
+    admin: true
+  - route: /me/ping
+    public: true
+`
+
+func withGrant(req *http.Request, admin bool, scopes ...string) *http.Request {
+	ctx := context.WithValue(req.Context(), ctxKeys.SCOPES_KEY, scopes)
+	if admin { ctx = context.WithValue(ctx, ctxKeys.IS_ADMIN_KEY, true) }
+	return req.WithContext(ctx)
+}
+
+func TestScopeHierarchy(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"orders:read", "orders:read", true},
+		{"orders:write", "orders:read", true},
+		{"orders:admin", "orders:write", true},
+		{"orders:read", "orders:write", false},
+		{"orders:write", "payments:read", false},
+		{"orders:*", "orders:history:read", true},
+		{"orders:*", "ordersx:read", false},
+		{"write", "read", false},
+		{"orders:write", "orders:export", false},
+	}
+	for _, c := range cases {
+		if got := Satisfies([]string{c.granted}, c.required); got != c.want {
+			t.Errorf("%s covers %s = %v, want %v", c.granted, c.required, got, c.want)
+		}
+	}
+}
+
+func TestPoliciesAuthorize(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	if err != nil { t.Fatalf("parse: %v", err) }
+	SetPolicies(set)
+	defer SetPolicies(nil)
+
+	cases := []struct {
+		name    string
+		req     *http.Request
+		wantErr error
+	}{
+		{"write covers read", withGrant(httptest.NewRequest("GET", "/v1/me/orders/42", nil), false, "orders:write"), nil},
+		{"read is not write", withGrant(httptest.NewRequest("POST", "/me/orders/cancel", nil), false, "orders:read"), ErrInsufficientScope},
+		{"any scope", withGrant(httptest.NewRequest("PUT", "/me/profile", nil), false, "support:read"), nil},
+		{"implied scope", withGrant(httptest.NewRequest("PUT", "/me/profile", nil), false, "users:profile"), nil},
+		{"no scopes", httptest.NewRequest("PUT", "/me/profile", nil), ErrInsufficientScope},
+		{"admin required", withGrant(httptest.NewRequest("GET", "/admin/users", nil), false, "orders:admin"), ErrAdminRequired},
+		{"admin", withGrant(httptest.NewRequest("GET", "/admin/users", nil), true), nil},
+		{"public", httptest.NewRequest("GET", "/me/ping", nil), nil},
+		{"unmatched route", withGrant(httptest.NewRequest("GET", "/me/unknown", nil), true, "orders:admin"), ErrNoPolicy},
+	}
+	for _, c := range cases {
+		err := Authorize(c.req)
+		if (c.wantErr == nil) != (err == nil) || (c.wantErr != nil && !errors.Is(err, c.wantErr)) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+func TestParsePoliciesRejectsEmptyRequirements(t *testing.T) {
+	if _, err := ParsePolicies([]byte("policies:\n  - route: /me\n")); err == nil {
+		t.Error("expected a policy without requirements to be rejected")
+	}
+	if _, err := ParsePolicies([]byte("default: maybe\n")); err == nil {
+		t.Error("expected an unknown default to be rejected")
+	}
+}
@@ -0,0 +1,147 @@
+package authz
+
+import (
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	httpReq "komodo-forge-sdk-go/http/request"
+	"komodo-forge-sdk-go/http/route"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// What happens to requests no policy matches
+const (
+	DefaultDeny  = "deny"
+	DefaultAllow = "allow"
+)
+
+// Declarative requirement for requests matching a route and method.
+// The first policy in the file that matches a request decides it.
+type Policy struct {
+	Name      string   `yaml:"name,omitempty"`
+	Route     string   `yaml:"route"` // template like /me/orders/{id} or /me/*
+	Methods   []string `yaml:"methods,omitempty"`
+	Scopes    []string `yaml:"scopes,omitempty"`    // all required
+	AnyScopes []string `yaml:"anyScopes,omitempty"` // at least one required
+	Admin     bool     `yaml:"admin,omitempty"`
+	Public    bool     `yaml:"public,omitempty"` // any authenticated caller
+
+	template *route.Template
+}
+
+// A parsed and compiled policy file
+type PolicySet struct {
+	Default  string              `yaml:"default,omitempty"` // deny (default) | allow
+	Implies  map[string][]string `yaml:"implies,omitempty"` // scope -> scopes it also grants
+	Policies []Policy            `yaml:"policies"`
+}
+
+var policies atomic.Pointer[PolicySet]
+
+// Loads policies from AUTHZ_POLICIES_PATH; reports false when none is configured
+func LoadPoliciesFromConfig() (bool, error) {
+	path := config.GetConfigValue("AUTHZ_POLICIES_PATH")
+	if path == "" { return false, nil }
+	return true, LoadPolicies(path)
+}
+
+// Activates the authorization policies in path; a rejected file leaves the active set untouched
+func LoadPolicies(path string) error {
+	parsed, err := route.LoadPolicyFile(path, "authorization policies", ParsePolicies)
+	if err != nil { return err }
+
+	SetPolicies(parsed)
+	logger.Info(fmt.Sprintf("loaded %d authorization policies from %s", len(parsed.Policies), path))
+	return nil
+}
+
+// Compiles authorization policy yaml, checking every policy requires something, without activating it
+func ParsePolicies(data []byte) (*PolicySet, error) {
+	var set PolicySet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid authorization policy yaml: %w", err)
+	}
+
+	switch set.Default {
+		case "":
+			set.Default = DefaultDeny
+		case DefaultDeny, DefaultAllow:
+		default:
+			return nil, fmt.Errorf("unknown default %q", set.Default)
+	}
+
+	for i := range set.Policies {
+		p := &set.Policies[i]
+		if p.Name == "" { p.Name = p.Route }
+		if err := p.compile(); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+	}
+	return &set, nil
+}
+
+// Activates a policy set (nil clears it)
+func SetPolicies(set *PolicySet) { policies.Store(set) }
+
+// Reports whether a policy file is active
+func PoliciesLoaded() bool { return policies.Load() != nil }
+
+// Returns the first policy matching the request, or nil
+func MatchPolicy(req *http.Request) *Policy {
+	set := policies.Load()
+	if set == nil { return nil }
+
+	for i := range set.Policies {
+		if p := &set.Policies[i]; p.matches(req.Method, req.URL.Path) { return p }
+	}
+	return nil
+}
+
+// Checks the request against the active policy file.
+// Unmatched routes are denied with ErrNoPolicy unless the file defaults to allow.
+func Authorize(req *http.Request) error {
+	set := policies.Load()
+	if set == nil { return nil }
+
+	policy := MatchPolicy(req)
+	if policy == nil {
+		if set.Default == DefaultAllow { return nil }
+		return fmt.Errorf("%w: %s %s", ErrNoPolicy, req.Method, httpReq.GetAPIRoute(req))
+	}
+	if policy.Public { return nil }
+	return Check(req, policy.Requirement())
+}
+
+func (p *Policy) Requirement() Requirement {
+	return Requirement{Scopes: p.Scopes, AnyScopes: p.AnyScopes, Admin: p.Admin}
+}
+
+func (p *Policy) compile() error {
+	if strings.Trim(p.Route, "/") == "" { return fmt.Errorf("route is required") }
+	p.template = route.ParsePolicyRoute(p.Route)
+
+	for i, m := range p.Methods { p.Methods[i] = strings.ToUpper(m) }
+
+	if p.Public && (len(p.Scopes) > 0 || len(p.AnyScopes) > 0 || p.Admin) {
+		return fmt.Errorf("public policies cannot require scopes or admin")
+	}
+	if !p.Public && len(p.Scopes) == 0 && len(p.AnyScopes) == 0 && !p.Admin {
+		return fmt.Errorf("policy requires nothing; mark it public instead")
+	}
+	return nil
+}
+
+func (p *Policy) matches(method string, path string) bool {
+	if len(p.Methods) > 0 && !slices.Contains(p.Methods, method) { return false }
+	return p.template.Match(path)
+}
+
+func currentImplications() map[string][]string {
+	if set := policies.Load(); set != nil { return set.Implies }
+	return nil
+}
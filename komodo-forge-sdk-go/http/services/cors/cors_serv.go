@@ -0,0 +1,172 @@
+package cors
+
+import (
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	HEADER_ALLOW_ORIGIN      = "Access-Control-Allow-Origin"
+	HEADER_ALLOW_CREDENTIALS = "Access-Control-Allow-Credentials"
+	HEADER_ALLOW_METHODS     = "Access-Control-Allow-Methods"
+	HEADER_ALLOW_HEADERS     = "Access-Control-Allow-Headers"
+	HEADER_EXPOSE_HEADERS    = "Access-Control-Expose-Headers"
+	HEADER_MAX_AGE           = "Access-Control-Max-Age"
+	HEADER_REQUEST_METHOD    = "Access-Control-Request-Method"
+	HEADER_REQUEST_HEADERS   = "Access-Control-Request-Headers"
+
+	DEFAULT_MAX_AGE_SEC = 600
+
+	regexPrefix = "regex:"
+)
+
+var (
+	defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	defaultHeaders = []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "X-CSRF-Token", "X-Requested-By"}
+)
+
+// One allowed origin pattern: exact ("https://shop.komodo.dev"), wildcard subdomain
+// ("https://*.komodo.dev", never the apex), regex ("regex:^https://pr-[0-9]+\.komodo\.dev$") or "*"
+type originMatcher struct {
+	any    bool
+	exact  string
+	scheme string
+	suffix string // ".komodo.dev:443" style host suffix for wildcard subdomains
+	re     *regexp.Regexp
+}
+
+// Reports whether a preflight request: OPTIONS with Access-Control-Request-Method
+func IsPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get(HEADER_REQUEST_METHOD) != ""
+}
+
+// Reports whether the origin is allowed by the policy matching the request
+func OriginAllowed(req *http.Request, origin string) bool {
+	return MatchPolicy(req).AllowsOrigin(origin)
+}
+
+// Lowercases "scheme://host[:port]" and rejects anything else, including "null" and origins with a path
+func NormalizeOrigin(origin string) (string, bool) {
+	origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" || parsed.User != nil { return "", false }
+	if parsed.Scheme != "http" && parsed.Scheme != "https" { return "", false }
+	if parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" { return "", false }
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), true
+}
+
+// Builds the fallback policy from CORS_* config values, used for routes without an override
+// and for fields the policy file's default leaves unset
+func ConfigDefault() Policy {
+	p := Policy{
+		Name:           "default",
+		AllowedOrigins: splitList(config.GetConfigValue("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods: splitList(config.GetConfigValue("CORS_ALLOWED_METHODS")),
+		AllowedHeaders: splitList(config.GetConfigValue("CORS_ALLOWED_HEADERS")),
+		ExposedHeaders: splitList(config.GetConfigValue("CORS_EXPOSED_HEADERS")),
+	}
+	if len(p.AllowedMethods) == 0 { p.AllowedMethods = slices.Clone(defaultMethods) }
+	if len(p.AllowedHeaders) == 0 { p.AllowedHeaders = slices.Clone(defaultHeaders) }
+
+	if creds, err := strconv.ParseBool(strings.TrimSpace(config.GetConfigValue("CORS_ALLOW_CREDENTIALS"))); err == nil {
+		p.AllowCredentials = &creds
+	}
+	maxAge := DEFAULT_MAX_AGE_SEC
+	if sec, err := strconv.Atoi(strings.TrimSpace(config.GetConfigValue("CORS_MAX_AGE_SEC"))); err == nil && sec >= 0 {
+		maxAge = sec
+	}
+	p.MaxAgeSec = &maxAge
+	return p
+}
+
+func (p *Policy) AllowsOrigin(origin string) bool {
+	normalized, ok := NormalizeOrigin(origin)
+	if !ok { return false }
+
+	for _, m := range p.origins {
+		if m.matches(normalized) { return true }
+	}
+	return false
+}
+
+// Checks the method and headers a preflight asks for
+func (p *Policy) CheckPreflight(req *http.Request) error {
+	method := strings.ToUpper(strings.TrimSpace(req.Header.Get(HEADER_REQUEST_METHOD)))
+	if !slices.Contains(p.AllowedMethods, method) { return fmt.Errorf("method %s not allowed", method) }
+
+	if p.anyHeader { return nil }
+	for _, hdr := range splitList(req.Header.Get(HEADER_REQUEST_HEADERS)) {
+		if !slices.Contains(p.AllowedHeaders, http.CanonicalHeaderKey(hdr)) {
+			return fmt.Errorf("header %s not allowed", hdr)
+		}
+	}
+	return nil
+}
+
+// Value for Access-Control-Allow-Origin: the caller's origin, or "*" for public policies
+// without credentials so shared caches can reuse the response
+func (p *Policy) AllowOriginValue(origin string) string {
+	if p.anyOrigin && !p.Credentials() { return "*" }
+	normalized, _ := NormalizeOrigin(origin)
+	return normalized
+}
+
+func (p *Policy) Credentials() bool { return p.AllowCredentials != nil && *p.AllowCredentials }
+
+func (p *Policy) MaxAge() int {
+	if p.MaxAgeSec == nil { return DEFAULT_MAX_AGE_SEC }
+	return *p.MaxAgeSec
+}
+
+func compileOrigin(pattern string) (originMatcher, error) {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+		case pattern == "*":
+			return originMatcher{any: true}, nil
+		case strings.HasPrefix(pattern, regexPrefix):
+			expr := strings.TrimPrefix(pattern, regexPrefix)
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil { return originMatcher{}, fmt.Errorf("invalid origin regex %q: %w", expr, err) }
+			return originMatcher{re: re}, nil
+		case strings.Contains(pattern, "://*."):
+			scheme, host, _ := strings.Cut(strings.ToLower(pattern), "://*.")
+			if _, ok := NormalizeOrigin(scheme + "://" + host); !ok {
+				return originMatcher{}, fmt.Errorf("invalid wildcard origin %q", pattern)
+			}
+			return originMatcher{scheme: scheme, suffix: "." + host}, nil
+	}
+
+	normalized, ok := NormalizeOrigin(pattern)
+	if !ok { return originMatcher{}, fmt.Errorf("invalid origin %q", pattern) }
+	return originMatcher{exact: normalized}, nil
+}
+
+func (m originMatcher) matches(origin string) bool {
+	switch {
+		case m.any:
+			return true
+		case m.re != nil:
+			return m.re.MatchString(origin)
+		case m.suffix != "":
+			scheme, host, _ := strings.Cut(origin, "://")
+			if scheme != m.scheme || !strings.HasSuffix(host, m.suffix) { return false }
+			// At least one label in front of the suffix; "https://.komodo.dev" is not a subdomain
+			sub := strings.TrimSuffix(host, m.suffix)
+			return sub != "" && !strings.HasPrefix(sub, ".") && !strings.Contains(sub, ":")
+	}
+	return m.exact == origin
+}
+
+func splitList(raw string) []string {
+	out := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" { out = append(out, item) }
+	}
+	return out
+}
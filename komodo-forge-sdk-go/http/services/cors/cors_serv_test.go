@@ -0,0 +1,131 @@
+package cors
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+const testPolicies = `
+default:
+  allowedOrigins:
+    - http://localhost:7001
+    - https://*.komodo.dev
+    - regex:https://pr-[0-9]+\.preview\.komodo\.io
+  allowCredentials: true
+  allowedHeaders: [authorization, content-type, x-csrf-token]
+  exposedHeaders: [x-request-id]
+routes:
+  - route: /item/suggestion
+    allowedMethods: [post]
+  - route: /item/{sku}
+    allowedOrigins: ["*"]
+    allowedMethods: [get, head]
+    allowCredentials: false
+`
+
+func TestOriginPatterns(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	if err != nil { t.Fatalf("parse: %v", err) }
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:7001", true},
+		{"HTTP://LOCALHOST:7001/", true},
+		{"http://localhost:7002", false},
+		{"https://shop.komodo.dev", true},
+		{"https://a.b.komodo.dev", true},
+		{"https://komodo.dev", false},
+		{"http://shop.komodo.dev", false},
+		{"https://shop.komodo.dev:8443", false},
+		{"https://evilkomodo.dev", false},
+		{"https://pr-42.preview.komodo.io", true},
+		{"https://pr-42.preview.komodo.io.evil.example", false},
+		{"null", false},
+		{"https://shop.komodo.dev/path", false},
+	}
+	for _, tc := range cases {
+		if got := set.Default.AllowsOrigin(tc.origin); got != tc.allowed {
+			t.Errorf("origin %q: allowed = %v, want %v", tc.origin, got, tc.allowed)
+		}
+	}
+}
+
+func TestRouteOverrides(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	if err != nil { t.Fatalf("parse: %v", err) }
+	SetPolicies(set)
+	t.Cleanup(func() { SetPolicies(nil) })
+
+	item := MatchPolicy(httptest.NewRequest("GET", "/v2/item/SKU-1", nil))
+	if item.Name != "/item/{sku}" || item.Credentials() {
+		t.Fatalf("item route: got policy %q credentials %v", item.Name, item.Credentials())
+	}
+	if got := item.AllowOriginValue("https://anyone.example"); got != "*" {
+		t.Errorf("public policy origin value = %q, want *", got)
+	}
+	// Unset fields inherit from the default
+	if len(item.ExposedHeaders) != 1 || item.ExposedHeaders[0] != "X-Request-Id" {
+		t.Errorf("item route exposed headers = %v", item.ExposedHeaders)
+	}
+
+	// Literal routes listed before "/item/{sku}" win over the single-segment template
+	preflight := httptest.NewRequest("OPTIONS", "/item/suggestion", nil)
+	preflight.Header.Set(HEADER_REQUEST_METHOD, "POST")
+	preflight.Header.Set(HEADER_REQUEST_HEADERS, "authorization, content-type, x-csrf-token")
+	suggestion := MatchPolicy(preflight)
+	if suggestion.Name != "/item/suggestion" || !suggestion.Credentials() {
+		t.Fatalf("suggestion route: got policy %q credentials %v", suggestion.Name, suggestion.Credentials())
+	}
+	if err := suggestion.CheckPreflight(preflight); err != nil {
+		t.Errorf("suggestion preflight: %v", err)
+	}
+	if got := suggestion.AllowOriginValue("http://localhost:7001"); got != "http://localhost:7001" {
+		t.Errorf("credentialed policy origin value = %q", got)
+	}
+
+	unmatched := MatchPolicy(httptest.NewRequest("POST", "/item/suggestion/extra", nil))
+	if unmatched.Name != "default" {
+		t.Errorf("unmatched route: got policy %q", unmatched.Name)
+	}
+}
+
+func TestCheckPreflight(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	if err != nil { t.Fatalf("parse: %v", err) }
+
+	cases := []struct {
+		method  string
+		headers string
+		allowed bool
+	}{
+		{"POST", "content-type, x-csrf-token", true},
+		{"post", "", true},
+		{"DELETE", "", false},
+		{"POST", "x-unknown", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("OPTIONS", "/item/suggestion", nil)
+		req.Header.Set(HEADER_REQUEST_METHOD, tc.method)
+		if tc.headers != "" { req.Header.Set(HEADER_REQUEST_HEADERS, tc.headers) }
+
+		if err := set.Default.CheckPreflight(req); (err == nil) != tc.allowed {
+			t.Errorf("%s with %q: err = %v, allowed = %v", tc.method, tc.headers, err, tc.allowed)
+		}
+	}
+}
+
+func TestRejectsWildcardWithCredentials(t *testing.T) {
+	invalid := []string{
+		"default:\n  allowedOrigins: [\"*\"]\n  allowCredentials: true\n",
+		"default:\n  allowedOrigins: [\"regex:(\"]\n",
+		"default:\n  allowedOrigins: [\"https://shop.komodo.dev/cart\"]\n",
+		"routes:\n  - allowedOrigins: [\"http://localhost:7001\"]\n",
+	}
+	for _, data := range invalid {
+		if _, err := ParsePolicies([]byte(data)); err == nil {
+			t.Errorf("expected policy to be rejected:\n%s", data)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package cors
+
+import (
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/http/route"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cross-origin rules for requests matching a route. Unset fields inherit from the default policy.
+// Of the route overrides, the first one in the file matching the request applies.
+type Policy struct {
+	Name             string   `yaml:"name,omitempty"`
+	Route            string   `yaml:"route,omitempty"` // template like /item/{sku} or /item/*
+	AllowedOrigins   []string `yaml:"allowedOrigins,omitempty"`
+	AllowedMethods   []string `yaml:"allowedMethods,omitempty"`
+	AllowedHeaders   []string `yaml:"allowedHeaders,omitempty"` // "*" allows any, only without credentials
+	ExposedHeaders   []string `yaml:"exposedHeaders,omitempty"`
+	AllowCredentials *bool    `yaml:"allowCredentials,omitempty"`
+	MaxAgeSec        *int     `yaml:"maxAgeSec,omitempty"`
+
+	template  *route.Template
+	origins   []originMatcher
+	anyOrigin bool
+	anyHeader bool
+}
+
+// A parsed and compiled policy file
+type PolicySet struct {
+	Default Policy   `yaml:"default"`
+	Routes  []Policy `yaml:"routes,omitempty"`
+}
+
+var policies atomic.Pointer[PolicySet]
+
+// Loads policies from CORS_POLICIES_PATH; without one the CORS_* config values apply to every route.
+// Reports false when no file is configured.
+func LoadPoliciesFromConfig() (bool, error) {
+	path := config.GetConfigValue("CORS_POLICIES_PATH")
+	if path == "" {
+		set, err := ParsePolicies(nil)
+		if err != nil { return false, err }
+		SetPolicies(set)
+		return false, nil
+	}
+	return true, LoadPolicies(path)
+}
+
+// Activates the cors policies in path unless the file is unreadable or invalid
+func LoadPolicies(path string) error {
+	parsed, err := route.LoadPolicyFile(path, "cors policies", ParsePolicies)
+	if err != nil { return err }
+
+	SetPolicies(parsed)
+	logger.Info(fmt.Sprintf("loaded %d cors route policies from %s", len(parsed.Routes), path))
+	return nil
+}
+
+// Compiles cors policy yaml without activating it. The file's default is layered over ConfigDefault.
+func ParsePolicies(data []byte) (*PolicySet, error) {
+	var set PolicySet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid cors policy yaml: %w", err)
+	}
+
+	if set.Default.Route != "" { return nil, fmt.Errorf("default policy cannot set a route") }
+	set.Default = set.Default.inherit(ConfigDefault())
+	set.Default.Name = "default"
+	if err := set.Default.compile(); err != nil {
+		return nil, fmt.Errorf("policy %q: %w", set.Default.Name, err)
+	}
+
+	for i := range set.Routes {
+		p := &set.Routes[i]
+		if p.Name == "" { p.Name = p.Route }
+		*p = p.inherit(set.Default)
+
+		if strings.Trim(p.Route, "/") == "" { return nil, fmt.Errorf("policy %q: route is required", p.Name) }
+		p.template = route.ParsePolicyRoute(p.Route)
+
+		if err := p.compile(); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+	}
+	return &set, nil
+}
+
+// Activates a policy set (nil falls back to ConfigDefault)
+func SetPolicies(set *PolicySet) { policies.Store(set) }
+
+// Returns the first route policy matching the request, or the default policy
+func MatchPolicy(req *http.Request) *Policy {
+	set := policies.Load()
+	if set == nil {
+		fallback := ConfigDefault()
+		if err := fallback.compile(); err != nil {
+			logger.Error("invalid cors config, denying cross-origin requests", err)
+			return &Policy{Name: "deny"}
+		}
+		return &fallback
+	}
+
+	for i := range set.Routes {
+		if p := &set.Routes[i]; p.template.Match(req.URL.Path) { return p }
+	}
+	return &set.Default
+}
+
+func (p Policy) inherit(base Policy) Policy {
+	if p.AllowedOrigins == nil { p.AllowedOrigins = slices.Clone(base.AllowedOrigins) }
+	if p.AllowedMethods == nil { p.AllowedMethods = slices.Clone(base.AllowedMethods) }
+	if p.AllowedHeaders == nil { p.AllowedHeaders = slices.Clone(base.AllowedHeaders) }
+	if p.ExposedHeaders == nil { p.ExposedHeaders = slices.Clone(base.ExposedHeaders) }
+	if p.AllowCredentials == nil { p.AllowCredentials = base.AllowCredentials }
+	if p.MaxAgeSec == nil { p.MaxAgeSec = base.MaxAgeSec }
+	return p
+}
+
+func (p *Policy) compile() error {
+	p.origins = make([]originMatcher, 0, len(p.AllowedOrigins))
+	p.anyOrigin = false
+	for _, pattern := range p.AllowedOrigins {
+		m, err := compileOrigin(pattern)
+		if err != nil { return err }
+		if m.any { p.anyOrigin = true }
+		p.origins = append(p.origins, m)
+	}
+
+	for i, m := range p.AllowedMethods { p.AllowedMethods[i] = strings.ToUpper(strings.TrimSpace(m)) }
+	p.anyHeader = false
+	for i, hdr := range p.AllowedHeaders {
+		if hdr = strings.TrimSpace(hdr); hdr == "*" { p.anyHeader = true }
+		p.AllowedHeaders[i] = http.CanonicalHeaderKey(hdr)
+	}
+	for i, hdr := range p.ExposedHeaders { p.ExposedHeaders[i] = http.CanonicalHeaderKey(strings.TrimSpace(hdr)) }
+
+	// Browsers ignore "*" on credentialed requests; echoing every origin with credentials would
+	// let any site read responses as the user, so it is refused outright
+	if p.Credentials() && p.anyOrigin { return fmt.Errorf("allowedOrigins \"*\" cannot be combined with credentials") }
+	if p.Credentials() && p.anyHeader { return fmt.Errorf("allowedHeaders \"*\" cannot be combined with credentials") }
+	if p.MaxAgeSec != nil && *p.MaxAgeSec < 0 { return fmt.Errorf("maxAgeSec must not be negative") }
+	return nil
+}
@@ -0,0 +1,166 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Readable only by this host; the token is handed to scripts in the GET /csrf body instead
+	COOKIE_NAME = "__Host-komodo-csrf"
+
+	DEFAULT_TTL_SEC  = 12 * 3600
+	anonymousBinding = "anonymous" // binding for browsers that have no session yet
+)
+
+var (
+	ErrMissingToken = errors.New("missing csrf token")
+	ErrInvalidToken = errors.New("invalid csrf token")
+	ErrExpiredToken = errors.New("expired csrf token")
+	ErrOriginDenied = errors.New("request origin not allowed")
+)
+
+var (
+	secret     []byte
+	secretOnce sync.Once
+)
+
+// Issues a token bound to the session: "<issued unix>.<nonce>.<HMAC-SHA256(session|issued|nonce)>".
+// An empty sessionID binds the token to sessionless browsers only.
+func Issue(sessionID string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil { return "", err }
+
+	issued := strconv.FormatInt(time.Now().Unix(), 10)
+	encodedNonce := base64.RawURLEncoding.EncodeToString(nonce)
+	return issued + "." + encodedNonce + "." + sign(sessionID, issued, encodedNonce), nil
+}
+
+// Verifies the token's signature, session binding and age in constant time
+func Verify(token string, sessionID string) error {
+	if token == "" { return ErrMissingToken }
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { return ErrInvalidToken }
+
+	expected := sign(sessionID, parts[0], parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) { return ErrInvalidToken }
+
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil { return ErrInvalidToken }
+	if time.Since(time.Unix(issued, 0)) > TTL() { return ErrExpiredToken }
+	return nil
+}
+
+// Reports whether the value has the shape of an issued token, without checking its signature
+func WellFormed(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { return false }
+	if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil { return false }
+
+	nonce, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(nonce) != 16 { return false }
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	return err == nil && len(mac) == sha256.Size
+}
+
+// Checks Origin, or Referer when Origin is absent, against the request's own origin and CSRF_TRUSTED_ORIGINS.
+// Requests carrying neither header are left to the token check.
+func CheckOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		referer := req.Header.Get("Referer")
+		if referer == "" {
+			if origin == "null" { return fmt.Errorf("%w: opaque origin", ErrOriginDenied) }
+			return nil
+		}
+		parsed, err := url.Parse(referer)
+		if err != nil || parsed.Host == "" { return fmt.Errorf("%w: malformed referer", ErrOriginDenied) }
+		origin = parsed.Scheme + "://" + parsed.Host
+	}
+
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	if origin == requestOrigin(req) || slices.Contains(TrustedOrigins(), origin) { return nil }
+	return fmt.Errorf("%w: %s", ErrOriginDenied, origin)
+}
+
+// Origins allowed to send state-changing requests (CSRF_TRUSTED_ORIGINS, comma separated)
+func TrustedOrigins() []string {
+	out := make([]string, 0)
+	for _, origin := range strings.Split(config.GetConfigValue("CSRF_TRUSTED_ORIGINS"), ",") {
+		if origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/")); origin != "" {
+			out = append(out, origin)
+		}
+	}
+	return out
+}
+
+// Reads the CSRF cookie value, or "" when absent
+func TokenFromCookie(req *http.Request) string {
+	cookie, err := req.Cookie(COOKIE_NAME)
+	if err != nil { return "" }
+	return cookie.Value
+}
+
+// Sets the CSRF cookie used for the double-submit comparison
+func SetCookie(wtr http.ResponseWriter, token string) {
+	http.SetCookie(wtr, &http.Cookie{
+		Name:     COOKIE_NAME,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(TTL().Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Maximum token age (CSRF_TOKEN_TTL_SEC)
+func TTL() time.Duration {
+	if sec, err := strconv.Atoi(strings.TrimSpace(config.GetConfigValue("CSRF_TOKEN_TTL_SEC"))); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return DEFAULT_TTL_SEC * time.Second
+}
+
+func sign(sessionID string, issued string, nonce string) string {
+	if sessionID == "" { sessionID = anonymousBinding }
+
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(sessionID + "|" + issued + "|" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// HMAC key from CSRF_SECRET. Without one a random per-process key is used, which only suits
+// single-instance development since other instances can't verify the tokens.
+func signingKey() []byte {
+	secretOnce.Do(func() {
+		if configured := config.GetConfigValue("CSRF_SECRET"); configured != "" {
+			secret = []byte(configured)
+			return
+		}
+		logger.Warn("CSRF_SECRET not configured, using a per-process csrf key")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	})
+	return secret
+}
+
+func requestOrigin(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil || strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https") { scheme = "https" }
+	return strings.ToLower(scheme + "://" + req.Host)
+}
@@ -0,0 +1,53 @@
+package csrf
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenBoundToSession(t *testing.T) {
+	token, err := Issue("session-a")
+	if err != nil { t.Fatalf("issue: %v", err) }
+	if !WellFormed(token) { t.Fatalf("issued token %q is not well formed", token) }
+
+	if err := Verify(token, "session-a"); err != nil {
+		t.Errorf("verify for issuing session: %v", err)
+	}
+	if err := Verify(token, "session-b"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("verify for another session: err = %v, want ErrInvalidToken", err)
+	}
+	if err := Verify(token, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("verify without session: err = %v, want ErrInvalidToken", err)
+	}
+	if WellFormed("any-well-formed-string") {
+		t.Error("arbitrary strings must not pass the shape check")
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	t.Setenv("CSRF_TRUSTED_ORIGINS", "http://localhost:7001, https://shop.komodo.dev")
+
+	cases := []struct {
+		origin  string
+		referer string
+		allowed bool
+	}{
+		{"http://localhost:7001", "", true},
+		{"https://SHOP.komodo.dev", "", true},
+		{"https://evil.example", "", false},
+		{"", "https://shop.komodo.dev/cart", true},
+		{"", "https://evil.example/page", false},
+		{"null", "", false},
+		{"", "", true}, // left to the token check
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "http://api.komodo.dev/me/orders", nil)
+		if tc.origin != "" { req.Header.Set("Origin", tc.origin) }
+		if tc.referer != "" { req.Header.Set("Referer", tc.referer) }
+
+		if err := CheckOrigin(req); (err == nil) != tc.allowed {
+			t.Errorf("origin %q referer %q: err = %v, allowed = %v", tc.origin, tc.referer, err, tc.allowed)
+		}
+	}
+}
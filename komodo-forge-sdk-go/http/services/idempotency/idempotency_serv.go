@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"komodo-forge-sdk-go/config"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StateInProgress = "in_progress"
+	StateCompleted  = "completed"
+)
+
+const (
+	DEFAULT_TTL_SEC      = 300 // how long completed responses are replayed
+	DEFAULT_LOCK_TTL_SEC = 30  // how long an unfinished request holds its key
+)
+
+// Stored outcome of the first request made with an idempotency key
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	State       string      `json:"state"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+type Store interface {
+	// Claims the key with an in-progress record. Returns the existing record (nil when claimed).
+	Begin(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*Record, error)
+	// Replaces the in-progress record with the captured response
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Frees the key so the request can be retried (e.g. after a server error)
+	Release(ctx context.Context, key string) error
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// Returns the process-wide store: Redis in prod/staging, in-memory otherwise
+func Default() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		switch strings.ToLower(config.GetConfigValue("ENV")) {
+			case "prod", "staging":
+				store = NewRedisStore("idem:")
+			default:
+				store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Overrides the process-wide store (tests, or services wiring their own backend)
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Identifies a request by method, route, body hash and user so a reused key with a different payload is detected
+func Fingerprint(method string, route string, body []byte, userID string) string {
+	bodySum := sha256.Sum256(body)
+
+	h := sha256.New()
+	for _, part := range []string{method, route, hex.EncodeToString(bodySum[:]), userID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Replay window for completed responses (IDEMPOTENCY_TTL_SEC)
+func TTL() time.Duration { return config.GetSeconds("IDEMPOTENCY_TTL_SEC", DEFAULT_TTL_SEC) }
+
+// How long an in-flight request holds its key before others may retry (IDEMPOTENCY_LOCK_TTL_SEC)
+func LockTTL() time.Duration { return config.GetSeconds("IDEMPOTENCY_LOCK_TTL_SEC", DEFAULT_LOCK_TTL_SEC) }
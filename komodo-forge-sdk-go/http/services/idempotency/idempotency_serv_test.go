@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if existing, err := store.Begin(ctx, "u1:key", "fp-a", time.Minute); err != nil || existing != nil {
+		t.Fatalf("first claim: existing %v, err %v", existing, err)
+	}
+
+	// A second claim sees the in-flight record instead of claiming again
+	existing, err := store.Begin(ctx, "u1:key", "fp-a", time.Minute)
+	if err != nil || existing == nil || existing.State != StateInProgress {
+		t.Fatalf("claim while in flight: existing %+v, err %v", existing, err)
+	}
+
+	rec := Record{Fingerprint: "fp-a", Status: http.StatusCreated, Body: []byte(`{"ok":true}`)}
+	if err := store.Complete(ctx, "u1:key", rec, time.Minute); err != nil { t.Fatalf("complete: %v", err) }
+
+	existing, _ = store.Begin(ctx, "u1:key", "fp-a", time.Minute)
+	if existing == nil || existing.State != StateCompleted || existing.Status != http.StatusCreated {
+		t.Fatalf("claim after completion: existing %+v", existing)
+	}
+
+	// Released keys can be claimed again
+	store.Release(ctx, "u1:key")
+	if existing, _ := store.Begin(ctx, "u1:key", "fp-b", time.Minute); existing != nil {
+		t.Errorf("claim after release: existing %+v", existing)
+	}
+}
+
+func TestMemoryStoreLockExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	store.Begin(ctx, "u1:key", "fp-a", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// A crashed request's claim lapses so the client isn't stuck on 409 forever
+	if existing, _ := store.Begin(ctx, "u1:key", "fp-a", time.Minute); existing != nil {
+		t.Errorf("claim after lock expiry: existing %+v", existing)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("POST", "/me/orders", []byte(`{"sku":"A"}`), "u1")
+	if base != Fingerprint("POST", "/me/orders", []byte(`{"sku":"A"}`), "u1") {
+		t.Fatal("fingerprint is not deterministic")
+	}
+
+	variants := []string{
+		Fingerprint("PUT", "/me/orders", []byte(`{"sku":"A"}`), "u1"),
+		Fingerprint("POST", "/me/orders/create", []byte(`{"sku":"A"}`), "u1"),
+		Fingerprint("POST", "/me/orders", []byte(`{"sku":"B"}`), "u1"),
+		Fingerprint("POST", "/me/orders", []byte(`{"sku":"A"}`), "u2"),
+	}
+	for i, fp := range variants {
+		if fp == base { t.Errorf("variant %d has the same fingerprint as the original request", i) }
+	}
+}
@@ -0,0 +1,65 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	rec     Record
+	expires time.Time
+}
+
+// Process-local store for development and single-instance deployments
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key string, fingerprint string, lockTTL time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+
+	if entry, ok := s.entries[key]; ok {
+		existing := entry.rec
+		return &existing, nil
+	}
+
+	s.entries[key] = memoryEntry{
+		rec:     Record{Fingerprint: fingerprint, State: StateInProgress, CreatedAt: now},
+		expires: now.Add(lockTTL),
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec.State = StateCompleted
+	s.entries[key] = memoryEntry{rec: rec, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Drops expired entries; called under the lock on every claim so the map can't grow unbounded
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.expires) { delete(s.entries, key) }
+	}
+}
@@ -0,0 +1,51 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"time"
+)
+
+// Distributed store on the shared Elasticache client; records are JSON under prefix+key
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore { return &RedisStore{prefix: prefix} }
+
+func (s *RedisStore) Begin(_ context.Context, key string, fingerprint string, lockTTL time.Duration) (*Record, error) {
+	claim, err := json.Marshal(Record{Fingerprint: fingerprint, State: StateInProgress, CreatedAt: time.Now()})
+	if err != nil { return nil, err }
+
+	// A record may expire between SETNX and GET, so retry the claim once
+	for range 2 {
+		stored, err := elasticache.SetNX(s.prefix+key, string(claim), elasticache.TTLSeconds(lockTTL))
+		if err != nil { return nil, err }
+		if stored { return nil, nil }
+
+		raw, err := elasticache.Get(s.prefix + key)
+		if err != nil { return nil, err }
+		if raw == "" { continue }
+
+		var existing Record
+		if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+			return nil, fmt.Errorf("corrupt idempotency record for %s: %w", key, err)
+		}
+		return &existing, nil
+	}
+	return nil, fmt.Errorf("failed to claim idempotency key %s", key)
+}
+
+func (s *RedisStore) Complete(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	rec.State = StateCompleted
+
+	data, err := json.Marshal(rec)
+	if err != nil { return err }
+	return elasticache.Set(s.prefix+key, string(data), elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) Release(_ context.Context, key string) error {
+	return elasticache.Delete(s.prefix + key)
+}
@@ -0,0 +1,53 @@
+package ipAccess
+
+import (
+	"context"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Strike reasons reported by middleware
+const (
+	StrikeRateLimit      = "rate_limit"
+	StrikeRuleValidation = "rule_validation"
+)
+
+const (
+	DEFAULT_AUTO_BAN_WINDOW_SEC   = 60
+	DEFAULT_AUTO_BAN_COOLDOWN_SEC = 900
+)
+
+// Counts a strike against ip. After AUTO_BAN_STRIKES strikes within AUTO_BAN_WINDOW_SEC the ip is
+// blacklisted for AUTO_BAN_COOLDOWN_SEC. Disabled while AUTO_BAN_STRIKES is unset or 0.
+// Whitelisted IPs are never banned.
+func RecordStrike(ctx context.Context, client string, reason string) {
+	threshold, _ := strconv.Atoi(strings.TrimSpace(config.GetConfigValue("AUTO_BAN_STRIKES")))
+	if threshold <= 0 { return }
+
+	ip := net.ParseIP(client)
+	if ip == nil { return }
+
+	current := Default()
+	if _, ok := current.Lookup(ListWhitelist, ip); ok { return }
+	if _, ok := current.Lookup(ListBlacklist, ip); ok { return }
+
+	window := config.GetSeconds("AUTO_BAN_WINDOW_SEC", DEFAULT_AUTO_BAN_WINDOW_SEC)
+	count, err := DefaultStore().Strike(ctx, ip.String(), window)
+	if err != nil {
+		logger.Error("failed to record strike for client ip: "+ip.String(), err)
+		return
+	}
+	if count < threshold { return }
+
+	cooldown := config.GetSeconds("AUTO_BAN_COOLDOWN_SEC", DEFAULT_AUTO_BAN_COOLDOWN_SEC)
+	detail := fmt.Sprintf("%d strikes within %s (last: %s)", count, window, reason)
+	if _, err := Add(ctx, ListBlacklist, ip.String(), cooldown, detail, SourceAutoBan); err != nil {
+		logger.Error("failed to auto-ban client ip: "+ip.String(), err)
+		return
+	}
+	logger.Warn(fmt.Sprintf("auto-banned client ip %s for %s: %s", ip, cooldown, detail))
+}
@@ -1,34 +1,210 @@
 package ipAccess
 
 import (
+	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Lists contains parsed whitelist/blacklist IPs and CIDR networks.
+const (
+	ListWhitelist = "whitelist"
+	ListBlacklist = "blacklist"
+)
+
+// Where an entry came from; configuration entries can only be changed by redeploying
+const (
+	SourceConfig  = "config"
+	SourceAdmin   = "admin"
+	SourceAutoBan = "auto_ban"
+)
+
+// A single IP or CIDR range on a list
+type Entry struct {
+	CIDR      string     `json:"cidr"`
+	List      string     `json:"list"`
+	Source    string     `json:"source"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // nil never expires
+}
+
+func (e *Entry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Lists holds the whitelist and blacklist as CIDR tries.
 type Lists struct {
-	WhitelistIPs  []net.IP
-	WhitelistNets []*net.IPNet
-	BlacklistIPs  []net.IP
-	BlacklistNets []*net.IPNet
+	mu        sync.RWMutex
+	whitelist *Trie
+	blacklist *Trie
+	// Set while IP_WHITELIST has entries; dynamic whitelist entries alone never deny anyone
+	restricted bool
+}
+
+func NewLists() *Lists {
+	return &Lists{whitelist: NewTrie(), blacklist: NewTrie()}
 }
 
 // Returns true if the ip is allowed according to the provided lists.
+// Unexpired whitelist entries always allow and skip the blacklist; only a configured IP_WHITELIST
+// denies everyone else, so an admin allow entry can't lock out other clients.
 func Evaluate(ip net.IP, lists *Lists) bool {
 	if lists == nil { return true }
 
-	// If whitelist present, only allow those entries
-	if len(lists.WhitelistIPs) > 0 || len(lists.WhitelistNets) > 0 {
-		return ipInList(ip, lists.WhitelistIPs, lists.WhitelistNets)
-	}
+	lists.mu.RLock()
+	defer lists.mu.RUnlock()
+	now := time.Now()
+
+	if _, ok := lists.whitelist.Lookup(ip, now); ok { return true }
+	if lists.restricted { return false }
 
 	// If blacklisted, deny
-	if ipInList(ip, lists.BlacklistIPs, lists.BlacklistNets) {
-		return false
+	_, banned := lists.blacklist.Lookup(ip, now)
+	return !banned
+}
+
+// Returns the most specific unexpired entry on list covering ip
+func (l *Lists) Lookup(list string, ip net.IP) (Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	trie := l.trie(list)
+	if trie == nil { return Entry{}, false }
+	return trie.Lookup(ip, time.Now())
+}
+
+// Adds or replaces an entry; dynamic entries can't replace a configuration entry for the same range
+func (l *Lists) Add(entry Entry) error {
+	network, err := ParseCIDR(entry.CIDR)
+	if err != nil { return err }
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	trie := l.trie(entry.List)
+	if trie == nil { return fmt.Errorf("%w: %q", ErrUnknownList, entry.List) }
+
+	entry.CIDR = network.String()
+	if entry.Source != SourceConfig && configured(trie, entry.CIDR) {
+		return fmt.Errorf("%w: %s", ErrManagedByConfig, entry.CIDR)
 	}
+	trie.Insert(network, entry)
+	if entry.List == ListWhitelist && entry.Source == SourceConfig { l.restricted = true }
+	return nil
+}
+
+// Reports whether exactly cidr is listed on list by configuration
+func (l *Lists) Configured(list string, cidr string) bool {
+	network, err := ParseCIDR(cidr)
+	if err != nil { return false }
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	trie := l.trie(list)
+	return trie != nil && configured(trie, network.String())
+}
+
+// Removes the entry for exactly cidr; reports whether one existed
+func (l *Lists) Remove(list string, cidr string) bool {
+	network, err := ParseCIDR(cidr)
+	if err != nil { return false }
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	trie := l.trie(list)
+	if trie == nil || !trie.Remove(network) { return false }
+	if list == ListWhitelist { l.restricted = len(staticEntries(trie, time.Now())) > 0 }
 	return true
 }
 
+// Returns the unexpired entries on list
+func (l *Lists) Entries(list string) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	trie := l.trie(list)
+	if trie == nil { return nil }
+	return trie.Entries(time.Now())
+}
+
+// Swaps the dynamic entries on list for the given set, keeping configuration entries
+func (l *Lists) Replace(list string, dynamic []Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.trie(list)
+	if old == nil { return }
+
+	next := NewTrie()
+	now := time.Now()
+	static := staticEntries(old, now)
+	for _, entry := range static {
+		if network, err := ParseCIDR(entry.CIDR); err == nil { next.Insert(network, entry) }
+	}
+	for _, entry := range dynamic {
+		network, err := ParseCIDR(entry.CIDR)
+		if err != nil || entry.expired(now) { continue }
+
+		// A stored entry for a configured range (e.g. saved before it was configured) must not displace it
+		if configured(next, network.String()) { continue }
+		next.Insert(network, entry)
+	}
+
+	if list == ListWhitelist {
+		l.whitelist = next
+		l.restricted = len(staticEntries(next, now)) > 0
+	} else {
+		l.blacklist = next
+	}
+}
+
+func (l *Lists) trie(list string) *Trie {
+	switch list {
+		case ListWhitelist:
+			return l.whitelist
+		case ListBlacklist:
+			return l.blacklist
+	}
+	return nil
+}
+
+// Reports whether cidr has a configuration entry of its own on trie
+func configured(trie *Trie, cidr string) bool {
+	for _, entry := range staticEntries(trie, time.Now()) {
+		if entry.CIDR == cidr { return true }
+	}
+	return false
+}
+
+func staticEntries(trie *Trie, now time.Time) []Entry {
+	var out []Entry
+	for _, entry := range trie.Entries(now) {
+		if entry.Source == SourceConfig { out = append(out, entry) }
+	}
+	return out
+}
+
+// Parses a single IP (as a host-sized network) or a CIDR range
+func ParseCIDR(raw string) (*net.IPNet, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.Contains(raw, "/") {
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil { return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, raw) }
+		return network, nil
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil { return nil, fmt.Errorf("%w: %q", ErrInvalidAddress, raw) }
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
 func ipInList(ip net.IP, ips []net.IP, nets []*net.IPNet) bool {
 	for _, a := range ips {
 		if a.Equal(ip) { return true }
@@ -63,9 +239,19 @@ func ParseList(raw string) (ips []net.IP, nets []*net.IPNet) {
 	return ips, nets
 }
 
-// Parses both whitelist and blacklist raw strings and returns a fully populated Lists struct.
+// Parses both whitelist and blacklist raw strings into Lists of configuration entries.
 func ParseLists(whitelistRaw, blacklistRaw string) *Lists {
-	wlIPs, wlNets := ParseList(whitelistRaw)
-	blIPs, blNets := ParseList(blacklistRaw)
-	return &Lists{WhitelistIPs: wlIPs, WhitelistNets: wlNets, BlacklistIPs: blIPs, BlacklistNets: blNets}
+	lists := NewLists()
+	now := time.Now()
+
+	for list, raw := range map[string]string{ListWhitelist: whitelistRaw, ListBlacklist: blacklistRaw} {
+		ips, nets := ParseList(raw)
+		for _, ip := range ips {
+			lists.Add(Entry{CIDR: ip.String(), List: list, Source: SourceConfig, CreatedAt: now})
+		}
+		for _, network := range nets {
+			lists.Add(Entry{CIDR: network.String(), List: list, Source: SourceConfig, CreatedAt: now})
+		}
+	}
+	return lists
 }
@@ -1 +1,127 @@
 package ipAccess
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListsMatchMostSpecificUnexpiredEntry(t *testing.T) {
+	lists := ParseLists("", "198.51.100.0/24, 2001:db8::/32")
+	expired := time.Now().Add(-time.Second)
+	lists.Add(Entry{CIDR: "203.0.113.5", List: ListBlacklist, Source: SourceAdmin, ExpiresAt: &expired})
+
+	for ip, want := range map[string]bool{
+		"198.51.100.77": false,
+		"198.51.101.1":  true,
+		"2001:db8::42":  false,
+		"203.0.113.5":   true, // ban has expired
+	} {
+		if got := Evaluate(net.ParseIP(ip), lists); got != want {
+			t.Errorf("Evaluate(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	lists.Replace(ListBlacklist, nil)
+	if got := len(lists.Entries(ListBlacklist)); got != 2 {
+		t.Errorf("after Replace: %d entries, want the 2 configuration entries", got)
+	}
+}
+
+func TestDynamicWhitelistOnlyBypassesBlacklist(t *testing.T) {
+	lists := ParseLists("", "198.51.100.0/24")
+	lists.Add(Entry{CIDR: "198.51.100.7", List: ListWhitelist, Source: SourceAdmin})
+	expired := time.Now().Add(-time.Second)
+	lists.Add(Entry{CIDR: "192.0.2.0/24", List: ListWhitelist, Source: SourceAdmin, ExpiresAt: &expired})
+
+	for ip, want := range map[string]bool{
+		"198.51.100.7": true, // allowed despite the blacklisted range
+		"198.51.100.8": false,
+		"203.0.113.9":  true, // no IP_WHITELIST, so unlisted clients still get in
+		"192.0.2.1":    true,
+	} {
+		if got := Evaluate(net.ParseIP(ip), lists); got != want {
+			t.Errorf("without IP_WHITELIST: Evaluate(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	restricted := ParseLists("10.0.0.0/8", "")
+	restricted.Add(Entry{CIDR: "203.0.113.9", List: ListWhitelist, Source: SourceAdmin})
+	for ip, want := range map[string]bool{
+		"10.1.2.3":    true,
+		"203.0.113.9": true,
+		"192.0.2.1":   false,
+	} {
+		if got := Evaluate(net.ParseIP(ip), restricted); got != want {
+			t.Errorf("with IP_WHITELIST: Evaluate(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	// Syncing dynamic entries away keeps the configured whitelist in force
+	restricted.Replace(ListWhitelist, nil)
+	if Evaluate(net.ParseIP("192.0.2.1"), restricted) || Evaluate(net.ParseIP("203.0.113.9"), restricted) {
+		t.Error("unlisted client allowed after the dynamic entries were replaced")
+	}
+}
+
+func TestDynamicEntriesCannotReplaceConfiguration(t *testing.T) {
+	t.Setenv("IP_WHITELIST", "10.0.0.1")
+	t.Setenv("IP_BLACKLIST", "198.51.100.0/24")
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	if _, err := Add(ctx, ListWhitelist, "10.0.0.1", time.Hour, "", SourceAdmin); !errors.Is(err, ErrManagedByConfig) {
+		t.Fatalf("admin entry over a configured range: err = %v, want ErrManagedByConfig", err)
+	}
+
+	// Entries already in the store (saved before the range was configured) are ignored on sync
+	for _, entry := range []Entry{
+		{CIDR: "10.0.0.1/32", List: ListWhitelist, Source: SourceAdmin, CreatedAt: time.Now()},
+		{CIDR: "198.51.100.0/24", List: ListBlacklist, Source: SourceAdmin, CreatedAt: time.Now()},
+	} {
+		expires := time.Now().Add(time.Hour)
+		entry.ExpiresAt = &expires
+		if err := DefaultStore().Save(ctx, entry); err != nil { t.Fatalf("save: %v", err) }
+	}
+	if err := Sync(ctx); err != nil { t.Fatalf("sync: %v", err) }
+
+	if Evaluate(net.ParseIP("8.8.8.8"), Default()) {
+		t.Error("configured whitelist stopped denying unlisted clients after a sync")
+	}
+	for list, ip := range map[string]string{ListWhitelist: "10.0.0.1", ListBlacklist: "198.51.100.9"} {
+		if entry, ok := Default().Lookup(list, net.ParseIP(ip)); !ok || entry.Source != SourceConfig || entry.ExpiresAt != nil {
+			t.Errorf("%s %s: got %+v (found %v), want the permanent configuration entry", list, ip, entry, ok)
+		}
+	}
+}
+
+func TestAutoBanAndRemove(t *testing.T) {
+	t.Setenv("AUTO_BAN_STRIKES", "3")
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	for range 2 { RecordStrike(ctx, "192.0.2.44", StrikeRateLimit) }
+	if !Evaluate(net.ParseIP("192.0.2.44"), Default()) {
+		t.Fatal("banned before reaching the strike threshold")
+	}
+
+	RecordStrike(ctx, "192.0.2.44", StrikeRuleValidation)
+	entry, banned := Default().Lookup(ListBlacklist, net.ParseIP("192.0.2.44"))
+	if !banned || entry.Source != SourceAutoBan || entry.ExpiresAt == nil {
+		t.Fatalf("expected an expiring auto-ban, got %+v (banned=%v)", entry, banned)
+	}
+
+	if err := Remove(ctx, ListBlacklist, "192.0.2.44"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if !Evaluate(net.ParseIP("192.0.2.44"), Default()) {
+		t.Error("still banned after removal")
+	}
+	if err := Remove(ctx, ListBlacklist, "192.0.2.44"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("second remove: %v, want ErrEntryNotFound", err)
+	}
+}
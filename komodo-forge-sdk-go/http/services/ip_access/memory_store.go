@@ -0,0 +1,71 @@
+package ipAccess
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// In-process store for local and test environments; entries are not shared between instances
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]Entry // list -> cidr -> entry
+	strikes map[string]strikeWindow
+}
+
+type strikeWindow struct {
+	count   int
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]map[string]Entry), strikes: make(map[string]strikeWindow)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries[entry.List] == nil { s.entries[entry.List] = make(map[string]Entry) }
+	s.entries[entry.List][entry.CIDR] = entry
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, list string, cidr string) error {
+	s.mu.Lock()
+	delete(s.entries[list], cidr)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, list string) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var out []Entry
+	for cidr, entry := range s.entries[list] {
+		if entry.expired(now) {
+			delete(s.entries[list], cidr)
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Strike(_ context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, w := range s.strikes {
+		if now.After(w.expires) { delete(s.strikes, k) }
+	}
+
+	w, ok := s.strikes[key]
+	if !ok { w.expires = now.Add(window) }
+	w.count++
+	s.strikes[key] = w
+	return w.count, nil
+}
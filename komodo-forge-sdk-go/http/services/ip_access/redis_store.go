@@ -0,0 +1,63 @@
+package ipAccess
+
+import (
+	"context"
+	"encoding/json"
+	"komodo-forge-sdk-go/aws/elasticache"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"time"
+)
+
+// Distributed store on the shared Elasticache client: one hash per list (cidr -> JSON entry)
+// and a counter per striking client
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore { return &RedisStore{prefix: prefix} }
+
+func (s *RedisStore) Save(_ context.Context, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil { return err }
+	return elasticache.HSet(s.prefix+entry.List, entry.CIDR, string(data))
+}
+
+func (s *RedisStore) Delete(_ context.Context, list string, cidr string) error {
+	return elasticache.HDel(s.prefix+list, cidr)
+}
+
+func (s *RedisStore) Load(_ context.Context, list string) ([]Entry, error) {
+	fields, err := elasticache.HGetAll(s.prefix + list)
+	if err != nil { return nil, err }
+
+	now := time.Now()
+	var out []Entry
+	var stale []string
+	for cidr, raw := range fields {
+		var entry Entry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			logger.Error("dropping corrupt ip access entry "+cidr, err)
+			stale = append(stale, cidr)
+			continue
+		}
+		if entry.expired(now) {
+			stale = append(stale, cidr)
+			continue
+		}
+		out = append(out, entry)
+	}
+
+	// Hash fields can't expire on their own, so whoever loads them cleans up
+	if len(stale) > 0 {
+		if err := elasticache.HDel(s.prefix+list, stale...); err != nil {
+			logger.Error("failed to prune expired ip access entries", err)
+		}
+	}
+	return out, nil
+}
+
+func (s *RedisStore) Strike(_ context.Context, key string, window time.Duration) (int, error) {
+	sec := int64((window + time.Second - 1) / time.Second)
+	count, err := elasticache.Incr(s.prefix+"strikes:"+key, max(sec, 1))
+	return int(count), err
+}
@@ -0,0 +1,84 @@
+package ipAccess
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolves the real client IP behind a chain of trusted proxies.
+// Forwarding headers are only honoured when the direct peer is a trusted proxy, and hops are walked
+// from the right so a client can't spoof its address by prepending entries.
+type Resolver struct {
+	trustedIPs  []net.IP
+	trustedNets []*net.IPNet
+}
+
+// Builds a resolver from a comma-separated list of trusted proxy IPs or CIDR ranges.
+// With an empty list forwarding headers are ignored and the peer address is used.
+func NewResolver(trustedRaw string) *Resolver {
+	ips, nets := ParseList(trustedRaw)
+	return &Resolver{trustedIPs: ips, trustedNets: nets}
+}
+
+// Returns true if ip belongs to a trusted proxy
+func (r *Resolver) Trusted(ip net.IP) bool {
+	if r == nil { return false }
+	return ipInList(ip, r.trustedIPs, r.trustedNets)
+}
+
+// Returns the client IP for the request, or nil if the peer address can't be parsed.
+// RFC 7239 Forwarded takes precedence over X-Forwarded-For when both are present.
+func (r *Resolver) Resolve(req *http.Request) net.IP {
+	peer := parseHop(req.RemoteAddr)
+	if peer == nil || !r.Trusted(peer) { return peer }
+
+	hops := forwardedFor(req.Header.Values("Forwarded"))
+	if len(hops) == 0 {
+		hops = splitHops(req.Header.Values("X-Forwarded-For"))
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHop(hops[i])
+		// An unknown or obfuscated hop ends the chain; the last proxy that reported it is the best we know
+		if ip == nil { return client }
+
+		client = ip
+		if !r.Trusted(ip) { return client }
+	}
+	return client
+}
+
+// Collects the for= parameter of every Forwarded element, in order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, elem := range splitHops(values) {
+		for pair := range strings.SplitSeq(elem, ";") {
+			name, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(name, "for") { continue }
+			hops = append(hops, strings.Trim(val, `"`))
+		}
+	}
+	return hops
+}
+
+// Flattens repeated header lines into a single list of comma-separated hops
+func splitHops(values []string) []string {
+	var hops []string
+	for _, val := range values {
+		for hop := range strings.SplitSeq(val, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" { hops = append(hops, hop) }
+		}
+	}
+	return hops
+}
+
+// Parses "ip", "ip:port", "[v6]" or "[v6]:port"
+func parseHop(hop string) net.IP {
+	hop = strings.TrimSpace(hop)
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	return net.ParseIP(strings.Trim(hop, "[]"))
+}
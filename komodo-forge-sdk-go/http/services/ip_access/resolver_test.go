@@ -0,0 +1,36 @@
+package ipAccess
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolverWalksTrustedChainFromTheRight(t *testing.T) {
+	resolver := NewResolver("10.0.0.0/8, 192.0.2.10")
+
+	tests := []struct {
+		name      string
+		peer      string
+		xff       string
+		forwarded string
+		want      string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:4000", "198.51.100.1", "", "203.0.113.9"},
+		{"spoofed leftmost hop", "10.0.0.5:4000", "1.2.3.4, 198.51.100.7, 10.0.0.8", "", "198.51.100.7"},
+		{"all hops trusted", "192.0.2.10:4000", "10.1.1.1, 10.2.2.2", "", "10.1.1.1"},
+		{"no forwarding headers", "10.0.0.5:4000", "", "", "10.0.0.5"},
+		{"forwarded wins over xff", "10.0.0.5:4000", "1.2.3.4", `for=198.51.100.7;proto=https, for="[2001:db8::1]:8443"`, "2001:db8::1"},
+		{"obfuscated hop stops the walk", "10.0.0.5:4000", "", "for=198.51.100.7, for=_hidden, for=10.0.0.9", "10.0.0.9"},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.peer
+		if tc.xff != "" { req.Header.Set("X-Forwarded-For", tc.xff) }
+		if tc.forwarded != "" { req.Header.Set("Forwarded", tc.forwarded) }
+
+		if got := resolver.Resolve(req); got.String() != tc.want {
+			t.Errorf("%s: got %v, want %s", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package ipAccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_SYNC_SEC = 15 // how often instances pull dynamic entries from the store
+
+var (
+	ErrUnknownList     = errors.New("unknown ip access list")
+	ErrInvalidAddress  = errors.New("invalid IP or CIDR")
+	ErrEntryNotFound   = errors.New("ip access entry not found")
+	ErrManagedByConfig = errors.New("entry is managed by configuration")
+)
+
+// Persists dynamic (admin and auto-ban) entries so every instance enforces them
+type Store interface {
+	Save(ctx context.Context, entry Entry) error
+	Delete(ctx context.Context, list string, cidr string) error
+	// Returns the unexpired entries on list, pruning expired ones
+	Load(ctx context.Context, list string) ([]Entry, error)
+	// Counts a strike against key and returns the total within the window
+	Strike(ctx context.Context, key string, window time.Duration) (int, error)
+}
+
+var (
+	store    Store
+	lists    *Lists
+	storeMu  sync.Mutex
+	syncOnce sync.Once
+)
+
+// Backend for dynamic entries and strike counters: Redis in prod/staging, memory otherwise
+func DefaultStore() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return defaultStoreLocked()
+}
+
+func defaultStoreLocked() Store {
+	if store == nil {
+		switch strings.ToLower(config.GetConfigValue("ENV")) {
+			case "prod", "staging":
+				store = NewRedisStore("ipaccess:")
+			default:
+				store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Replaces the dynamic-entry backend; the cached lists are dropped and rebuilt from it on next use
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	lists = nil
+	storeMu.Unlock()
+}
+
+// Returns the process-wide lists: IP_WHITELIST/IP_BLACKLIST from configuration plus dynamic entries
+// from the store, refreshed every IP_ACCESS_SYNC_SEC.
+func Default() *Lists {
+	storeMu.Lock()
+	created := lists == nil
+	if created {
+		lists = ParseLists(config.GetConfigValue("IP_WHITELIST"), config.GetConfigValue("IP_BLACKLIST"))
+	}
+	current := lists
+	storeMu.Unlock()
+
+	if created {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := Sync(ctx); err != nil {
+			logger.Error("failed to load dynamic ip access entries", err)
+		}
+		cancel()
+		syncOnce.Do(func() { go syncLoop() })
+	}
+	return current
+}
+
+// Pulls dynamic entries for both lists from the store
+func Sync(ctx context.Context) error {
+	storeMu.Lock()
+	s, current := defaultStoreLocked(), lists
+	storeMu.Unlock()
+	if current == nil { return nil }
+
+	for _, list := range []string{ListWhitelist, ListBlacklist} {
+		entries, err := s.Load(ctx, list)
+		if err != nil { return fmt.Errorf("failed to load %s: %w", list, err) }
+		current.Replace(list, entries)
+	}
+	return nil
+}
+
+func syncLoop() {
+	ticker := time.NewTicker(config.GetSeconds("IP_ACCESS_SYNC_SEC", DEFAULT_SYNC_SEC))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := Sync(ctx); err != nil {
+			logger.Error("failed to sync ip access lists", err)
+		}
+		cancel()
+	}
+}
+
+// Adds ip or cidr to list for ttl (0 never expires), persisting it so other instances pick it up.
+// Ranges already listed in configuration are rejected with ErrManagedByConfig.
+func Add(ctx context.Context, list string, cidr string, ttl time.Duration, reason string, source string) (Entry, error) {
+	if list != ListWhitelist && list != ListBlacklist { return Entry{}, ErrUnknownList }
+
+	network, err := ParseCIDR(cidr)
+	if err != nil { return Entry{}, err }
+
+	entry := Entry{CIDR: network.String(), List: list, Source: source, Reason: reason, CreatedAt: time.Now().UTC()}
+	if ttl > 0 {
+		expires := entry.CreatedAt.Add(ttl)
+		entry.ExpiresAt = &expires
+	}
+
+	current := Default()
+	if current.Configured(list, entry.CIDR) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrManagedByConfig, entry.CIDR)
+	}
+
+	if err := DefaultStore().Save(ctx, entry); err != nil { return Entry{}, err }
+	if err := current.Add(entry); err != nil { return Entry{}, err }
+
+	logger.Info(fmt.Sprintf("added %s to ip %s (source: %s)", entry.CIDR, list, source))
+	return entry, nil
+}
+
+// Removes a dynamic entry from list; configuration entries are rejected
+func Remove(ctx context.Context, list string, cidr string) error {
+	if list != ListWhitelist && list != ListBlacklist { return ErrUnknownList }
+
+	network, err := ParseCIDR(cidr)
+	if err != nil { return err }
+
+	current := Default()
+	existing, found := findEntry(current.Entries(list), network.String())
+	if !found { return ErrEntryNotFound }
+	if existing.Source == SourceConfig { return ErrManagedByConfig }
+
+	if err := DefaultStore().Delete(ctx, list, existing.CIDR); err != nil { return err }
+	current.Remove(list, existing.CIDR)
+
+	logger.Info(fmt.Sprintf("removed %s from ip %s", existing.CIDR, list))
+	return nil
+}
+
+// Returns the unexpired entries on list
+func List(list string) ([]Entry, error) {
+	if list != ListWhitelist && list != ListBlacklist { return nil, ErrUnknownList }
+	return Default().Entries(list), nil
+}
+
+func findEntry(entries []Entry, cidr string) (Entry, bool) {
+	for _, entry := range entries {
+		if entry.CIDR == cidr { return entry, true }
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,89 @@
+package ipAccess
+
+import (
+	"net"
+	"time"
+)
+
+// Binary prefix trie over 128-bit addresses (IPv4 is stored IPv4-mapped), so a lookup costs at most
+// 128 steps however many entries a list holds.
+type Trie struct {
+	root trieNode
+	size int
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	entry    *Entry
+}
+
+func NewTrie() *Trie { return &Trie{} }
+
+// Adds or replaces the entry for network
+func (t *Trie) Insert(network *net.IPNet, entry Entry) {
+	addr, bits := trieKey(network)
+	node := &t.root
+	for i := range bits {
+		b := bitAt(addr, i)
+		if node.children[b] == nil { node.children[b] = &trieNode{} }
+		node = node.children[b]
+	}
+	if node.entry == nil { t.size++ }
+	node.entry = &entry
+}
+
+// Removes the entry for exactly network; reports whether one existed
+func (t *Trie) Remove(network *net.IPNet) bool {
+	addr, bits := trieKey(network)
+	node := &t.root
+	for i := range bits {
+		if node = node.children[bitAt(addr, i)]; node == nil { return false }
+	}
+	if node.entry == nil { return false }
+	node.entry = nil
+	t.size--
+	return true
+}
+
+// Returns the most specific unexpired entry covering ip
+func (t *Trie) Lookup(ip net.IP, now time.Time) (Entry, bool) {
+	addr := ip.To16()
+	if addr == nil { return Entry{}, false }
+
+	var match *Entry
+	node := &t.root
+	for i := 0; node != nil; i++ {
+		if node.entry != nil && !node.entry.expired(now) { match = node.entry }
+		if i == 128 { break }
+		node = node.children[bitAt(addr, i)]
+	}
+	if match == nil { return Entry{}, false }
+	return *match, true
+}
+
+// Returns all unexpired entries
+func (t *Trie) Entries(now time.Time) []Entry {
+	var out []Entry
+	var walk func(*trieNode)
+	walk = func(node *trieNode) {
+		if node == nil { return }
+		if node.entry != nil && !node.entry.expired(now) { out = append(out, *node.entry) }
+		walk(node.children[0])
+		walk(node.children[1])
+	}
+	walk(&t.root)
+	return out
+}
+
+func (t *Trie) Len() int { return t.size }
+
+// Maps a network onto the 128-bit key space
+func trieKey(network *net.IPNet) (net.IP, int) {
+	ones, bits := network.Mask.Size()
+	if bits == 32 { ones += 96 }
+	return network.IP.To16(), ones
+}
+
+func bitAt(addr net.IP, i int) int {
+	return int(addr[i/8]>>(7-uint(i%8))) & 1
+}
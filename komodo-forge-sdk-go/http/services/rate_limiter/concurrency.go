@@ -0,0 +1,96 @@
+package rateLimiter
+
+import (
+	"context"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"komodo-forge-sdk-go/config"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DEFAULT_CONCURRENCY_LEASE_SEC = 120
+
+// Caps in-flight requests per key; suited to long calls where request rate says little about load
+type ConcurrencyLimiter interface {
+	// Takes a slot when one is free. release must be called once the request finishes.
+	Acquire(ctx context.Context, key string, max int) (release func(), dec Decision, err error)
+}
+
+var (
+	memoryConcurrency = &memorySlots{inFlight: make(map[string]int)}
+	redisConcurrency  = redisSlots{}
+)
+
+// Returns the shared concurrency limiter, distributed in prod/staging
+func Concurrency() ConcurrencyLimiter {
+	if isDistributed() { return redisConcurrency }
+	return memoryConcurrency
+}
+
+type memorySlots struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func (m *memorySlots) Acquire(_ context.Context, key string, max int) (func(), Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dec := Decision{Limit: max}
+	if m.inFlight[key] >= max {
+		dec.RetryAfter = time.Second
+		return func() {}, dec, nil
+	}
+
+	m.inFlight[key]++
+	dec.Allowed = true
+	dec.Remaining = max - m.inFlight[key]
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.inFlight[key]--; m.inFlight[key] <= 0 { delete(m.inFlight, key) }
+		})
+	}
+	return release, dec, nil
+}
+
+// Slots are leases in a Redis sorted set so a crashed instance can't hold them past the lease
+type redisSlots struct{}
+
+func (redisSlots) Acquire(ctx context.Context, key string, max int) (func(), Decision, error) {
+	id := newID()
+	acquired, remaining, err := elasticache.AcquireSlot(ctx, key, max, leaseDuration(), id)
+	if err != nil { return func() {}, Decision{Limit: max}, err }
+
+	dec := Decision{Allowed: acquired, Limit: max, Remaining: remaining}
+	if !acquired {
+		dec.RetryAfter = time.Second
+		return func() {}, dec, nil
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			// the request context may already be cancelled, so release on a fresh one
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := elasticache.ReleaseSlot(ctx, key, id); err != nil {
+				logger.Error("failed to release concurrency slot for: "+key, err)
+			}
+		})
+	}
+	return release, dec, nil
+}
+
+// Upper bound on how long a slot is held if never released (RATE_LIMIT_CONCURRENCY_LEASE_SEC)
+func leaseDuration() time.Duration {
+	if sec, err := strconv.Atoi(config.GetConfigValue("RATE_LIMIT_CONCURRENCY_LEASE_SEC")); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return DEFAULT_CONCURRENCY_LEASE_SEC * time.Second
+}
@@ -0,0 +1,66 @@
+package rateLimiter
+
+import (
+	"context"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"time"
+)
+
+// Generic cell rate algorithm: tracks a theoretical arrival time (TAT) per key instead of a token count.
+// Behaves like a token bucket with the same Rate and Burst but needs a single timestamp of state.
+type memoryGCRA struct {
+	tats *stateMap[time.Time]
+}
+
+func newMemoryGCRA() *memoryGCRA {
+	return &memoryGCRA{tats: newStateMap[time.Time]()}
+}
+
+func (g *memoryGCRA) AllowN(_ context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	if cost <= 0 { cost = 1 }
+	interval := emissionInterval(limit)
+	tolerance := time.Duration(float64(interval) * limit.Burst)
+	now := time.Now()
+	dec := Decision{Limit: int(limit.Burst)}
+
+	g.tats.with(key, now, func(tat *time.Time) time.Duration {
+		if tat.Before(now) { *tat = now }
+
+		newTAT := tat.Add(time.Duration(float64(interval) * cost))
+		if allowAt := newTAT.Add(-tolerance); allowAt.After(now) {
+			dec.RetryAfter = allowAt.Sub(now)
+		} else {
+			dec.Allowed = true
+			*tat = newTAT
+		}
+
+		dec.Reset = tat.Sub(now)
+		if interval > 0 {
+			dec.Remaining = max(int((tolerance-dec.Reset)/interval), 0)
+		}
+		return dec.Reset
+	})
+	return dec, nil
+}
+
+func (g *memoryGCRA) Reset(_ context.Context, key string) error {
+	g.tats.delete(key)
+	return nil
+}
+
+// GCRA shared across instances; the TAT is a single Redis string
+type redisGCRA struct{}
+
+func (redisGCRA) AllowN(ctx context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	if cost <= 0 { cost = 1 }
+	res, err := elasticache.GCRAAllow(ctx, key, emissionInterval(limit), limit.Burst, cost)
+	return decisionFrom(res, limit), err
+}
+
+func (redisGCRA) Reset(_ context.Context, key string) error { return elasticache.Delete(key) }
+
+// Time between evenly spaced requests at the limit's rate
+func emissionInterval(limit Limit) time.Duration {
+	if limit.Rate <= 0 { return time.Second }
+	return time.Duration(float64(time.Second) / limit.Rate)
+}
@@ -0,0 +1,55 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateAlgorithmsEnforceBurst(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for name, svc := range map[string]Service{
+		AlgorithmSlidingWindow: newMemorySlidingWindow(),
+		AlgorithmGCRA:          newMemoryGCRA(),
+	} {
+		for i := range 3 {
+			if dec, _ := svc.AllowN(ctx, "k", limit, 1); !dec.Allowed || dec.Remaining != 2-i {
+				t.Fatalf("%s request %d: allowed=%v remaining=%d", name, i, dec.Allowed, dec.Remaining)
+			}
+		}
+
+		dec, _ := svc.AllowN(ctx, "k", limit, 1)
+		if dec.Allowed || dec.RetryAfter <= 0 || dec.RetryAfter > 3*time.Second {
+			t.Errorf("%s 4th request: allowed=%v retryAfter=%v", name, dec.Allowed, dec.RetryAfter)
+		}
+
+		svc.Reset(ctx, "k")
+		if dec, _ := svc.AllowN(ctx, "k", limit, 1); !dec.Allowed {
+			t.Errorf("%s after reset: denied", name)
+		}
+	}
+}
+
+func TestConcurrencyReleasesSlots(t *testing.T) {
+	ctx := context.Background()
+	slots := &memorySlots{inFlight: make(map[string]int)}
+
+	release1, dec, _ := slots.Acquire(ctx, "checkout", 2)
+	if !dec.Allowed || dec.Remaining != 1 {
+		t.Fatalf("first acquire: allowed=%v remaining=%d", dec.Allowed, dec.Remaining)
+	}
+	release2, _, _ := slots.Acquire(ctx, "checkout", 2)
+
+	if _, dec, _ := slots.Acquire(ctx, "checkout", 2); dec.Allowed {
+		t.Fatal("third acquire allowed with 2 slots held")
+	}
+
+	release1()
+	release1() // releasing twice must not free a second slot
+	if _, dec, _ := slots.Acquire(ctx, "checkout", 2); !dec.Allowed || dec.Remaining != 0 {
+		t.Errorf("acquire after release: allowed=%v remaining=%d", dec.Allowed, dec.Remaining)
+	}
+	release2()
+}
@@ -0,0 +1,48 @@
+package rateLimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Mutex-guarded per-key limiter state that is evicted once idle past its expiry
+type stateMap[T any] struct {
+	mu        sync.Mutex
+	entries   map[string]*stateEntry[T]
+	lastSweep time.Time
+}
+
+type stateEntry[T any] struct {
+	val     T
+	expires time.Time
+}
+
+func newStateMap[T any]() *stateMap[T] {
+	return &stateMap[T]{entries: make(map[string]*stateEntry[T])}
+}
+
+// Runs fn on the key's state under the lock; fn returns how long the state stays relevant
+func (m *stateMap[T]) with(key string, now time.Time, fn func(val *T) time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, e := range m.entries {
+			if now.After(e.expires) { delete(m.entries, k) }
+		}
+		m.lastSweep = now
+	}
+
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &stateEntry[T]{}
+		m.entries[key] = entry
+	}
+	entry.expires = now.Add(fn(&entry.val))
+}
+
+func (m *stateMap[T]) delete(key string) {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+}
@@ -0,0 +1,206 @@
+package rateLimiter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpReq "komodo-forge-sdk-go/http/request"
+	"komodo-forge-sdk-go/http/route"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Principal kinds a policy can key its buckets on
+const (
+	PrincipalAuto   = "auto"    // user, then API key, then IP
+	PrincipalUser   = "user"    // JWT sub from USER_ID_KEY
+	PrincipalAPIKey = "api_key" // X-API-Key header (hashed)
+	PrincipalIP     = "ip"      // client IP
+)
+
+// Declarative limit for requests matching a route, method and client type.
+// Policies are checked in file order and the first match applies.
+type Policy struct {
+	Name        string   `yaml:"name"`
+	Route       string   `yaml:"route,omitempty"` // template like /item/{sku} or /oauth/*; empty matches all
+	Methods     []string `yaml:"methods,omitempty"`
+	ClientTypes []string `yaml:"clientTypes,omitempty"`
+	Principal   string   `yaml:"principal,omitempty"`
+	Algorithm   string   `yaml:"algorithm,omitempty"` // token_bucket (default) | sliding_window | gcra | concurrency
+	Rate        string   `yaml:"rate,omitempty"` // "<n>/s", "<n>/m" or "<n>/h"; a bare number is per second
+	Concurrency int      `yaml:"concurrency,omitempty"` // max in-flight requests, for the concurrency algorithm
+	Burst       float64  `yaml:"burst,omitempty"` // defaults to the per-period count
+	Cost        float64  `yaml:"cost,omitempty"`  // tokens per request, defaults to 1
+
+	limit    Limit
+	template *route.Template
+}
+
+type policyFile struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+var policies atomic.Pointer[[]Policy]
+
+// Loads policies from RATE_LIMIT_POLICIES_PATH; without it every request falls back to the global limit
+func LoadPoliciesFromConfig() error {
+	path := config.GetConfigValue("RATE_LIMIT_POLICIES_PATH")
+	if path == "" { return nil }
+	return LoadPolicies(path)
+}
+
+// Activates the rate limit policies in path, keeping the current ones if the file is rejected
+func LoadPolicies(path string) error {
+	parsed, err := route.LoadPolicyFile(path, "rate limit policies", ParsePolicies)
+	if err != nil { return err }
+
+	policies.Store(&parsed)
+	logger.Info(fmt.Sprintf("loaded %d rate limit policies from %s", len(parsed), path))
+	return nil
+}
+
+// Compiles rate limit policy yaml without activating it
+func ParsePolicies(data []byte) ([]Policy, error) {
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid rate limit policy yaml: %w", err)
+	}
+
+	for i := range file.Policies {
+		if err := file.Policies[i].compile(); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", file.Policies[i].Name, err)
+		}
+	}
+	return file.Policies, nil
+}
+
+// Returns the first policy matching the request, or the global default built from RATE_LIMIT_RPS/BURST
+func MatchPolicy(req *http.Request) *Policy {
+	if list := policies.Load(); list != nil {
+		clientType := clientTypeOf(req)
+
+		for i := range *list {
+			if p := &(*list)[i]; p.matches(req.Method, req.URL.Path, clientType) { return p }
+		}
+	}
+	return defaultPolicy()
+}
+
+func defaultPolicy() *Policy {
+	rpsVal, burstVal := rateConfig()
+	return &Policy{
+		Name: "default", Principal: PrincipalIP, Algorithm: AlgorithmTokenBucket, Cost: 1,
+		limit: Limit{Rate: rpsVal, Burst: burstVal},
+	}
+}
+
+func (p *Policy) Limit() Limit { return p.limit }
+
+// Returns the rate-based limiter for the policy's algorithm (nil for concurrency policies)
+func (p *Policy) Service() Service {
+	if p.Algorithm == AlgorithmConcurrency { return nil }
+	return ForAlgorithm(p.Algorithm)
+}
+
+// Builds the bucket key for the request's principal, scoped to the policy
+func (p *Policy) Key(req *http.Request) string {
+	kind, id := principalOf(req, p.Principal)
+	return p.Name + ":" + kind + ":" + id
+}
+
+func (p *Policy) compile() error {
+	if p.Name == "" { return fmt.Errorf("name is required") }
+
+	switch p.Principal {
+		case "":
+			p.Principal = PrincipalAuto
+		case PrincipalAuto, PrincipalUser, PrincipalAPIKey, PrincipalIP:
+		default:
+			return fmt.Errorf("unknown principal %q", p.Principal)
+	}
+
+	for i, m := range p.Methods { p.Methods[i] = strings.ToUpper(m) }
+
+	p.template = route.ParsePolicyRoute(p.Route)
+
+	switch p.Algorithm {
+		case "":
+			p.Algorithm = AlgorithmTokenBucket
+		case AlgorithmTokenBucket, AlgorithmSlidingWindow, AlgorithmGCRA:
+		case AlgorithmConcurrency:
+			if p.Concurrency < 1 { return fmt.Errorf("concurrency must be >= 1") }
+			return nil
+		default:
+			return fmt.Errorf("unknown algorithm %q", p.Algorithm)
+	}
+
+	count, period, err := parseRate(p.Rate)
+	if err != nil { return err }
+
+	if p.Burst <= 0 { p.Burst = count }
+	if p.Cost <= 0 { p.Cost = 1 }
+	if p.Cost > p.Burst {
+		return fmt.Errorf("cost %v exceeds burst %v", p.Cost, p.Burst)
+	}
+	p.limit = Limit{Rate: count / period.Seconds(), Burst: p.Burst}
+	return nil
+}
+
+func (p *Policy) matches(method string, path string, clientType string) bool {
+	if len(p.Methods) > 0 && !slices.Contains(p.Methods, method) { return false }
+	if len(p.ClientTypes) > 0 && !slices.Contains(p.ClientTypes, clientType) { return false }
+	return p.template.Match(path)
+}
+
+// Parses "<n>/<s|m|h>" (or a bare per-second number) into a count and period
+func parseRate(rate string) (float64, time.Duration, error) {
+	countStr, unit, hasUnit := strings.Cut(strings.TrimSpace(rate), "/")
+
+	count, err := strconv.ParseFloat(strings.TrimSpace(countStr), 64)
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q", rate)
+	}
+	if !hasUnit { return count, time.Second, nil }
+
+	switch strings.TrimSpace(unit) {
+		case "s", "sec", "second":
+			return count, time.Second, nil
+		case "m", "min", "minute":
+			return count, time.Minute, nil
+		case "h", "hour":
+			return count, time.Hour, nil
+		default:
+			return 0, 0, fmt.Errorf("invalid rate period in %q", rate)
+	}
+}
+
+func clientTypeOf(req *http.Request) string {
+	if ct, ok := req.Context().Value(ctxKeys.CLIENT_TYPE_KEY).(string); ok && ct != "" { return ct }
+	return httpReq.GetClientType(req)
+}
+
+// Resolves the principal kind and identifier; missing identities fall back to the client IP
+func principalOf(req *http.Request, kind string) (string, string) {
+	if kind == PrincipalUser || kind == PrincipalAuto {
+		if uid, ok := req.Context().Value(ctxKeys.USER_ID_KEY).(string); ok && uid != "" {
+			return PrincipalUser, uid
+		}
+	}
+	if kind == PrincipalAPIKey || kind == PrincipalAuto {
+		if apiKey := req.Header.Get("X-API-Key"); apiKey != "" {
+			// Raw keys never end up in bucket names or logs
+			sum := sha256.Sum256([]byte(apiKey))
+			return PrincipalAPIKey, hex.EncodeToString(sum[:8])
+		}
+	}
+	return PrincipalIP, httpReq.GetClientKey(req)
+}
@@ -0,0 +1,57 @@
+package rateLimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testPolicies = `
+policies:
+  - name: oauth-token
+    route: /oauth/token
+    methods: [post]
+    principal: ip
+    rate: 5/m
+    burst: 3
+  - name: item-detail
+    route: /item/{sku}
+    methods: [GET]
+    rate: 50/s
+    burst: 100
+`
+
+func TestPoliciesMatchAndLimit(t *testing.T) {
+	parsed, err := ParsePolicies([]byte(testPolicies))
+	if err != nil { t.Fatalf("parse: %v", err) }
+	policies.Store(&parsed)
+	defer policies.Store(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/oauth/token", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+
+	policy := MatchPolicy(req)
+	if policy.Name != "oauth-token" {
+		t.Fatalf("matched %q, want oauth-token", policy.Name)
+	}
+	if got := MatchPolicy(httptest.NewRequest(http.MethodGet, "/v2/item/ABC-1", nil)).Name; got != "item-detail" {
+		t.Errorf("item route matched %q, want item-detail", got)
+	}
+	if got := MatchPolicy(httptest.NewRequest(http.MethodGet, "/oauth/token", nil)).Name; got != "default" {
+		t.Errorf("GET /oauth/token matched %q, want default", got)
+	}
+
+	bkt := &bucket{created: time.Now()}
+	now := time.Now()
+	for i := range 3 {
+		if dec := bkt.take(policy.Limit(), policy.Cost, now); !dec.Allowed || dec.Remaining != 2-i {
+			t.Fatalf("request %d: allowed=%v remaining=%d", i, dec.Allowed, dec.Remaining)
+		}
+	}
+
+	dec := bkt.take(policy.Limit(), policy.Cost, now)
+	if dec.Allowed || dec.RetryAfter < 11*time.Second || dec.RetryAfter > 12*time.Second {
+		t.Errorf("4th request: allowed=%v retryAfter=%v, want denied with ~12s wait", dec.Allowed, dec.RetryAfter)
+	}
+}
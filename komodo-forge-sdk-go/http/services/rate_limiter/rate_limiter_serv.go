@@ -18,12 +18,34 @@ type bucket struct {
 	created time.Time
 }
 
+// Limiting algorithms a policy can choose
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmGCRA          = "gcra"
+	AlgorithmConcurrency   = "concurrency"
+)
+
+// Rate-based limiter; every algorithm has an in-process and an Elasticache implementation
 type Service interface {
-	Allow(ctx context.Context, key string) (allowed bool, wait time.Duration, err error)
-	GetUsage(ctx context.Context, key string) (used int, remaining int, reset time.Time, err error)
+	AllowN(ctx context.Context, key string, limit Limit, cost float64) (Decision, error)
 	Reset(ctx context.Context, key string) error
-	LoadConfig(cfg Config) error
-	ShouldFailOpen() bool
+}
+
+// Rate parameters shared by all algorithms: Rate units per second, at most Burst at once.
+// The sliding window reads this as Burst requests per Burst/Rate seconds.
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// Outcome of a limiter call, carrying what the RateLimit-* headers report
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration // until the limit is fully restored
 }
 
 type Config struct {
@@ -41,23 +63,78 @@ var (
 	evictOnce sync.Once
 )
 
-// Allow attempts to consume a token for the given client key
+// Allow attempts to consume a token for the given client key using the global limit
 func Allow(ctx context.Context, key string) (allowed bool, wait time.Duration, err error) {
-	env := strings.ToLower(config.GetConfigValue("ENV"))
+	rpsVal, burstVal := rateConfig()
+	dec, err := AllowN(ctx, key, Limit{Rate: rpsVal, Burst: burstVal}, 1)
+	return dec.Allowed, dec.RetryAfter, err
+}
+
+// AllowN consumes cost tokens from the key's token bucket under the given limit
+func AllowN(ctx context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	return ForAlgorithm(AlgorithmTokenBucket).AllowN(ctx, key, limit, cost)
+}
 
-	if env == "prod" || env == "staging" {
-		rpsVal, burstVal := rateConfig()
-		ttl := config.GetConfigValue("BUCKET_TTL_SECOND")
-		ttlSec, _ := strconv.Atoi(ttl)
-		return elasticache.AllowDistributed(ctx, key, rpsVal, burstVal, ttlSec)
+// Returns the shared limiter for a rate-based algorithm (token bucket when unknown).
+// Prod/staging share state through Elasticache; other environments keep it in process.
+func ForAlgorithm(algorithm string) Service {
+	limiters := memoryLimiters
+	if isDistributed() { limiters = redisLimiters }
+
+	if svc, ok := limiters[algorithm]; ok { return svc }
+	return limiters[AlgorithmTokenBucket]
+}
+
+var (
+	memoryLimiters = map[string]Service{
+		AlgorithmTokenBucket:   memoryTokenBucket{},
+		AlgorithmSlidingWindow: newMemorySlidingWindow(),
+		AlgorithmGCRA:          newMemoryGCRA(),
+	}
+	redisLimiters = map[string]Service{
+		AlgorithmTokenBucket:   redisTokenBucket{},
+		AlgorithmSlidingWindow: redisSlidingWindow{},
+		AlgorithmGCRA:          redisGCRA{},
 	}
+)
 
-	// local process bucket
-	b := getBucket(key)
-	if !b.allow() {
-		return false, b.retryAfter(), nil
+func isDistributed() bool {
+	env := strings.ToLower(config.GetConfigValue("ENV"))
+	return env == "prod" || env == "staging"
+}
+
+// In-process token bucket backed by the package bucket map
+type memoryTokenBucket struct{}
+
+func (memoryTokenBucket) AllowN(_ context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	if cost <= 0 { cost = 1 }
+	return getBucket(key).take(limit, cost, time.Now()), nil
+}
+
+func (memoryTokenBucket) Reset(ctx context.Context, key string) error { return Reset(ctx, key) }
+
+// Token bucket shared across instances through the Elasticache Lua script
+type redisTokenBucket struct{}
+
+func (redisTokenBucket) AllowN(ctx context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	if cost <= 0 { cost = 1 }
+	ttlSec, _ := strconv.Atoi(config.GetConfigValue("BUCKET_TTL_SECOND"))
+	if ttlSec <= 0 { ttlSec = 300 }
+
+	res, err := elasticache.AllowDistributedN(ctx, key, limit.Rate, limit.Burst, cost, ttlSec)
+	return decisionFrom(res, limit), err
+}
+
+func (redisTokenBucket) Reset(_ context.Context, key string) error { return elasticache.Delete(key) }
+
+func decisionFrom(res elasticache.LimitResult, limit Limit) Decision {
+	return Decision{
+		Allowed:    res.Allowed,
+		Limit:      int(limit.Burst),
+		Remaining:  res.Remaining,
+		RetryAfter: res.RetryAfter,
+		Reset:      res.Reset,
 	}
-	return true, 0, nil
 }
 
 // Returns simple usage metrics for the given key
@@ -97,32 +174,45 @@ func LoadConfig(cfg Config) error {
 // Checks and updates the bucket token count
 func (bkt *bucket) allow() bool {
 	rps, burst := rateConfig()
-	now := time.Now()
+	return bkt.take(Limit{Rate: rps, Burst: burst}, 1, time.Now()).Allowed
+}
+
+// Refills the bucket for the elapsed time, then tries to consume cost tokens
+func (bkt *bucket) take(limit Limit, cost float64, now time.Time) Decision {
 	bkt.mu.Lock()
+	defer bkt.mu.Unlock()
 
 	// Refill tokens based on elapsed time
 	if !bkt.last.IsZero() {
 		elapsed := now.Sub(bkt.last).Seconds()
 		if elapsed > 0 {
-			bkt.tokens += elapsed * rps
-			if bkt.tokens > burst {
-				bkt.tokens = burst
+			bkt.tokens += elapsed * limit.Rate
+			if bkt.tokens > limit.Burst {
+				bkt.tokens = limit.Burst
 			}
 		}
 	} else {
-		bkt.tokens = burst
+		bkt.tokens = limit.Burst
 	}
+	bkt.last = now
 
-	allowed := false
-	if bkt.tokens >= 1 {
-		bkt.tokens -= 1
-		allowed = true
+	dec := Decision{Limit: int(limit.Burst)}
+	if bkt.tokens >= cost {
+		bkt.tokens -= cost
+		dec.Allowed = true
+	} else if limit.Rate > 0 {
+		dec.RetryAfter = secondsToDuration((cost - bkt.tokens) / limit.Rate)
 	}
 
-	bkt.last = now
-	bkt.mu.Unlock()
+	dec.Remaining = int(bkt.tokens)
+	if limit.Rate > 0 {
+		dec.Reset = secondsToDuration((limit.Burst - bkt.tokens) / limit.Rate)
+	}
+	return dec
+}
 
-	return allowed
+func secondsToDuration(secs float64) time.Duration {
+	return time.Duration(secs * float64(time.Second))
 }
 
 // Estimates how long until the next token is available
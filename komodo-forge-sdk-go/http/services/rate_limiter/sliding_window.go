@@ -0,0 +1,91 @@
+package rateLimiter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"math"
+	"time"
+)
+
+// Sliding window log: at most Burst units of cost within any Burst/Rate window.
+// Exact at the window edges (no fixed-window double bursts), at the price of one entry per unit.
+type memorySlidingWindow struct {
+	logs *stateMap[[]time.Time]
+}
+
+func newMemorySlidingWindow() *memorySlidingWindow {
+	return &memorySlidingWindow{logs: newStateMap[[]time.Time]()}
+}
+
+func (sw *memorySlidingWindow) AllowN(_ context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	window, capacity, units := windowParams(limit, cost)
+	now := time.Now()
+	dec := Decision{Limit: capacity}
+
+	sw.logs.with(key, now, func(log *[]time.Time) time.Duration {
+		// drop hits that have left the window (the log is kept in arrival order)
+		cutoff := now.Add(-window)
+		kept := (*log)[:0]
+		for _, at := range *log {
+			if at.After(cutoff) { kept = append(kept, at) }
+		}
+		*log = kept
+
+		if len(kept)+units <= capacity {
+			for range units { *log = append(*log, now) }
+			dec.Allowed = true
+		} else if idx := len(kept) + units - capacity - 1; idx < len(kept) {
+			// wait until enough of the oldest hits expire to fit this request
+			dec.RetryAfter = kept[idx].Add(window).Sub(now)
+		} else {
+			dec.RetryAfter = window
+		}
+
+		dec.Remaining = capacity - len(*log)
+		if n := len(*log); n > 0 {
+			dec.Reset = (*log)[n-1].Add(window).Sub(now)
+		}
+		return window
+	})
+	return dec, nil
+}
+
+func (sw *memorySlidingWindow) Reset(_ context.Context, key string) error {
+	sw.logs.delete(key)
+	return nil
+}
+
+// Sliding window log shared across instances through a sorted set
+type redisSlidingWindow struct{}
+
+func (redisSlidingWindow) AllowN(ctx context.Context, key string, limit Limit, cost float64) (Decision, error) {
+	window, capacity, units := windowParams(limit, cost)
+
+	res, err := elasticache.SlidingWindowAllow(ctx, key, window, capacity, units, newID())
+	dec := decisionFrom(res, limit)
+	dec.Limit = capacity
+	return dec, err
+}
+
+func (redisSlidingWindow) Reset(_ context.Context, key string) error { return elasticache.Delete(key) }
+
+// Derives the window length, request capacity and whole units of cost from a rate limit
+func windowParams(limit Limit, cost float64) (time.Duration, int, int) {
+	capacity := max(int(limit.Burst), 1)
+	units := max(int(math.Ceil(cost)), 1)
+
+	window := time.Second
+	if limit.Rate > 0 {
+		window = time.Duration(float64(capacity) / limit.Rate * float64(time.Second))
+	}
+	return window, capacity, units
+}
+
+// Unique member id for log entries and concurrency leases
+func newID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
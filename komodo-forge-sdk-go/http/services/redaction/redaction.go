@@ -3,7 +3,7 @@ package redaction
 import "regexp"
 
 var (
-	keyRegex = regexp.MustCompile(`(?i)^(authorization|set-cookie|password|token|bearer|ssn|pwd|secret|api_key|cvv|card_number)$`)
+	keyRegex = regexp.MustCompile(`(?i)^(authorization|set-cookie|cookie|password|token|bearer|ssn|pwd|secret|api_key|x-api-key|x-csrf-token|client_?secret|refresh_?token|access_?token|cvv|card_number)$`)
 	piiRegex = regexp.MustCompile(`(?i)` +
 		`([a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,})|` + 	// Email
 		`(\b\d{3}-\d{2}-\d{4}\b)|` +                  // SSN
@@ -0,0 +1,80 @@
+package refreshToken
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	rec     Record
+	used    bool
+	expires time.Time
+}
+
+// In-memory token families; a restart signs every user out
+type MemoryStore struct {
+	mu       sync.Mutex
+	tokens   map[string]*memoryEntry
+	families map[string]time.Time // revoked family -> when the marker can be dropped
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]*memoryEntry), families: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, hash string, rec Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+	s.tokens[hash] = &memoryEntry{rec: rec, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, hash string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.tokens[hash]
+	if !ok || !time.Now().Before(entry.expires) { return nil, nil }
+	rec := entry.rec
+	return &rec, nil
+}
+
+func (s *MemoryStore) MarkUsed(_ context.Context, hash string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.tokens[hash]
+	if !ok || entry.used { return false, nil }
+	entry.used = true
+	return true, nil
+}
+
+func (s *MemoryStore) RevokeFamily(_ context.Context, familyID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.families[familyID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) FamilyRevoked(_ context.Context, familyID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.families[familyID]
+	return ok && time.Now().Before(expires), nil
+}
+
+// Drops expired entries; called under the lock on every save so the maps can't grow unbounded
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for hash, entry := range s.tokens {
+		if !now.Before(entry.expires) { delete(s.tokens, hash) }
+	}
+	for familyID, expires := range s.families {
+		if !now.Before(expires) { delete(s.families, familyID) }
+	}
+}
@@ -0,0 +1,49 @@
+package refreshToken
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"time"
+)
+
+// Distributed store on the shared Elasticache client.
+// Records are JSON under prefix+"token:"+hash; rotation claims and revoked families are marker keys.
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore { return &RedisStore{prefix: prefix} }
+
+func (s *RedisStore) Save(_ context.Context, hash string, rec Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil { return err }
+	return elasticache.Set(s.prefix+"token:"+hash, string(data), elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) Get(_ context.Context, hash string) (*Record, error) {
+	raw, err := elasticache.Get(s.prefix + "token:" + hash)
+	if err != nil || raw == "" { return nil, err }
+
+	var rec Record
+	if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+		return nil, fmt.Errorf("corrupt refresh token record: %w", err)
+	}
+	return &rec, nil
+}
+
+// SETNX makes the claim atomic across instances: only one concurrent rotation wins
+func (s *RedisStore) MarkUsed(_ context.Context, hash string, ttl time.Duration) (bool, error) {
+	return elasticache.SetNX(s.prefix+"used:"+hash, "1", elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) RevokeFamily(_ context.Context, familyID string, ttl time.Duration) error {
+	return elasticache.Set(s.prefix+"family:"+familyID, "revoked", elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) FamilyRevoked(_ context.Context, familyID string) (bool, error) {
+	raw, err := elasticache.Get(s.prefix + "family:" + familyID)
+	if err != nil { return false, err }
+	return raw != "", nil
+}
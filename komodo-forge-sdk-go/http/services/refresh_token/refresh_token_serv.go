@@ -0,0 +1,186 @@
+package refreshToken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/http/services/revocation"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULT_TTL_SEC     = 14 * 24 * 3600 // sliding lifetime of each refresh token
+	DEFAULT_MAX_TTL_SEC = 90 * 24 * 3600 // absolute lifetime of a token family
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid refresh token")
+	ErrClientMismatch = errors.New("refresh token was issued to a different client")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+	ErrRevoked        = errors.New("refresh token has been revoked")
+)
+
+// Server-side state of a refresh token. Only the SHA-256 of the token is ever stored.
+type Record struct {
+	FamilyID        string    `json:"familyId"`
+	ClientID        string    `json:"clientId"`
+	Subject         string    `json:"subject"`
+	Scopes          []string  `json:"scopes,omitempty"`
+	IssuedAt        time.Time `json:"issuedAt"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+	FamilyIssuedAt  time.Time `json:"familyIssuedAt"`
+	FamilyExpiresAt time.Time `json:"familyExpiresAt"`
+	TTLSec          int64     `json:"ttlSec,omitempty"` // per-client sliding lifetime; 0 uses REFRESH_TOKEN_TTL_SEC
+}
+
+type Store interface {
+	Save(ctx context.Context, hash string, rec Record, ttl time.Duration) error
+	// Returns nil when the token is unknown or expired
+	Get(ctx context.Context, hash string) (*Record, error)
+	// Atomically marks the token as used. Returns false if it had already been used.
+	MarkUsed(ctx context.Context, hash string, ttl time.Duration) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, ttl time.Duration) error
+	FamilyRevoked(ctx context.Context, familyID string) (bool, error)
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// Token families live in Redis in prod/staging so any instance can rotate them; in memory otherwise
+func Default() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		switch strings.ToLower(config.GetConfigValue("ENV")) {
+			case "prod", "staging":
+				store = NewRedisStore("refresh:")
+			default:
+				store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Swaps the token backend (tests, or a service bringing its own persistence)
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Starts a new token family for clientID/subject and returns its first token.
+// ttl overrides the sliding lifetime for this family (0 uses TTL()).
+func Issue(ctx context.Context, clientID string, subject string, scopes []string, ttl time.Duration) (string, *Record, error) {
+	if clientID == "" || subject == "" { return "", nil, fmt.Errorf("refresh tokens need a client and subject") }
+
+	now := time.Now()
+	rec := Record{
+		FamilyID:        uuid.NewString(),
+		ClientID:        clientID,
+		Subject:         subject,
+		Scopes:          scopes,
+		// Subject watermarks have second precision; a finer start time would outlive a revocation in the same second
+		FamilyIssuedAt:  now.Truncate(time.Second),
+		FamilyExpiresAt: now.Add(MaxTTL()),
+		TTLSec:          int64(ttl / time.Second),
+	}
+	return save(ctx, rec, now)
+}
+
+// Exchanges a refresh token for its successor in the same family.
+// Presenting a token that was already rotated revokes the whole family.
+func Rotate(ctx context.Context, token string, clientID string) (string, *Record, error) {
+	s := Default()
+	hash := Hash(token)
+
+	rec, err := s.Get(ctx, hash)
+	if err != nil { return "", nil, err }
+	if rec == nil || !time.Now().Before(rec.ExpiresAt) { return "", nil, ErrInvalidToken }
+	if rec.ClientID != clientID { return "", nil, ErrClientMismatch }
+
+	if err := checkFamily(ctx, s, rec); err != nil { return "", nil, err }
+
+	first, err := s.MarkUsed(ctx, hash, time.Until(rec.ExpiresAt))
+	if err != nil { return "", nil, err }
+	if !first {
+		// Either the legitimate client or an attacker holds a stale copy; neither can be trusted now
+		if err := s.RevokeFamily(ctx, rec.FamilyID, time.Until(rec.FamilyExpiresAt)); err != nil { return "", nil, err }
+		return "", nil, ErrTokenReused
+	}
+
+	return save(ctx, *rec, time.Now())
+}
+
+// Revokes the family of the given token; unknown tokens are ignored (RFC 7009)
+func Revoke(ctx context.Context, token string) (bool, error) {
+	s := Default()
+
+	rec, err := s.Get(ctx, Hash(token))
+	if err != nil || rec == nil { return false, err }
+	return true, s.RevokeFamily(ctx, rec.FamilyID, time.Until(rec.FamilyExpiresAt))
+}
+
+// Looks up a refresh token without consuming it
+func Lookup(ctx context.Context, token string) (*Record, error) {
+	s := Default()
+
+	rec, err := s.Get(ctx, Hash(token))
+	if err != nil || rec == nil { return nil, err }
+	if !time.Now().Before(rec.ExpiresAt) { return nil, nil }
+	if err := checkFamily(ctx, s, rec); err != nil { return nil, err }
+	return rec, nil
+}
+
+// Storage key for a token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sliding lifetime of each refresh token (REFRESH_TOKEN_TTL_SEC)
+func TTL() time.Duration { return config.GetSeconds("REFRESH_TOKEN_TTL_SEC", DEFAULT_TTL_SEC) }
+
+// Absolute lifetime of a token family (REFRESH_TOKEN_MAX_TTL_SEC)
+func MaxTTL() time.Duration { return config.GetSeconds("REFRESH_TOKEN_MAX_TTL_SEC", DEFAULT_MAX_TTL_SEC) }
+
+// Rejects tokens whose family was revoked, or whose subject was revoked after the family started
+func checkFamily(ctx context.Context, s Store, rec *Record) error {
+	revoked, err := s.FamilyRevoked(ctx, rec.FamilyID)
+	if err != nil { return err }
+	if revoked { return ErrRevoked }
+
+	if err := revocation.Check(ctx, "", rec.Subject, rec.FamilyIssuedAt); err != nil {
+		if errors.Is(err, revocation.ErrRevoked) { return ErrRevoked }
+		return err
+	}
+	return nil
+}
+
+func save(ctx context.Context, rec Record, now time.Time) (string, *Record, error) {
+	ttl := TTL()
+	if rec.TTLSec > 0 { ttl = time.Duration(rec.TTLSec) * time.Second }
+
+	expires := now.Add(ttl)
+	if expires.After(rec.FamilyExpiresAt) { expires = rec.FamilyExpiresAt }
+	if !now.Before(expires) { return "", nil, ErrInvalidToken }
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil { return "", nil, err }
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	rec.IssuedAt, rec.ExpiresAt = now, expires
+	if err := Default().Save(ctx, Hash(token), rec, expires.Sub(now)); err != nil { return "", nil, err }
+	return token, &rec, nil
+}
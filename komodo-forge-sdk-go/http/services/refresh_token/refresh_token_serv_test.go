@@ -0,0 +1,55 @@
+package refreshToken
+
+import (
+	"context"
+	"errors"
+	"komodo-forge-sdk-go/http/services/revocation"
+	"testing"
+)
+
+func TestRotationDetectsReuse(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	first, rec, err := Issue(ctx, "web-client", "user-1", []string{"read"}, 0)
+	if err != nil { t.Fatalf("issue: %v", err) }
+
+	if _, _, err := Rotate(ctx, first, "other-client"); !errors.Is(err, ErrClientMismatch) {
+		t.Fatalf("rotate with wrong client: err = %v, want ErrClientMismatch", err)
+	}
+
+	second, next, err := Rotate(ctx, first, "web-client")
+	if err != nil { t.Fatalf("rotate: %v", err) }
+	if second == first || next.FamilyID != rec.FamilyID || next.Subject != "user-1" {
+		t.Fatalf("rotated record = %+v, want a new token in family %s", next, rec.FamilyID)
+	}
+
+	// Replaying the rotated token kills the family, including the token that replaced it
+	if _, _, err := Rotate(ctx, first, "web-client"); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("replay: err = %v, want ErrTokenReused", err)
+	}
+	if _, _, err := Rotate(ctx, second, "web-client"); !errors.Is(err, ErrRevoked) {
+		t.Errorf("successor after reuse: err = %v, want ErrRevoked", err)
+	}
+
+	if _, _, err := Rotate(ctx, "not-a-token", "web-client"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRevokeSubjectEndsFamilyStartedThatSecond(t *testing.T) {
+	SetStore(NewMemoryStore())
+	revocation.SetStore(revocation.NewMemoryStore())
+	defer SetStore(nil)
+	defer revocation.SetStore(nil)
+	ctx := context.Background()
+
+	token, _, err := Issue(ctx, "web-client", "user-1", nil, 0)
+	if err != nil { t.Fatalf("issue: %v", err) }
+	if _, err := revocation.RevokeSubject(ctx, "user-1"); err != nil { t.Fatalf("revoke subject: %v", err) }
+
+	if _, _, err := Rotate(ctx, token, "web-client"); !errors.Is(err, ErrRevoked) {
+		t.Errorf("rotate after subject revocation: err = %v, want ErrRevoked", err)
+	}
+}
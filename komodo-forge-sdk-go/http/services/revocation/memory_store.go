@@ -0,0 +1,72 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type watermark struct {
+	notBefore time.Time
+	expires   time.Time
+}
+
+// Keeps revocations in this process; they are lost on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	tokens   map[string]time.Time // jti -> expiry
+	subjects map[string]watermark
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]time.Time), subjects: make(map[string]watermark)}
+}
+
+func (s *MemoryStore) RevokeToken(_ context.Context, jti string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+	s.tokens[jti] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) RevokeSubject(_ context.Context, subject string, notBefore time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+	// Never move a watermark backwards
+	if existing, ok := s.subjects[subject]; ok && existing.notBefore.After(notBefore) { notBefore = existing.notBefore }
+	s.subjects[subject] = watermark{notBefore: notBefore, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.tokens[jti]
+	return ok && time.Now().Before(expires), nil
+}
+
+func (s *MemoryStore) NotBefore(_ context.Context, subject string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mark, ok := s.subjects[subject]
+	if !ok || !time.Now().Before(mark.expires) { return time.Time{}, nil }
+	return mark.notBefore, nil
+}
+
+// Drops expired entries; called under the lock on every write so the maps can't grow unbounded
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for jti, expires := range s.tokens {
+		if !now.Before(expires) { delete(s.tokens, jti) }
+	}
+	for subject, mark := range s.subjects {
+		if !now.Before(mark.expires) { delete(s.subjects, subject) }
+	}
+}
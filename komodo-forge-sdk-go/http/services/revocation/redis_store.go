@@ -0,0 +1,42 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"komodo-forge-sdk-go/aws/elasticache"
+	"strconv"
+	"time"
+)
+
+// Distributed store on the shared Elasticache client.
+// Revoked JTIs live under prefix+"jti:"+jti and subject watermarks (unix seconds) under prefix+"sub:"+subject.
+type RedisStore struct {
+	prefix string
+}
+
+func NewRedisStore(prefix string) *RedisStore { return &RedisStore{prefix: prefix} }
+
+func (s *RedisStore) RevokeToken(_ context.Context, jti string, ttl time.Duration) error {
+	return elasticache.Set(s.prefix+"jti:"+jti, "1", elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) RevokeSubject(ctx context.Context, subject string, notBefore time.Time, ttl time.Duration) error {
+	// Never move a watermark backwards (e.g. a slower instance writing an older revocation)
+	if existing, err := s.NotBefore(ctx, subject); err == nil && existing.After(notBefore) { notBefore = existing }
+	return elasticache.Set(s.prefix+"sub:"+subject, strconv.FormatInt(notBefore.Unix(), 10), elasticache.TTLSeconds(ttl))
+}
+
+func (s *RedisStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	raw, err := elasticache.Get(s.prefix + "jti:" + jti)
+	if err != nil { return false, err }
+	return raw != "", nil
+}
+
+func (s *RedisStore) NotBefore(_ context.Context, subject string) (time.Time, error) {
+	raw, err := elasticache.Get(s.prefix + "sub:" + subject)
+	if err != nil || raw == "" { return time.Time{}, err }
+
+	sec, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil { return time.Time{}, fmt.Errorf("corrupt revocation watermark for %s: %w", subject, err) }
+	return time.Unix(sec, 0), nil
+}
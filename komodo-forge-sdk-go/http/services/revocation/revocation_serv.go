@@ -0,0 +1,93 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"komodo-forge-sdk-go/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a subject's not-before watermark is kept; must cover the longest-lived token (a refresh token family)
+const DEFAULT_SUBJECT_TTL_SEC = 90 * 24 * 3600
+
+var ErrRevoked = errors.New("token has been revoked")
+
+type Store interface {
+	// Denylists a single token by JTI until ttl (the token's remaining lifetime) elapses
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	// Invalidates every token for subject issued at or before notBefore
+	RevokeSubject(ctx context.Context, subject string, notBefore time.Time, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// Returns the subject's watermark, or the zero time when none is set
+	NotBefore(ctx context.Context, subject string) (time.Time, error)
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// Revocations must reach every instance, so prod/staging share them through Redis; elsewhere they stay in memory
+func Default() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		switch strings.ToLower(config.GetConfigValue("ENV")) {
+			case "prod", "staging":
+				store = NewRedisStore("revoked:")
+			default:
+				store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Swaps the revocation backend, e.g. for a fresh store per test
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Revokes a token until it would have expired anyway; already-expired tokens are a no-op
+func RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" { return errors.New("token has no jti") }
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 { return nil }
+	return Default().RevokeToken(ctx, jti, ttl)
+}
+
+// Revokes every token issued to subject up to now (logout everywhere, credential reset)
+func RevokeSubject(ctx context.Context, subject string) (time.Time, error) {
+	if subject == "" { return time.Time{}, errors.New("missing subject") }
+
+	// iat has second precision, so the watermark does too
+	now := time.Now().Truncate(time.Second)
+	return now, Default().RevokeSubject(ctx, subject, now, SubjectTTL())
+}
+
+// Returns ErrRevoked when the token's JTI is denylisted or it was issued before the subject's watermark.
+// Store errors are returned as-is so callers fail closed.
+func Check(ctx context.Context, jti string, subject string, issuedAt time.Time) error {
+	s := Default()
+
+	if jti != "" {
+		revoked, err := s.IsRevoked(ctx, jti)
+		if err != nil { return err }
+		if revoked { return ErrRevoked }
+	}
+
+	if subject != "" {
+		notBefore, err := s.NotBefore(ctx, subject)
+		if err != nil { return err }
+		if !notBefore.IsZero() && !issuedAt.After(notBefore) { return ErrRevoked }
+	}
+	return nil
+}
+
+// Lifetime of subject watermarks (REVOCATION_SUBJECT_TTL_SEC)
+func SubjectTTL() time.Duration { return config.GetSeconds("REVOCATION_SUBJECT_TTL_SEC", DEFAULT_SUBJECT_TTL_SEC) }
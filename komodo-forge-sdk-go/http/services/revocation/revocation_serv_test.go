@@ -0,0 +1,54 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckHonoursJTIAndSubjectWatermark(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	issued := time.Now().Add(-time.Minute)
+	if err := Check(ctx, "jti-1", "user-1", issued); err != nil {
+		t.Fatalf("fresh token reported revoked: %v", err)
+	}
+
+	if err := RevokeToken(ctx, "jti-1", time.Now().Add(time.Hour)); err != nil { t.Fatalf("revoke token: %v", err) }
+	if err := Check(ctx, "jti-1", "user-1", issued); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked jti: err = %v, want ErrRevoked", err)
+	}
+	if err := Check(ctx, "jti-2", "user-1", issued); err != nil {
+		t.Errorf("other jti for the same subject reported revoked: %v", err)
+	}
+
+	if _, err := RevokeSubject(ctx, "user-1"); err != nil { t.Fatalf("revoke subject: %v", err) }
+	if err := Check(ctx, "jti-2", "user-1", issued); !errors.Is(err, ErrRevoked) {
+		t.Errorf("token issued before watermark: err = %v, want ErrRevoked", err)
+	}
+	if err := Check(ctx, "jti-3", "user-1", time.Now().Add(2*time.Second)); err != nil {
+		t.Errorf("token issued after watermark reported revoked: %v", err)
+	}
+	if err := Check(ctx, "jti-4", "user-2", issued); err != nil {
+		t.Errorf("other subject reported revoked: %v", err)
+	}
+}
+
+func TestExpiredRevocationsAreForgotten(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	s.RevokeToken(ctx, "jti-1", time.Millisecond)
+	s.RevokeSubject(ctx, "user-1", time.Now(), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if revoked, _ := s.IsRevoked(ctx, "jti-1"); revoked {
+		t.Error("jti still revoked after its ttl")
+	}
+	if notBefore, _ := s.NotBefore(ctx, "user-1"); !notBefore.IsZero() {
+		t.Errorf("watermark = %v after its ttl, want zero", notBefore)
+	}
+}
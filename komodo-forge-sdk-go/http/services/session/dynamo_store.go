@@ -0,0 +1,90 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"komodo-forge-sdk-go/aws/dynamodb"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// Index over user_id used to list and revoke a user's sessions
+	userIndex = "user-id-index"
+
+	// Guards writes to sessions that may have been revoked concurrently
+	sessionExists = "attribute_exists(session_id)"
+)
+
+// Store backed by a DynamoDB table keyed by session_id, with DynamoDB TTL on the ttl attribute
+type DynamoStore struct {
+	table string
+}
+
+func NewDynamoStore(table string) *DynamoStore { return &DynamoStore{table: table} }
+
+func (s *DynamoStore) Save(ctx context.Context, sess *Session) error {
+	return dynamodb.WriteItemFrom(ctx, s.table, sess, false, nil, nil)
+}
+
+func (s *DynamoStore) Get(ctx context.Context, id string) (*Session, error) {
+	key, err := dynamodb.BuildKey("session_id", id, "", nil)
+	if err != nil { return nil, err }
+
+	var sess Session
+	if err := dynamodb.GetItemAs(ctx, s.table, key, false, nil, &sess); err != nil {
+		if errors.Is(err, dynamodb.ErrItemNotFound) { return nil, ErrNotFound }
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// Updates only the expiry attributes; the condition keeps a concurrent revoke from being undone
+func (s *DynamoStore) Touch(ctx context.Context, id string, lastSeen time.Time, expiresAt time.Time) error {
+	key, err := dynamodb.BuildKey("session_id", id, "", nil)
+	if err != nil { return err }
+
+	seen, err := attributevalue.Marshal(lastSeen)
+	if err != nil { return err }
+	expires, err := attributevalue.Marshal(expiresAt)
+	if err != nil { return err }
+
+	_, err = dynamodb.UpdateItem(ctx, s.table, key,
+		"SET last_seen_at = :seen, expires_at = :exp",
+		map[string]types.AttributeValue{":seen": seen, ":exp": expires},
+		nil, aws.String(sessionExists),
+	)
+	return notFoundOnCondition(err)
+}
+
+func (s *DynamoStore) Delete(ctx context.Context, id string) error {
+	key, err := dynamodb.BuildKey("session_id", id, "", nil)
+	if err != nil { return err }
+	return dynamodb.DeleteItem(ctx, s.table, key, false, nil, nil)
+}
+
+func (s *DynamoStore) Consume(ctx context.Context, id string) error {
+	key, err := dynamodb.BuildKey("session_id", id, "", nil)
+	if err != nil { return err }
+	return notFoundOnCondition(dynamodb.DeleteItem(ctx, s.table, key, false, nil, aws.String(sessionExists)))
+}
+
+func (s *DynamoStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	sessions := make([]*Session, 0)
+	err := dynamodb.QueryAllAs(ctx, dynamodb.QueryInput{
+		TableName:              s.table,
+		IndexName:              aws.String(userIndex),
+		KeyConditionExpression: "user_id = :uid",
+		ExpressionValues:       map[string]types.AttributeValue{":uid": &types.AttributeValueMemberS{Value: userID}},
+	}, &sessions)
+	return sessions, err
+}
+
+func notFoundOnCondition(err error) error {
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) { return ErrNotFound }
+	return err
+}
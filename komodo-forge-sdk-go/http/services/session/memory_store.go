@@ -0,0 +1,79 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Process-local store for tests and single-instance development
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemoryStore() *MemoryStore { return &MemoryStore{sessions: make(map[string]Session)} }
+
+func (s *MemoryStore) Save(_ context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired(time.Now())
+	s.sessions[sess.ID] = *sess
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok { return nil, ErrNotFound }
+	return &sess, nil
+}
+
+func (s *MemoryStore) Touch(_ context.Context, id string, lastSeen time.Time, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok { return ErrNotFound }
+	sess.LastSeenAt, sess.ExpiresAt = lastSeen, expiresAt
+	s.sessions[id] = sess
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemoryStore) Consume(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok { return ErrNotFound }
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemoryStore) ListByUser(_ context.Context, userID string) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*Session, 0)
+	for _, sess := range s.sessions {
+		if sess.UserID == userID { out = append(out, &sess) }
+	}
+	return out, nil
+}
+
+// Drops sessions past their absolute expiry; called under the lock on every save
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for id, sess := range s.sessions {
+		if !now.Before(sess.AbsoluteExpiresAt) { delete(s.sessions, id) }
+	}
+}
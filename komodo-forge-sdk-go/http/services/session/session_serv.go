@@ -0,0 +1,263 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"komodo-forge-sdk-go/aws/dynamodb"
+	"komodo-forge-sdk-go/config"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// __Host- cookies must be Secure, host-only and scoped to "/", so subdomains can't plant or read them
+	COOKIE_NAME = "__Host-komodo-session"
+
+	DEFAULT_IDLE_TTL_SEC = 30 * 60        // sliding lifetime, renewed on use
+	DEFAULT_MAX_TTL_SEC  = 7 * 24 * 3600  // absolute lifetime from login
+	touchInterval        = time.Minute    // minimum gap between last-seen writes
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrExpired  = errors.New("session expired")
+)
+
+// Server-side state of a browser session. The store only sees the SHA-256 of the cookie value,
+// which doubles as the session ID shown when listing or revoking sessions.
+type Session struct {
+	ID                string    `json:"sessionId" dynamodbav:"session_id"`
+	UserID            string    `json:"userId" dynamodbav:"user_id"`
+	Scopes            []string  `json:"scopes,omitempty" dynamodbav:"scopes,omitempty"`
+	IsAdmin           bool      `json:"isAdmin,omitempty" dynamodbav:"is_admin,omitempty"`
+	ClientIP          string    `json:"clientIp,omitempty" dynamodbav:"client_ip,omitempty"`
+	UserAgent         string    `json:"userAgent,omitempty" dynamodbav:"user_agent,omitempty"`
+	CreatedAt         time.Time `json:"createdAt" dynamodbav:"created_at"`
+	LastSeenAt        time.Time `json:"lastSeenAt" dynamodbav:"last_seen_at"`
+	ExpiresAt         time.Time `json:"expiresAt" dynamodbav:"expires_at"`                   // idle expiry
+	AbsoluteExpiresAt time.Time `json:"absoluteExpiresAt" dynamodbav:"absolute_expires_at"` // hard cap
+	TTL               int64     `json:"-" dynamodbav:"ttl"`                                 // epoch seconds for DynamoDB TTL
+}
+
+// Privileges a session carries; changing them rotates the session ID
+type Grant struct {
+	UserID  string
+	Scopes  []string
+	IsAdmin bool
+}
+
+type Store interface {
+	Save(ctx context.Context, sess *Session) error
+	// Returns ErrNotFound when no session has the id
+	Get(ctx context.Context, id string) (*Session, error)
+	// Updates only the sliding expiry of an existing session; returns ErrNotFound instead of
+	// re-creating one that was revoked while the request was in flight
+	Touch(ctx context.Context, id string, lastSeen time.Time, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	// Deletes the session, returning ErrNotFound when it was already gone
+	Consume(ctx context.Context, id string) error
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// Returns the process-wide store: DynamoDB once the client is initialized, in-memory otherwise
+func Default() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		if dynamodb.IsInitialized() {
+			store = NewDynamoStore(TableName())
+		} else {
+			store = NewMemoryStore()
+		}
+	}
+	return store
+}
+
+// Replaces the session backend, e.g. to wrap it in tests
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Table holding the sessions (SESSIONS_TABLE)
+func TableName() string {
+	if table := config.GetConfigValue("SESSIONS_TABLE"); table != "" { return table }
+	return "komodo-sessions-dev"
+}
+
+// Starts a session for the grant and returns the cookie value, which is never stored
+func Create(ctx context.Context, req *http.Request, grant Grant) (string, *Session, error) {
+	if grant.UserID == "" { return "", nil, fmt.Errorf("sessions need a user") }
+
+	now := time.Now().UTC()
+	sess := &Session{
+		UserID:            grant.UserID,
+		Scopes:            grant.Scopes,
+		IsAdmin:           grant.IsAdmin,
+		UserAgent:         req.UserAgent(),
+		CreatedAt:         now,
+		AbsoluteExpiresAt: now.Add(MaxTTL()),
+	}
+	if ip, ok := req.Context().Value(ctxKeys.CLIENT_IP_KEY).(string); ok { sess.ClientIP = ip }
+
+	return save(ctx, sess, now)
+}
+
+// Resolves a cookie value to a live session, extending its idle expiry.
+// Expired sessions are deleted and reported as ErrExpired.
+func Validate(ctx context.Context, token string) (*Session, error) {
+	sess, err := load(ctx, token)
+	if err != nil { return nil, err }
+
+	// Sliding expiry without writing on every request
+	now := time.Now().UTC()
+	if now.Sub(sess.LastSeenAt) >= touchInterval {
+		expiresAt := expiry(now, sess.AbsoluteExpiresAt)
+		if err := Default().Touch(ctx, sess.ID, now, expiresAt); err != nil { return nil, err }
+		sess.LastSeenAt, sess.ExpiresAt = now, expiresAt
+	}
+	return sess, nil
+}
+
+// Replaces the session ID, e.g. after login or a privilege change, so a fixated or leaked ID stops working.
+// A nil grant keeps the current privileges. The absolute expiry carries over.
+// The old session is consumed first, so a session revoked mid-request can't be carried over.
+func Rotate(ctx context.Context, token string, grant *Grant) (string, *Session, error) {
+	sess, err := load(ctx, token)
+	if err != nil { return "", nil, err }
+	if err := Default().Consume(ctx, sess.ID); err != nil { return "", nil, err }
+
+	if grant != nil {
+		if grant.UserID != "" { sess.UserID = grant.UserID }
+		sess.Scopes, sess.IsAdmin = grant.Scopes, grant.IsAdmin
+	}
+	return save(ctx, sess, time.Now().UTC())
+}
+
+// Ends a session by its ID; unknown IDs are ignored
+func Revoke(ctx context.Context, id string) error { return Default().Delete(ctx, id) }
+
+// Ends a session only if it belongs to the user, so users can sign out their own devices
+func RevokeForUser(ctx context.Context, userID string, id string) error {
+	sess, err := Default().Get(ctx, id)
+	if err != nil { return err }
+	if sess.UserID != userID { return ErrNotFound }
+	return Default().Delete(ctx, id)
+}
+
+// Ends every session of the user and returns how many were ended
+func RevokeAll(ctx context.Context, userID string) (int, error) {
+	sessions, err := Default().ListByUser(ctx, userID)
+	if err != nil { return 0, err }
+
+	for _, sess := range sessions {
+		if err := Default().Delete(ctx, sess.ID); err != nil { return 0, err }
+	}
+	return len(sessions), nil
+}
+
+// Returns the user's live sessions
+func List(ctx context.Context, userID string) ([]*Session, error) {
+	sessions, err := Default().ListByUser(ctx, userID)
+	if err != nil { return nil, err }
+
+	now := time.Now().UTC()
+	live := make([]*Session, 0, len(sessions))
+	for _, sess := range sessions {
+		if now.Before(sess.ExpiresAt) && now.Before(sess.AbsoluteExpiresAt) { live = append(live, sess) }
+	}
+	return live, nil
+}
+
+// Storage key and public ID for a cookie value
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Reads the session cookie value, or "" when absent
+func TokenFromRequest(req *http.Request) string {
+	cookie, err := req.Cookie(COOKIE_NAME)
+	if err != nil { return "" }
+	return cookie.Value
+}
+
+// Sets the session cookie; it lives until the absolute expiry, the idle expiry is enforced server-side
+func SetCookie(wtr http.ResponseWriter, token string, sess *Session) {
+	http.SetCookie(wtr, &http.Cookie{
+		Name:     COOKIE_NAME,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(time.Until(sess.AbsoluteExpiresAt).Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Tells the browser to drop the session cookie
+func ClearCookie(wtr http.ResponseWriter) {
+	http.SetCookie(wtr, &http.Cookie{
+		Name:     COOKIE_NAME,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Sliding lifetime of a session (SESSION_IDLE_TTL_SEC)
+func IdleTTL() time.Duration { return config.GetSeconds("SESSION_IDLE_TTL_SEC", DEFAULT_IDLE_TTL_SEC) }
+
+// Absolute lifetime of a session (SESSION_MAX_TTL_SEC)
+func MaxTTL() time.Duration { return config.GetSeconds("SESSION_MAX_TTL_SEC", DEFAULT_MAX_TTL_SEC) }
+
+// Resolves a cookie value to a live session without extending it
+func load(ctx context.Context, token string) (*Session, error) {
+	if token == "" { return nil, ErrNotFound }
+	s := Default()
+
+	sess, err := s.Get(ctx, Hash(token))
+	if err != nil { return nil, err }
+
+	now := time.Now().UTC()
+	if !now.Before(sess.ExpiresAt) || !now.Before(sess.AbsoluteExpiresAt) {
+		if err := s.Delete(ctx, sess.ID); err != nil { return nil, err }
+		return nil, ErrExpired
+	}
+	return sess, nil
+}
+
+func save(ctx context.Context, sess *Session, now time.Time) (string, *Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil { return "", nil, err }
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	sess.ID = Hash(token)
+	sess.LastSeenAt = now
+	sess.ExpiresAt = expiry(now, sess.AbsoluteExpiresAt)
+	sess.TTL = sess.AbsoluteExpiresAt.Unix()
+	if err := Default().Save(ctx, sess); err != nil { return "", nil, err }
+	return token, sess, nil
+}
+
+func expiry(now time.Time, absolute time.Time) time.Time {
+	if idle := now.Add(IdleTTL()); idle.Before(absolute) { return idle }
+	return absolute
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	store := NewMemoryStore()
+	SetStore(store)
+	defer SetStore(nil)
+	ctx := context.Background()
+	req := httptest.NewRequest("POST", "/login", nil)
+
+	token, sess, err := Create(ctx, req, Grant{UserID: "user-1", Scopes: []string{"orders:read"}})
+	if err != nil { t.Fatalf("create: %v", err) }
+	if sess.ID == token || sess.ID != Hash(token) {
+		t.Fatalf("session id should be the hash of the cookie value")
+	}
+	if got, err := Validate(ctx, token); err != nil || got.UserID != "user-1" {
+		t.Fatalf("validate: %+v, %v", got, err)
+	}
+
+	// Privilege change swaps the ID; the old cookie stops working
+	rotated, elevated, err := Rotate(ctx, token, &Grant{Scopes: []string{"orders:write"}, IsAdmin: true})
+	if err != nil { t.Fatalf("rotate: %v", err) }
+	if _, err := Validate(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old token after rotation: err = %v, want ErrNotFound", err)
+	}
+	if !elevated.IsAdmin || elevated.UserID != "user-1" || !elevated.AbsoluteExpiresAt.Equal(sess.AbsoluteExpiresAt) {
+		t.Errorf("rotated session = %+v, want same user and absolute expiry with new privileges", elevated)
+	}
+
+	// Idle expiry
+	stale := *elevated
+	stale.ExpiresAt = time.Now().Add(-time.Second)
+	store.Save(ctx, &stale)
+	if _, err := Validate(ctx, rotated); !errors.Is(err, ErrExpired) {
+		t.Errorf("idle session: err = %v, want ErrExpired", err)
+	}
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	SetStore(NewMemoryStore())
+	defer SetStore(nil)
+	ctx := context.Background()
+	req := httptest.NewRequest("POST", "/login", nil)
+
+	_, laptop, _ := Create(ctx, req, Grant{UserID: "user-1"})
+	phoneToken, _, _ := Create(ctx, req, Grant{UserID: "user-1"})
+	otherToken, _, _ := Create(ctx, req, Grant{UserID: "user-2"})
+
+	if err := RevokeForUser(ctx, "user-2", laptop.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoking another user's session: err = %v, want ErrNotFound", err)
+	}
+	if sessions, _ := List(ctx, "user-1"); len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions for user-1, got %d", len(sessions))
+	}
+
+	if n, err := RevokeAll(ctx, "user-1"); err != nil || n != 2 {
+		t.Fatalf("revoke all: %d, %v", n, err)
+	}
+	if _, err := Validate(ctx, phoneToken); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoked session still valid: %v", err)
+	}
+	if _, err := Validate(ctx, otherToken); err != nil {
+		t.Errorf("other user's session was revoked: %v", err)
+	}
+}
+
+// Revokes the session right after it is read, as a sign-out racing an in-flight request would
+type revokingStore struct {
+	*MemoryStore
+}
+
+func (s revokingStore) Get(ctx context.Context, id string) (*Session, error) {
+	sess, err := s.MemoryStore.Get(ctx, id)
+	if err == nil { s.MemoryStore.Delete(ctx, id) }
+	return sess, err
+}
+
+func TestRevokeDuringRequestIsNotUndone(t *testing.T) {
+	store := NewMemoryStore()
+	SetStore(store)
+	defer SetStore(nil)
+	ctx := context.Background()
+
+	token, sess, _ := Create(ctx, httptest.NewRequest("POST", "/login", nil), Grant{UserID: "user-1"})
+	// Due for a sliding-expiry touch
+	sess.LastSeenAt = time.Now().Add(-2 * touchInterval)
+	store.Save(ctx, sess)
+
+	SetStore(revokingStore{store})
+	if _, err := Validate(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("touch after revoke: err = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("touch re-created the revoked session")
+	}
+
+	store.Save(ctx, sess)
+	if _, _, err := Rotate(ctx, token, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rotate after revoke: err = %v, want ErrNotFound", err)
+	}
+	if sessions, _ := store.ListByUser(ctx, "user-1"); len(sessions) != 0 {
+		t.Errorf("rotate carried a revoked session over: %d sessions left", len(sessions))
+	}
+}
@@ -1,16 +1,24 @@
 package utils
 
 import (
+	"bytes"
 	ctxKeys "komodo-forge-sdk-go/http/context"
 	"net/http"
 )
 
 // ResponseWriter wraps http.ResponseWriter to capture status code and bytes written.
+// When Buffer is set, the status and body are held back until Commit is called.
 type ResponseWriter struct {
 	http.ResponseWriter
 	Status       int
 	BytesWritten int
 	WroteHeader  bool
+	Buffer       *bytes.Buffer
+}
+
+// Returns a writer that buffers the full response so it can be inspected before sending
+func NewBufferedResponseWriter(wtr http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: wtr, Status: http.StatusOK, Buffer: &bytes.Buffer{}}
 }
 
 func (wtr *ResponseWriter) WriteHeader(code int) {
@@ -18,16 +26,42 @@ func (wtr *ResponseWriter) WriteHeader(code int) {
 		wtr.Status = code
 		wtr.WroteHeader = true
 	}
+	if wtr.Buffer != nil { return }
 	wtr.ResponseWriter.WriteHeader(code)
 }
 
 func (wtr *ResponseWriter) Write(b []byte) (int, error) {
 	if !wtr.WroteHeader { wtr.WriteHeader(http.StatusOK) }
+	if wtr.Buffer != nil {
+		num, err := wtr.Buffer.Write(b)
+		wtr.BytesWritten += num
+		return num, err
+	}
 	num, err := wtr.ResponseWriter.Write(b)
 	wtr.BytesWritten += num
 	return num, err
 }
 
+// Sends the buffered status and body to the underlying writer and switches to pass-through
+func (wtr *ResponseWriter) Commit() error {
+	if wtr.Buffer == nil { return nil }
+
+	buf := wtr.Buffer
+	wtr.Buffer = nil
+
+	wtr.ResponseWriter.WriteHeader(wtr.Status)
+	_, err := wtr.ResponseWriter.Write(buf.Bytes())
+	return err
+}
+
+// Drops anything buffered so far (headers already set on the writer are kept)
+func (wtr *ResponseWriter) Discard() {
+	if wtr.Buffer != nil { wtr.Buffer.Reset() }
+	wtr.Status = http.StatusOK
+	wtr.WroteHeader = false
+	wtr.BytesWritten = 0
+}
+
 func (wtr *ResponseWriter) Unwrap() http.ResponseWriter {
 	return wtr.ResponseWriter
 }
@@ -0,0 +1,126 @@
+package versioning
+
+import (
+	"context"
+	"fmt"
+	ctxKeys "komodo-forge-sdk-go/http/context"
+	httpErr "komodo-forge-sdk-go/http/errors"
+	httpReq "komodo-forge-sdk-go/http/request"
+	logger "komodo-forge-sdk-go/logging/runtime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler registered for one major version of a route
+type version struct {
+	handler    http.Handler
+	deprecated time.Time // zero when the version is current
+	sunset     time.Time // zero when no removal date is announced
+	link       string    // migration guide advertised alongside deprecation
+}
+
+type Option func(*version)
+
+// Marks the version deprecated as of the given time (Deprecation header, RFC 9745)
+func Deprecated(at time.Time) Option { return func(v *version) { v.deprecated = at } }
+
+// Announces when the version will be removed (Sunset header, RFC 8594)
+func Sunset(at time.Time) Option { return func(v *version) { v.sunset = at } }
+
+// Points deprecated-version clients at migration docs
+func Link(url string) Option { return func(v *version) { v.link = url } }
+
+// Dispatches one route to a handler per major API version.
+// The version is negotiated with httpReq.GetAPIVersion (Accept/Content-Type `v=`/`version=`, then /vN path prefix).
+type Router struct {
+	versions map[int]*version
+	fallback int
+}
+
+func NewRouter() *Router { return &Router{versions: make(map[int]*version)} }
+
+// Registers the handler for a major version
+func (r *Router) Handle(major int, handler http.Handler, opts ...Option) *Router {
+	if major < 1 || handler == nil {
+		panic(fmt.Sprintf("versioning: invalid registration for v%d", major))
+	}
+	if _, exists := r.versions[major]; exists {
+		panic(fmt.Sprintf("versioning: v%d registered twice", major))
+	}
+
+	v := &version{handler: handler}
+	for _, opt := range opts { opt(v) }
+	r.versions[major] = v
+	return r
+}
+
+func (r *Router) HandleFunc(major int, fn http.HandlerFunc, opts ...Option) *Router {
+	return r.Handle(major, fn, opts...)
+}
+
+// Sets the version served when the client doesn't ask for one (defaults to the oldest registered)
+func (r *Router) Default(major int) *Router {
+	r.fallback = major
+	return r
+}
+
+// Lists the registered major versions in ascending order
+func (r *Router) Supported() []int {
+	out := make([]int, 0, len(r.versions))
+	for major := range r.versions { out = append(out, major) }
+	slices.Sort(out)
+	return out
+}
+
+func (r *Router) ServeHTTP(wtr http.ResponseWriter, req *http.Request) {
+	wtr.Header().Add("Vary", "Accept")
+
+	major, ok := r.negotiate(req)
+	v := r.versions[major]
+	if !ok || v == nil {
+		r.sendNotAcceptable(wtr, req)
+		return
+	}
+
+	if !v.deprecated.IsZero() {
+		wtr.Header().Set("Deprecation", "@"+strconv.FormatInt(v.deprecated.Unix(), 10))
+		if v.link != "" {
+			wtr.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", v.link))
+		}
+	}
+	if !v.sunset.IsZero() {
+		wtr.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+	}
+
+	ctx := context.WithValue(req.Context(), ctxKeys.VERSION_KEY, "/v"+strconv.Itoa(major))
+	v.handler.ServeHTTP(wtr, req.WithContext(ctx))
+}
+
+// Resolves the requested major version; unparseable versions are reported as not ok
+func (r *Router) negotiate(req *http.Request) (int, bool) {
+	if httpReq.GetAPIVersion(req) == "" {
+		if r.fallback > 0 { return r.fallback, true }
+
+		supported := r.Supported()
+		if len(supported) == 0 { return 0, false }
+		return supported[0], true
+	}
+	return httpReq.GetAPIMajorVersion(req)
+}
+
+func (r *Router) sendNotAcceptable(wtr http.ResponseWriter, req *http.Request) {
+	supported := r.Supported()
+	labels := make([]string, len(supported))
+	for i, major := range supported { labels[i] = "v" + strconv.Itoa(major) }
+
+	logger.Warn("unsupported api version requested: " + httpReq.GetAPIVersion(req))
+
+	httpErr.SendError(
+		wtr, req, httpErr.Global.NotAcceptable,
+		httpErr.WithDetail("supported versions: "+strings.Join(labels, ", ")),
+		httpErr.WithExtension("supportedVersions", labels),
+	)
+}
@@ -0,0 +1,55 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouterNegotiatesVersion(t *testing.T) {
+	sunset := time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)
+	respond := func(body string) http.HandlerFunc {
+		return func(wtr http.ResponseWriter, _ *http.Request) { wtr.Write([]byte(body)) }
+	}
+
+	router := NewRouter().
+		HandleFunc(1, respond("v1"), Deprecated(time.Unix(1798761600, 0)), Sunset(sunset)).
+		HandleFunc(2, respond("v2"))
+
+	cases := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		body   string
+	}{
+		{"default is oldest", "/item/abc", "application/json", http.StatusOK, "v1"},
+		{"accept param", "/item/abc", "application/json;v=2", http.StatusOK, "v2"},
+		{"path prefix", "/v2/item/abc", "application/json", http.StatusOK, "v2"},
+		{"unknown version", "/item/abc", "application/json; version=9", http.StatusNotAcceptable, ""},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.status)
+			continue
+		}
+		if tc.body != "" && rec.Body.String() != tc.body {
+			t.Errorf("%s: body = %q, want %q", tc.name, rec.Body.String(), tc.body)
+		}
+
+		deprecated := rec.Header().Get("Deprecation") != ""
+		if deprecated != (tc.body == "v1") {
+			t.Errorf("%s: Deprecation header present = %v", tc.name, deprecated)
+		}
+		if tc.body == "v1" && rec.Header().Get("Sunset") != sunset.Format(http.TimeFormat) {
+			t.Errorf("%s: Sunset = %q", tc.name, rec.Header().Get("Sunset"))
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	rateLimiter "komodo-forge-sdk-go/http/services/rate_limiter"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitPoliciesMatchLiteralItemRoutes(t *testing.T) {
+	if err := rateLimiter.LoadPolicies("rate_limits.yml"); err != nil { t.Fatalf("load: %v", err) }
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		policy string
+	}{
+		{"GET", "/v1/item/inventory", "item-inventory"},
+		{"POST", "/v1/item/suggestion", "item-suggestion"},
+		{"GET", "/v1/item/SKU-1", "item-detail"},
+		{"GET", "/v1/item/suggestion", "item-detail"},
+	} {
+		if got := rateLimiter.MatchPolicy(httptest.NewRequest(tc.method, tc.path, nil)).Name; got != tc.policy {
+			t.Errorf("%s %s matched %q, want %q", tc.method, tc.path, got, tc.policy)
+		}
+	}
+}
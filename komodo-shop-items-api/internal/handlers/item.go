@@ -8,40 +8,53 @@ import (
 	httpErr "komodo-forge-sdk-go/http/errors"
 	shopitems "komodo-forge-sdk-go/http/services/shop_items"
 	logger "komodo-forge-sdk-go/logging/runtime"
+	v2 "komodo-shop-items-api/pkg/v2/models"
 )
 
 // Returns a single item (product or service) by SKU
 func GetItemBySKU(wtr http.ResponseWriter, req *http.Request) {
+	_, item, ok := fetchItem(wtr, req)
+	if !ok { return }
+
+	wtr.WriteHeader(http.StatusOK)
+	json.NewEncoder(wtr).Encode(item)
+}
+
+// Returns a single item by SKU wrapped with its type (v2)
+func GetItemBySKUV2(wtr http.ResponseWriter, req *http.Request) {
+	itemType, item, ok := fetchItem(wtr, req)
+	if !ok { return }
+
+	wtr.WriteHeader(http.StatusOK)
+	json.NewEncoder(wtr).Encode(v2.Item{Type: itemType, SKU: req.PathValue("sku"), Item: item})
+}
+
+// Looks up the SKU as a product, then as a service. Sends the error response and returns false on failure.
+func fetchItem(wtr http.ResponseWriter, req *http.Request) (v2.ItemType, any, bool) {
 	wtr.Header().Set("Content-Type", "application/json")
 
 	sku := req.PathValue("sku")
 	if sku == "" {
 		httpErr.SendError(wtr, req, httpErr.ShopItem.InvalidSKU, httpErr.WithDetail("sku path parameter is required"))
-		return
+		return "", nil, false
 	}
 
 	bucket := config.GetConfigValue("S3_ITEMS_BUCKET")
 	if bucket == "" {
 		logger.Error("S3_ITEMS_BUCKET not configured", nil)
 		httpErr.SendError(wtr, req, httpErr.ShopItem.StorageError, httpErr.WithDetail("storage not configured"))
-		return
+		return "", nil, false
 	}
 
 	// Try product first, then fall back to service
-	product, err := shopitems.FetchProductBySKU(req.Context(), bucket, sku)
-	if err == nil {
-		wtr.WriteHeader(http.StatusOK)
-		json.NewEncoder(wtr).Encode(product)
-		return
+	if product, err := shopitems.FetchProductBySKU(req.Context(), bucket, sku); err == nil {
+		return v2.ItemTypeProduct, product, true
 	}
-
-	service, err := shopitems.FetchServiceBySKU(req.Context(), bucket, sku)
-	if err == nil {
-		wtr.WriteHeader(http.StatusOK)
-		json.NewEncoder(wtr).Encode(service)
-		return
+	if service, err := shopitems.FetchServiceBySKU(req.Context(), bucket, sku); err == nil {
+		return v2.ItemTypeService, service, true
 	}
 
 	logger.Warn("item not found for sku: " + sku)
 	httpErr.SendError(wtr, req, httpErr.ShopItem.ItemNotFound, httpErr.WithDetail("no product or service found for sku: "+sku))
+	return "", nil, false
 }
@@ -4,7 +4,9 @@ import (
 	awsS3 "komodo-forge-sdk-go/aws/s3"
 	awsSM "komodo-forge-sdk-go/aws/secrets-manager"
 	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/crypto/jwt"
 	mw "komodo-forge-sdk-go/http/middleware"
+	apiVersion "komodo-forge-sdk-go/http/versioning"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"komodo-shop-items-api/internal/handlers"
 	"net/http"
@@ -41,13 +43,25 @@ func main() {
 			"S3_ITEMS_BUCKET",
 			"SHOP_ITEMS_API_CLIENT_ID",
 			"SHOP_ITEMS_API_CLIENT_SECRET",
+			"JWT_ISSUER",
+			"JWT_AUDIENCE",
 			"IP_WHITELIST",
 			"IP_BLACKLIST",
+			"TRUSTED_PROXIES",
+			"AUTO_BAN_STRIKES",
+			"AUTO_BAN_WINDOW_SEC",
+			"AUTO_BAN_COOLDOWN_SEC",
 			"MAX_CONTENT_LENGTH",
 			"IDEMPOTENCY_TTL_SEC",
 			"RATE_LIMIT_RPS",
 			"RATE_LIMIT_BURST",
 			"BUCKET_TTL_SECOND",
+			"CSRF_SECRET",
+			"CSRF_TRUSTED_ORIGINS",
+			"CSRF_TOKEN_TTL_SEC",
+			"CORS_ALLOWED_ORIGINS",
+			"CORS_ALLOW_CREDENTIALS",
+			"CORS_MAX_AGE_SEC",
 		},
 	}
 	if err := awsSM.Bootstrap(smCfg); err != nil {
@@ -57,6 +71,12 @@ func main() {
 		logger.Info("aws secrets manager initialized successfully")
 	}
 
+	// Tokens are verified against the auth-api JWKS (JWT_JWKS_URL) rather than a local public key
+	if err := jwt.InitializeKeys(); err != nil {
+		logger.Fatal("failed to initialize JWT keys", err)
+		os.Exit(1)
+	}
+
 	s3Cfg := awsS3.Config{
 		Region:    config.GetConfigValue("AWS_REGION"),
 		Endpoint:  config.GetConfigValue("S3_ENDPOINT"),
@@ -73,6 +93,7 @@ func main() {
 	// Shared middleware stack for /item routes
 	itemMW := []func(http.Handler) http.Handler{
 		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
 		mw.TelemetryMiddleware,
 		mw.RateLimiterMiddleware,
 		mw.IPAccessMiddleware,
@@ -80,23 +101,52 @@ func main() {
 		mw.SecurityHeadersMiddleware,
 	}
 
-	// Extended middleware stack for protected /item routes
-	protectedMW := append(itemMW,
+	// Extended middleware stack for protected /item routes; rate limited after auth so policies can key on the user
+	protectedMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.IPAccessMiddleware,
+		mw.CORSMiddleware,
+		mw.SecurityHeadersMiddleware,
 		mw.AuthMiddleware,
+		mw.RateLimiterMiddleware,
 		mw.CSRFMiddleware,
 		mw.NormalizationMiddleware,
 		mw.SanitizationMiddleware,
 		mw.RuleValidationMiddleware,
-	)
+	}
+
+	// Browser preflights are answered by CORSMiddleware before any auth or validation runs
+	preflightMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.IPAccessMiddleware,
+		mw.CORSMiddleware,
+	}
+
+	// Item detail responses are checked against the contract the SSR engine relies on
+	itemDetailMW := append(itemMW, mw.ResponseValidationMiddleware)
+
+	// v1 item detail stays available for existing consumers until its sunset date
+	itemBySKU := apiVersion.NewRouter().
+		HandleFunc(1, handlers.GetItemBySKU,
+			apiVersion.Deprecated(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)),
+			apiVersion.Sunset(time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC)),
+		).
+		HandleFunc(2, handlers.GetItemBySKUV2)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", handlers.HealthHandler)
 
 	mux.Handle("GET /item/inventory", chain(http.HandlerFunc(handlers.GetInventory), itemMW...))
-	mux.Handle("GET /item/{sku}", chain(http.HandlerFunc(handlers.GetItemBySKU), itemMW...))
+	mux.Handle("GET /item/{sku}", chain(itemBySKU, itemDetailMW...))
 
 	mux.Handle("POST /item/suggestion", chain(http.HandlerFunc(handlers.GetSuggestions), protectedMW...))
 
+	mux.Handle("OPTIONS /item/", chain(http.NotFoundHandler(), preflightMW...))
+
 	server := &http.Server{
 		Addr:              ":" + config.GetConfigValue("PORT"),
 		Handler:           mux,
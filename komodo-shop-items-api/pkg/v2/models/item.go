@@ -0,0 +1,17 @@
+package models
+
+// ItemType discriminates the payload of a v2 item response
+type ItemType string
+
+const (
+	ItemTypeProduct ItemType = "product"
+	ItemTypeService ItemType = "service"
+)
+
+// Item is the v2 GET /item/{sku} body: the v1 product or service wrapped with its type,
+// so clients no longer have to sniff fields to tell them apart.
+type Item struct {
+	Type ItemType `json:"type"`
+	SKU  string   `json:"sku"`
+	Item any      `json:"item"`
+}
@@ -4,6 +4,7 @@ import (
 	"komodo-forge-sdk-go/aws/dynamodb"
 	awsSM "komodo-forge-sdk-go/aws/secrets-manager"
 	"komodo-forge-sdk-go/config"
+	"komodo-forge-sdk-go/crypto/jwt"
 	mw "komodo-forge-sdk-go/http/middleware"
 	logger "komodo-forge-sdk-go/logging/runtime"
 	"komodo-user-api/internal/handlers"
@@ -40,13 +41,26 @@ func main() {
 			"DYNAMODB_SECRET_KEY",
 			"USER_API_CLIENT_ID",
 			"USER_API_CLIENT_SECRET",
+			"JWT_ISSUER",
+			"JWT_AUDIENCE",
 			"IP_WHITELIST",
 			"IP_BLACKLIST",
+			"TRUSTED_PROXIES",
+			"AUTO_BAN_STRIKES",
+			"AUTO_BAN_WINDOW_SEC",
+			"AUTO_BAN_COOLDOWN_SEC",
 			"MAX_CONTENT_LENGTH",
 			"IDEMPOTENCY_TTL_SEC",
 			"RATE_LIMIT_RPS",
 			"RATE_LIMIT_BURST",
 			"BUCKET_TTL_SECOND",
+			"CSRF_SECRET",
+			"CSRF_TRUSTED_ORIGINS",
+			"CSRF_TOKEN_TTL_SEC",
+			"CORS_ALLOWED_ORIGINS",
+			"CORS_ALLOWED_METHODS",
+			"CORS_ALLOW_CREDENTIALS",
+			"CORS_MAX_AGE_SEC",
 		},
 	}
 	if err := awsSM.Bootstrap(smCfg); err != nil {
@@ -56,6 +70,12 @@ func main() {
 		logger.Info("aws secrets manager initialized successfully")
 	}
 
+	// Tokens are verified against the auth-api JWKS (JWT_JWKS_URL) rather than a local public key
+	if err := jwt.InitializeKeys(); err != nil {
+		logger.Fatal("failed to initialize JWT keys", err)
+		os.Exit(1)
+	}
+
 	ddbCfg := dynamodb.Config{
 		Region:    config.GetConfigValue("AWS_REGION"),
 		Endpoint:  config.GetConfigValue("DYNAMODB_ENDPOINT"),
@@ -72,12 +92,14 @@ func main() {
 	// Middleware stack for all /me routes
 	meMW := []func(http.Handler) http.Handler{
 		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
 		mw.TelemetryMiddleware,
 		mw.RateLimiterMiddleware,
 		mw.IPAccessMiddleware,
 		mw.CORSMiddleware,
 		mw.SecurityHeadersMiddleware,
 		mw.AuthMiddleware,
+		mw.AuthzMiddleware,
 		mw.CSRFMiddleware,
 		mw.NormalizationMiddleware,
 		mw.RuleValidationMiddleware,
@@ -85,6 +107,15 @@ func main() {
 		mw.IdempotencyMiddleware,
 	}
 
+	// Browser preflights are answered by CORSMiddleware before any auth or validation runs
+	preflightMW := []func(http.Handler) http.Handler{
+		mw.RequestIDMiddleware,
+		mw.ClientIPMiddleware,
+		mw.TelemetryMiddleware,
+		mw.IPAccessMiddleware,
+		mw.CORSMiddleware,
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", handlers.HealthHandler)
 
@@ -111,6 +142,8 @@ func main() {
 	mux.Handle("GET /me/preferences", chain(http.HandlerFunc(handlers.GetPreferences), meMW...))
 	mux.Handle("PUT /me/preferences", chain(http.HandlerFunc(handlers.UpdatePreferences), meMW...))
 
+	mux.Handle("OPTIONS /me/", chain(http.NotFoundHandler(), preflightMW...))
+
 	server := &http.Server{
 		Addr:              ":" + config.GetConfigValue("PORT"),
 		Handler:           mux,
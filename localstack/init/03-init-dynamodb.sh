@@ -1,7 +1,7 @@
 #!/bin/bash
 
 # LocalStack initialization script for DynamoDB
-# Creates DynamoDB tables for user and auth data
+# Creates DynamoDB tables for user and auth data, and seeds the local OAuth clients
 
 echo "Initializing DynamoDB in LocalStack..."
 
@@ -51,6 +51,12 @@ awslocal dynamodb create-table \
     StreamEnabled=true,StreamViewType=NEW_AND_OLD_IMAGES \
   2>/dev/null || echo "Sessions table already exists"
 
+# Sessions carry their absolute expiry in "ttl" so DynamoDB reaps them
+awslocal dynamodb update-time-to-live \
+  --table-name komodo-sessions-dev \
+  --time-to-live-specification "Enabled=true,AttributeName=ttl" \
+  2>/dev/null || echo "Sessions TTL already enabled"
+
 # OAuth tokens table
 echo "Creating OAuthTokens table..."
 awslocal dynamodb create-table \
@@ -66,4 +72,59 @@ awslocal dynamodb create-table \
     ReadCapacityUnits=5,WriteCapacityUnits=5 \
   2>/dev/null || echo "OAuthTokens table already exists"
 
+# OAuth clients table
+echo "Creating OAuthClients table..."
+awslocal dynamodb create-table \
+  --table-name komodo-oauth-clients-dev \
+  --attribute-definitions \
+    AttributeName=client_id,AttributeType=S \
+  --key-schema \
+    AttributeName=client_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  2>/dev/null || echo "OAuthClients table already exists"
+
+# Local clients: test-client / test-secret (argon2id hash) for M2M, komodo-ssr as the public PKCE storefront client,
+# komodo-order-api / test-secret exchanging user tokens for payments-api tokens
+echo "Seeding OAuth clients..."
+awslocal dynamodb put-item \
+  --table-name komodo-oauth-clients-dev \
+  --item '{
+    "client_id": {"S": "test-client"},
+    "name": {"S": "Local test client"},
+    "secret_hash": {"S": "$argon2id$v=19$m=65536,t=3,p=2$hX55DPQefXu1TMlNYbSZcA$W+bkkywPMOr9v18/dexO1S4xXb1l4HN4nVnzRzar+2Q"},
+    "grant_types": {"L": [{"S": "client_credentials"}]},
+    "scopes": {"L": [{"S": "read"}, {"S": "write"}, {"S": "admin"}, {"S": "checkout:read"}, {"S": "checkout:write"}, {"S": "orders:read"}, {"S": "users:profile"}, {"S": "auth:login"}, {"S": "profile:read"}, {"S": "addresses:read"}, {"S": "payments:read"}, {"S": "preferences:read"}]},
+    "audience": {"S": "komodo-apis:service"},
+    "disabled": {"BOOL": false},
+    "created_at": {"S": "2026-01-01T00:00:00Z"},
+    "updated_at": {"S": "2026-01-01T00:00:00Z"}
+  }' || echo "Failed to seed test-client"
+awslocal dynamodb put-item \
+  --table-name komodo-oauth-clients-dev \
+  --item '{
+    "client_id": {"S": "komodo-ssr"},
+    "name": {"S": "Komodo storefront"},
+    "redirect_uris": {"L": [{"S": "http://localhost:7001/auth/callback"}]},
+    "grant_types": {"L": [{"S": "authorization_code"}, {"S": "refresh_token"}]},
+    "scopes": {"L": [{"S": "read"}, {"S": "write"}, {"S": "orders:write"}, {"S": "users:profile"}, {"S": "profile:write"}, {"S": "addresses:write"}, {"S": "payments:write"}, {"S": "preferences:write"}]},
+    "audience": {"S": "komodo-apis:user"},
+    "disabled": {"BOOL": false},
+    "created_at": {"S": "2026-01-01T00:00:00Z"},
+    "updated_at": {"S": "2026-01-01T00:00:00Z"}
+  }' || echo "Failed to seed komodo-ssr"
+awslocal dynamodb put-item \
+  --table-name komodo-oauth-clients-dev \
+  --item '{
+    "client_id": {"S": "komodo-order-api"},
+    "name": {"S": "Komodo order api"},
+    "secret_hash": {"S": "$argon2id$v=19$m=65536,t=3,p=2$hX55DPQefXu1TMlNYbSZcA$W+bkkywPMOr9v18/dexO1S4xXb1l4HN4nVnzRzar+2Q"},
+    "grant_types": {"L": [{"S": "urn:ietf:params:oauth:grant-type:token-exchange"}]},
+    "scopes": {"L": [{"S": "payments:read"}, {"S": "payments:write"}]},
+    "exchange_audiences": {"L": [{"S": "komodo-payments-api"}]},
+    "disabled": {"BOOL": false},
+    "created_at": {"S": "2026-01-01T00:00:00Z"},
+    "updated_at": {"S": "2026-01-01T00:00:00Z"}
+  }' || echo "Failed to seed komodo-order-api"
+
 echo "DynamoDB initialized successfully"